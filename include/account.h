@@ -0,0 +1,93 @@
+#ifndef RBS_ACCOUNT_H
+#define RBS_ACCOUNT_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/wait.h>
+#include <unistd.h>
+#include "store.h"
+#include "client.h"
+#include "config.h"
+
+/* Account overview: balances, per-asset valuation and patrimony.
+ * Each asset's quote is fetched in a forked child so the command
+ * completes in one round-trip time instead of one per asset, and a
+ * single failing quote degrades that line instead of the command. */
+
+int rbs_account_draw(void);
+
+int rbs_account_cmd(int argc, char *argv[]) {
+	int watch = 0, interval = 2, i;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--watch") == 0) {
+			watch = 1;
+			if (i + 1 < argc && argv[i + 1][0] >= '0' &&
+			    argv[i + 1][0] <= '9')
+				interval = atoi(argv[++i]);
+		}
+	}
+	if (interval < 1) interval = 1;
+	if (!watch) return rbs_account_draw();
+	for (;;) {
+		printf("\033[2J\033[H");
+		rbs_account_draw();
+		fflush(stdout);
+		sleep(interval);
+	}
+	return 0;
+}
+
+int rbs_account_draw(void) {
+	rbs_reb_line bals[RBS_REB_MAX];
+	int pipes[RBS_REB_MAX][2];
+	pid_t pids[RBS_REB_MAX];
+	char buf[64];
+	double px, total = 0;
+	int n, i, complete = 1;
+	ssize_t got;
+	rbs_client_header();
+	n = rbs_client_balances(bals, RBS_REB_MAX);
+	if (n <= 0) {
+		printf("no balances found; write asset=qty lines to ~/.robson/balances\n");
+		return 1;
+	}
+	for (i = 0; i < n; i++) {
+		if (pipe(pipes[i]) != 0) return 1;
+		pids[i] = fork();
+		if (pids[i] == 0) {
+			/* child: one quote, result (or silence) up the pipe */
+			close(pipes[i][0]);
+			if (rbs_reb_price(&bals[i]) == 0) {
+				snprintf(buf, sizeof(buf), "%f", bals[i].price);
+				if (write(pipes[i][1], buf, strlen(buf)) < 0) {
+					/* parent treats silence as failure */
+				}
+			}
+			close(pipes[i][1]);
+			_exit(0);
+		}
+		close(pipes[i][1]);
+	}
+	printf("%-8s %14s %14s %14s\n", "ASSET", "QTY", "PRICE", "NOTIONAL");
+	for (i = 0; i < n; i++) {
+		got = read(pipes[i][0], buf, sizeof(buf) - 1);
+		close(pipes[i][0]);
+		waitpid(pids[i], NULL, 0);
+		if (got > 0) {
+			buf[got] = '\0';
+			px = atof(buf);
+			total += bals[i].qty * px;
+			printf("%-8s %14f %14f %14.2f\n", bals[i].asset,
+			       bals[i].qty, px, bals[i].qty * px);
+		} else {
+			complete = 0;
+			printf("%-8s %14f %14s %14s\n", bals[i].asset,
+			       bals[i].qty, "unavailable", "-");
+		}
+	}
+	printf("patrimony: %.2f USDT%s\n", total,
+	       complete ? "" : " (partial: some quotes unavailable)");
+	return complete ? 0 : 1;
+}
+
+#endif
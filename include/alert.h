@@ -0,0 +1,260 @@
+#ifndef RBS_ALERT_H
+#define RBS_ALERT_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "price.h"
+#include "daemon.h"
+#include "fmt.h"
+
+/* Local price alerts. One key=value file per alert under
+ * ~/.robson/alerts (same layout as plans); robson alert watch polls
+ * the feed and fires each alert once when its threshold crosses.
+ * Every firing lands in alerts.log; desktop notifications go through
+ * notify-send when it exists, telegram through the bot API when
+ * ROBSON_TELEGRAM_TOKEN and ROBSON_TELEGRAM_CHAT are set. */
+
+typedef struct {
+	char id[24];
+	char symbol[16];
+	char notify[48];	/* comma list: log,desktop,telegram */
+	double above, below;	/* 0 = unset */
+	time_t fired;		/* 0 = armed */
+} rbs_alert;
+
+void rbs_alert_path(char *out, size_t n, const char *id) {
+	char dir[512];
+	rbs_store_dir(dir, sizeof(dir), "alerts");
+	snprintf(out, n, "%s/%s.alert", dir, id);
+}
+
+int rbs_alert_save(rbs_alert *a) {
+	char path[640], tmp[700];
+	FILE *f;
+	rbs_alert_path(path, sizeof(path), a->id);
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(tmp, "w");
+	if (!f) return -1;
+	fprintf(f, "id=%s\n", a->id);
+	fprintf(f, "symbol=%s\n", a->symbol);
+	fprintf(f, "notify=%s\n", a->notify);
+	fprintf(f, "above=%.8f\n", a->above);
+	fprintf(f, "below=%.8f\n", a->below);
+	fprintf(f, "fired=%ld\n", (long)a->fired);
+	fclose(f);
+	rename(tmp, path);
+	return 0;
+}
+
+int rbs_alert_load(const char *path, rbs_alert *a) {
+	char line[256], k[64], v[192];
+	FILE *f = fopen(path, "r");
+	if (!f) return -1;
+	memset(a, 0, sizeof(*a));
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0) continue;
+		if (strcmp(k, "id") == 0) snprintf(a->id, sizeof(a->id), "%s", v);
+		else if (strcmp(k, "symbol") == 0) snprintf(a->symbol, sizeof(a->symbol), "%s", v);
+		else if (strcmp(k, "notify") == 0) snprintf(a->notify, sizeof(a->notify), "%s", v);
+		else if (strcmp(k, "above") == 0) a->above = atof(v);
+		else if (strcmp(k, "below") == 0) a->below = atof(v);
+		else if (strcmp(k, "fired") == 0) a->fired = (time_t)atol(v);
+	}
+	fclose(f);
+	return 0;
+}
+
+int rbs_alert_load_all(rbs_alert *out, int max) {
+	char dir[512], path[700];
+	DIR *d;
+	struct dirent *e;
+	int n = 0;
+	rbs_store_dir(dir, sizeof(dir), "alerts");
+	d = opendir(dir);
+	if (!d) return 0;
+	while ((e = readdir(d)) && n < max) {
+		char *dot = strstr(e->d_name, ".alert");
+		if (!dot || dot[6] != '\0') continue;
+		snprintf(path, sizeof(path), "%s/%s", dir, e->d_name);
+		if (rbs_alert_load(path, &out[n]) == 0) n++;
+	}
+	closedir(d);
+	return n;
+}
+
+void rbs_alert_notify(rbs_alert *a, double px, const char *crossed) {
+	char msg[160], path[640], cmd[360];
+	const char *tok = getenv("ROBSON_TELEGRAM_TOKEN");
+	const char *chat = getenv("ROBSON_TELEGRAM_CHAT");
+	FILE *f;
+	snprintf(msg, sizeof(msg), "%s crossed %s %.8f (now %.8f)",
+	         a->symbol, crossed,
+	         strcmp(crossed, "above") == 0 ? a->above : a->below, px);
+	fprintf(stderr, "ALERT: %s\n", msg);
+	rbs_state_path(path, sizeof(path), "alerts.log");
+	f = fopen(path, "a");
+	if (f) {
+		fprintf(f, "%ld price %s\n", (long)time(NULL), msg);
+		fclose(f);
+	}
+	if (strchr(msg, '\'')) {
+		fprintf(stderr, "  (notifications skipped: quote in message)\n");
+		return;
+	}
+	if (strstr(a->notify, "desktop")) {
+		snprintf(cmd, sizeof(cmd),
+		         "notify-send 'robson' '%s' 2>/dev/null", msg);
+		if (system(cmd) != 0)
+			fprintf(stderr, "  (desktop notification failed; is "
+			        "notify-send installed?)\n");
+	}
+	if (strstr(a->notify, "telegram")) {
+		if (tok && chat && !strchr(tok, '\'') && !strchr(chat, '\'')) {
+			snprintf(cmd, sizeof(cmd),
+			         "curl -sf -m 10 'https://api.telegram.org/bot%s/"
+			         "sendMessage' --data-urlencode 'chat_id=%s' "
+			         "--data-urlencode 'text=%s' >/dev/null 2>&1", tok,
+			         chat, msg);
+			if (system(cmd) != 0)
+				fprintf(stderr, "  (telegram notification failed)\n");
+		} else {
+			fprintf(stderr, "  (telegram skipped: set "
+			        "ROBSON_TELEGRAM_TOKEN and ROBSON_TELEGRAM_CHAT)\n");
+		}
+	}
+}
+
+/* One pass over armed alerts; returns how many fired. */
+int rbs_alert_pass(void) {
+	static rbs_alert as[128];
+	double px;
+	int n, i, fired = 0;
+	n = rbs_alert_load_all(as, 128);
+	for (i = 0; i < n; i++) {
+		if (as[i].fired) continue;
+		if (rbs_price_get(as[i].symbol, &px) != 0) continue;
+		if (as[i].above > 0 && px >= as[i].above)
+			rbs_alert_notify(&as[i], px, "above");
+		else if (as[i].below > 0 && px <= as[i].below)
+			rbs_alert_notify(&as[i], px, "below");
+		else
+			continue;
+		as[i].fired = time(NULL);
+		rbs_alert_save(&as[i]);
+		fired++;
+	}
+	return fired;
+}
+
+int rbs_alert_cmd(int argc, char *argv[]) {
+	static rbs_alert as[128];
+	rbs_alert a;
+	char path[640], when[40];
+	int n, i;
+	if (argc >= 3 && strcmp(argv[1], "add") == 0) {
+		memset(&a, 0, sizeof(a));
+		if (!rbs_symbol_ok(argv[2])) {
+			printf("bad symbol %s\n", argv[2]);
+			return 1;
+		}
+		snprintf(a.symbol, sizeof(a.symbol), "%s", argv[2]);
+		snprintf(a.notify, sizeof(a.notify), "log");
+		for (i = 3; i < argc; i++) {
+			if (strcmp(argv[i], "--above") == 0 && i + 1 < argc)
+				a.above = atof(argv[++i]);
+			else if (strcmp(argv[i], "--below") == 0 && i + 1 < argc)
+				a.below = atof(argv[++i]);
+			else if (strcmp(argv[i], "--notify") == 0 && i + 1 < argc)
+				snprintf(a.notify, sizeof(a.notify), "%s", argv[++i]);
+		}
+		if (a.above <= 0 && a.below <= 0) {
+			printf("usage: robson alert add SYMBOL [--above PX] "
+			       "[--below PX] [--notify log,desktop,telegram]\n");
+			return 1;
+		}
+		snprintf(a.id, sizeof(a.id), "A%ld%02d", (long)time(NULL),
+		         (int)(getpid() % 100));
+		if (rbs_alert_save(&a) != 0) {
+			printf("cannot save alert\n");
+			return 1;
+		}
+		printf("alert %s: %s", a.id, a.symbol);
+		if (a.above > 0) printf(" above %.8f", a.above);
+		if (a.below > 0) printf(" below %.8f", a.below);
+		printf(" (notify %s)\n", a.notify);
+		return 0;
+	}
+	if (argc >= 2 && strcmp(argv[1], "list") == 0) {
+		n = rbs_alert_load_all(as, 128);
+		if (!n) {
+			printf("no alerts\n");
+			return 0;
+		}
+		printf("%-16s %-10s %12s %12s %-20s %s\n", "ID", "SYMBOL",
+		       "ABOVE", "BELOW", "NOTIFY", "STATE");
+		for (i = 0; i < n; i++) {
+			printf("%-16s %-10s ", as[i].id, as[i].symbol);
+			if (as[i].above > 0) printf("%12.8g ", as[i].above);
+			else printf("%12s ", "-");
+			if (as[i].below > 0) printf("%12.8g ", as[i].below);
+			else printf("%12s ", "-");
+			printf("%-20s ", as[i].notify);
+			if (as[i].fired) {
+				rbs_fmt_time_short(as[i].fired, when, sizeof(when));
+				printf("fired %s\n", when);
+			} else {
+				printf("armed\n");
+			}
+		}
+		return 0;
+	}
+	if (argc >= 3 && strcmp(argv[1], "rm") == 0) {
+		if (strchr(argv[2], '/') || strchr(argv[2], '.')) {
+			printf("bad alert id %s\n", argv[2]);
+			return 1;
+		}
+		rbs_alert_path(path, sizeof(path), argv[2]);
+		if (unlink(path) != 0) {
+			printf("no such alert %s\n", argv[2]);
+			return 1;
+		}
+		printf("alert %s removed\n", argv[2]);
+		return 0;
+	}
+	if (argc >= 2 && strcmp(argv[1], "watch") == 0) {
+		long interval = 10;
+		time_t started = time(NULL);
+		int once = 0;
+		for (i = 2; i < argc; i++) {
+			if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc) {
+				interval = rbs_parse_duration(argv[++i]);
+				if (interval <= 0) {
+					printf("--interval wants 10s/1m style\n");
+					return 1;
+				}
+			}
+			else if (strcmp(argv[i], "--once") == 0) once = 1;
+		}
+		if (once) {
+			n = rbs_alert_pass();
+			printf("%d alert%s fired\n", n, n == 1 ? "" : "s");
+			return 0;
+		}
+		printf("alert watch: every %lds\n", interval);
+		fflush(stdout);
+		for (;;) {
+			rbs_alert_pass();
+			rbs_daemon_heartbeat("alerts", started, 0, 0, "");
+			fflush(stderr);
+			sleep(interval);
+		}
+	}
+	printf("usage: robson alert <add|list|rm|watch>\n");
+	return 1;
+}
+
+#endif
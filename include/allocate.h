@@ -0,0 +1,127 @@
+#ifndef RBS_ALLOCATE_H
+#define RBS_ALLOCATE_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+#include "plan.h"
+
+/* Capital allocation across strategies: ~/.robson/allocations holds
+ * strategy=amount lines written by robson allocate. Validation then
+ * refuses any plan that would push its strategy's committed notional
+ * (validated plus executed plans) past the allocation, so one
+ * strategy cannot consume the whole account. */
+
+double rbs_alloc_for(const char *strategy) {
+	char path[640], line[128], key[64], val[64];
+	FILE *f;
+	double amount = -1;
+	rbs_state_path(path, sizeof(path), "allocations");
+	f = fopen(path, "r");
+	if (!f) return -1;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, strategy) == 0) {
+			amount = atof(val);
+			break;
+		}
+	}
+	fclose(f);
+	return amount;
+}
+
+/* Notional already committed by validated and executed plans of one
+ * strategy; exclude_id leaves the plan being validated out of its own
+ * total. */
+double rbs_alloc_used(const char *strategy, const char *exclude_id) {
+	static rbs_plan plans[1024];
+	double used = 0;
+	int n, i;
+	n = rbs_plan_load_all(plans, 1024);
+	for (i = 0; i < n; i++) {
+		if (strcmp(plans[i].strategy, strategy) != 0) continue;
+		if (exclude_id && strcmp(plans[i].id, exclude_id) == 0) continue;
+		if (strcmp(plans[i].status, "validated") != 0 &&
+		    strcmp(plans[i].status, "executed") != 0) continue;
+		used += plans[i].qty * plans[i].price;
+	}
+	return used;
+}
+
+int rbs_allocate_cmd(int argc, char *argv[]) {
+	char path[640], tmp[700], spec[256], line[128], key[64], val[64];
+	char *tok, *colon, *endp;
+	const char *strategies = NULL;
+	double capital = 0, pct, pct_sum = 0, alloc, used;
+	FILE *f;
+	int i;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--capital") == 0 && i + 1 < argc) capital = atof(argv[++i]);
+		else if (strcmp(argv[i], "--strategies") == 0 && i + 1 < argc) strategies = argv[++i];
+	}
+	rbs_state_path(path, sizeof(path), "allocations");
+	if (!strategies) {
+		/* Show current allocations and how much each has deployed. */
+		f = fopen(path, "r");
+		if (!f) {
+			printf("no allocations; set them with robson allocate "
+			       "--capital 10000 --strategies trend:60,meanrev:40\n");
+			return 1;
+		}
+		printf("%-12s %12s %12s %12s\n", "STRATEGY", "ALLOCATED",
+		       "COMMITTED", "FREE");
+		while (fgets(line, sizeof(line), f)) {
+			if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+				continue;
+			alloc = atof(val);
+			used = rbs_alloc_used(key, NULL);
+			printf("%-12s %12.2f %12.2f %12.2f\n", key, alloc,
+			       used, alloc - used);
+		}
+		fclose(f);
+		return 0;
+	}
+	if (capital <= 0) {
+		printf("usage: robson allocate --capital 10000 --strategies trend:60,meanrev:40\n");
+		return 1;
+	}
+	snprintf(spec, sizeof(spec), "%s", strategies);
+	for (tok = strtok(spec, ","); tok; tok = strtok(NULL, ",")) {
+		colon = strchr(tok, ':');
+		if (!colon) {
+			printf("bad strategy share %s (want name:percent)\n", tok);
+			return 1;
+		}
+		pct = strtod(colon + 1, &endp);
+		if (endp == colon + 1 || *endp != '\0' || pct <= 0) {
+			printf("bad percent for %.*s: %s\n",
+			       (int)(colon - tok), tok, colon + 1);
+			return 1;
+		}
+		pct_sum += pct;
+	}
+	if (pct_sum > 100.001) {
+		printf("strategy shares sum to %.2f%%, cannot exceed 100%%\n", pct_sum);
+		return 1;
+	}
+	rbs_store_dir(tmp, sizeof(tmp), NULL);
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(tmp, "w");
+	if (!f) {
+		printf("cannot write allocations\n");
+		return 1;
+	}
+	snprintf(spec, sizeof(spec), "%s", strategies);
+	for (tok = strtok(spec, ","); tok; tok = strtok(NULL, ",")) {
+		colon = strchr(tok, ':');
+		*colon = '\0';
+		fprintf(f, "%s=%f\n", tok, capital * atof(colon + 1) / 100.0);
+		printf("%-12s %12.2f\n", tok, capital * atof(colon + 1) / 100.0);
+	}
+	fclose(f);
+	rename(tmp, path);
+	return 0;
+}
+
+#endif
@@ -0,0 +1,83 @@
+#ifndef RBS_ALLOCATION_H
+#define RBS_ALLOCATION_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+#include "client.h"
+#include "positions.h"
+
+/* Portfolio allocation: each asset's share of patrimony from the
+ * balances file, plus long/short exposure from open positions, as a
+ * table with a bar per row. Strategy budgets live under
+ * robson allocate; this is the where-is-the-money view. */
+
+void rbs_allocation_bar(double pct) {
+	int bars = (int)(pct / 2.5 + 0.5), i;	/* 40 cols = 100% */
+	if (bars > 40) bars = 40;
+	for (i = 0; i < bars; i++) putchar('#');
+	putchar('\n');
+}
+
+int rbs_allocation_cmd(int argc, char *argv[]) {
+	static rbs_position pos[64];
+	rbs_reb_line bals[RBS_REB_MAX];
+	double total = 0, lng = 0, shrt = 0;
+	int json = 0, n, npos, i;
+	for (i = 1; i < argc; i++)
+		if (strcmp(argv[i], "--json") == 0) json = 1;
+	n = rbs_balances_load(bals, RBS_REB_MAX);
+	if (n <= 0) {
+		printf("no balances (write them to ~/.robson/balances)\n");
+		return 1;
+	}
+	for (i = 0; i < n; i++) {
+		if (rbs_reb_price(&bals[i]) != 0) {
+			printf("no price for %s; run the feed or set a quote in "
+			       "~/.robson/feed\n", bals[i].asset);
+			return 1;
+		}
+		bals[i].notional = bals[i].qty * bals[i].price;
+		total += bals[i].notional;
+	}
+	if (total <= 0) {
+		printf("patrimony is zero\n");
+		return 1;
+	}
+	npos = rbs_positions_build(pos, 64);
+	for (i = 0; i < npos; i++) {
+		if (pos[i].qty > 0) lng += pos[i].cost;
+		else if (pos[i].qty < 0) shrt += -pos[i].cost;
+	}
+	if (json) {
+		printf("{\"patrimony\":%.2f,\"long_exposure\":%.2f,"
+		       "\"short_exposure\":%.2f,\"assets\":[", total, lng, shrt);
+		for (i = 0; i < n; i++)
+			printf("%s{\"asset\":\"%s\",\"qty\":%.8f,\"value\":%.2f,"
+			       "\"pct\":%.2f}", i ? "," : "", bals[i].asset,
+			       bals[i].qty, bals[i].notional,
+			       bals[i].notional / total * 100.0);
+		printf("]}\n");
+		return 0;
+	}
+	printf("patrimony: %.2f USDT\n", total);
+	printf("%-8s %14s %12s %7s  %s\n", "ASSET", "QTY", "VALUE", "PCT",
+	       "");
+	for (i = 0; i < n; i++) {
+		double pct = bals[i].notional / total * 100.0;
+		printf("%-8s %14.8g %12.2f %6.2f%%  ", bals[i].asset,
+		       bals[i].qty, bals[i].notional, pct);
+		rbs_allocation_bar(pct);
+	}
+	if (npos) {
+		printf("\nexposure from open positions (entry cost):\n");
+		printf("  long  %12.2f USDT (%.2f%% of patrimony)\n", lng,
+		       lng / total * 100.0);
+		if (shrt > 0)
+			printf("  short %12.2f USDT (%.2f%% of patrimony)\n", shrt,
+			       shrt / total * 100.0);
+	}
+	return 0;
+}
+
+#endif
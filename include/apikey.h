@@ -0,0 +1,134 @@
+#ifndef RBS_APIKEY_H
+#define RBS_APIKEY_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+
+/* API keys for the gateway. ~/.robson/apikeys (0600) holds one
+ * key=scope line per key; as soon as any key exists serve-api demands
+ * X-Api-Key on every request. read lets a key hit the data routes,
+ * write additionally unlocks /validate and /execute. Rotation is
+ * create-new + revoke-old. */
+
+void rbs_apikeys_path(char *out, size_t n) {
+	{ char root_[512]; rbs_state_root(root_, sizeof(root_)); snprintf(out, n, "%s/apikeys", root_); }
+}
+
+/* Scope for a presented key: "read", "write", or NULL. */
+const char *rbs_apikey_scope(const char *key, char *scope, size_t n) {
+	char path[640], line[160], k[96], v[32];
+	FILE *f;
+	rbs_apikeys_path(path, sizeof(path));
+	f = fopen(path, "r");
+	if (!f) return NULL;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0)
+			continue;
+		if (key && strcmp(k, key) == 0) {
+			snprintf(scope, n, "%s", v);
+			fclose(f);
+			return scope;
+		}
+	}
+	fclose(f);
+	return NULL;
+}
+
+/* Auth is armed by the file existing, not by it holding keys:
+ * revoking the last key must lock the gateway (fail closed), never
+ * silently turn authentication off. */
+int rbs_apikeys_exist(void) {
+	char path[640];
+	rbs_apikeys_path(path, sizeof(path));
+	return access(path, F_OK) == 0;
+}
+
+int rbs_apikey_cmd(int argc, char *argv[]) {
+	char path[640], tmp[700], key[80], line[160], k[96], v[32];
+	const char *scope = "read";
+	FILE *f, *o;
+	int i, kept = 0;
+	rbs_apikeys_path(path, sizeof(path));
+	if (argc >= 2 && strcmp(argv[1], "create") == 0) {
+		unsigned char rnd[12];
+		FILE *ur;
+		for (i = 2; i < argc; i++)
+			if (strcmp(argv[i], "--scope") == 0 && i + 1 < argc)
+				scope = argv[++i];
+		if (strcmp(scope, "read") != 0 && strcmp(scope, "write") != 0) {
+			printf("scope must be read or write\n");
+			return 1;
+		}
+		ur = fopen("/dev/urandom", "r");
+		if (!ur || fread(rnd, 1, sizeof(rnd), ur) != sizeof(rnd)) {
+			if (ur) fclose(ur);
+			printf("no entropy source; refusing to mint a weak key\n");
+			return 1;
+		}
+		fclose(ur);
+		snprintf(key, sizeof(key), "rbs_");
+		for (i = 0; i < (int)sizeof(rnd); i++)
+			snprintf(key + 4 + i * 2, 3, "%02x", rnd[i]);
+		f = fopen(path, "a");
+		if (!f) {
+			printf("cannot write %s\n", path);
+			return 1;
+		}
+		fchmod(fileno(f), 0600);
+		fprintf(f, "%s=%s\n", key, scope);
+		fclose(f);
+		printf("%s (scope %s)\n", key, scope);
+		printf("store it now; only the prefix is shown later\n");
+		return 0;
+	}
+	if (argc >= 2 && strcmp(argv[1], "list") == 0) {
+		f = fopen(path, "r");
+		if (!f) {
+			printf("no api keys\n");
+			return 0;
+		}
+		while (fgets(line, sizeof(line), f)) {
+			if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0)
+				continue;
+			printf("%.12s...  %s\n", k, v);
+		}
+		fclose(f);
+		return 0;
+	}
+	if (argc >= 3 && strcmp(argv[1], "revoke") == 0) {
+		f = fopen(path, "r");
+		if (!f) {
+			printf("no api keys\n");
+			return 1;
+		}
+		snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+		o = fopen(tmp, "w");
+		if (!o) {
+			fclose(f);
+			return 1;
+		}
+		fchmod(fileno(o), 0600);
+		while (fgets(line, sizeof(line), f)) {
+			char parse[160];
+			snprintf(parse, sizeof(parse), "%s", line);
+			if (rbs_kv_line(parse, k, sizeof(k), v, sizeof(v)) == 0 &&
+			    strncmp(k, argv[2], strlen(argv[2])) == 0)
+				continue;	/* revoked */
+			fputs(line, o);
+			kept++;
+		}
+		fclose(f);
+		fclose(o);
+		rename(tmp, path);
+		printf("revoked keys matching %s...\n", argv[2]);
+		return 0;
+	}
+	printf("usage: robson apikey <create [--scope read|write]|list|revoke PREFIX>\n");
+	return 1;
+}
+
+#endif
@@ -0,0 +1,134 @@
+#ifndef RBS_AUDIT_H
+#define RBS_AUDIT_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <time.h>
+#include <unistd.h>
+#include <syslog.h>
+#include "store.h"
+#include "plan.h"
+#include "http.h"
+
+/* Audit trail sync. Execution drops the full plan record (parameters,
+ * approvals, legs, result) into ~/.robson/outbox; robson plan sync
+ * delivers queued records to the backend sink and leaves failures in
+ * place for the next retry, so the CLI store and the Django side
+ * cannot silently diverge. ROBSON_AUDIT_SINK selects the adapter:
+ *
+ *   /some/dir            copy records into a directory (default)
+ *   file:/path/log       append records to one file
+ *   syslog:              one syslog line per record
+ *   http://host/path     POST each record
+ */
+
+void rbs_audit_enqueue(rbs_plan *p, const char *result) {
+	char dir[512], src[640], dst[700];
+	FILE *in, *out;
+	char buf[512];
+	size_t got;
+	rbs_store_dir(dir, sizeof(dir), "outbox");
+	rbs_plan_path(src, sizeof(src), p->id);
+	snprintf(dst, sizeof(dst), "%s/%s-%ld.audit", dir, p->id, (long)time(NULL));
+	in = fopen(src, "r");
+	if (!in) return;
+	out = fopen(dst, "w");
+	if (!out) {
+		fclose(in);
+		return;
+	}
+	fprintf(out, "result=%s\n", result);
+	while ((got = fread(buf, 1, sizeof(buf), in)) > 0)
+		fwrite(buf, 1, got, out);
+	fclose(in);
+	fclose(out);
+}
+
+/* Deliver one queued record through the configured adapter.
+ * Returns 0 on success. */
+int rbs_audit_deliver(const char *sink, const char *src, const char *name) {
+	static char record[16384];
+	char dst[900], buf[512];
+	FILE *in, *out;
+	size_t got, total = 0;
+	in = fopen(src, "r");
+	if (!in) return -1;
+	if (strncmp(sink, "file:", 5) == 0) {
+		out = fopen(sink + 5, "a");
+		if (!out) {
+			fclose(in);
+			return -1;
+		}
+		fprintf(out, "--- %s ---\n", name);
+		while ((got = fread(buf, 1, sizeof(buf), in)) > 0)
+			fwrite(buf, 1, got, out);
+		fclose(in);
+		return fclose(out) == 0 ? 0 : -1;
+	}
+	if (strncmp(sink, "syslog:", 7) == 0 ||
+	    strncmp(sink, "http://", 7) == 0) {
+		total = fread(record, 1, sizeof(record) - 1, in);
+		record[total] = '\0';
+		fclose(in);
+		if (sink[0] == 's') {
+			char *nl;
+			/* syslog wants one line */
+			for (nl = record; *nl; nl++)
+				if (*nl == '\n') *nl = ' ';
+			openlog("robson-audit", LOG_PID, LOG_USER);
+			syslog(LOG_INFO, "%s %s", name, record);
+			closelog();
+			return 0;
+		}
+		return rbs_http_post_raw(sink, record, total) / 100 == 2 ? 0 : -1;
+	}
+	/* default: copy into a sink directory */
+	snprintf(dst, sizeof(dst), "%s/%s", sink, name);
+	out = fopen(dst, "w");
+	if (!out) {
+		fclose(in);
+		return -1;
+	}
+	while ((got = fread(buf, 1, sizeof(buf), in)) > 0)
+		fwrite(buf, 1, got, out);
+	fclose(in);
+	return fclose(out) == 0 ? 0 : -1;
+}
+
+int rbs_plan_sync_cmd(void) {
+	char dir[512], src[700];
+	const char *sink = getenv("ROBSON_AUDIT_SINK");
+	DIR *d;
+	struct dirent *e;
+	int queued = 0, sent = 0, failed = 0;
+	rbs_store_dir(dir, sizeof(dir), "outbox");
+	d = opendir(dir);
+	if (!d) {
+		printf("nothing queued\n");
+		return 0;
+	}
+	while ((e = readdir(d))) {
+		if (!strstr(e->d_name, ".audit")) continue;
+		queued++;
+		if (!sink || !*sink) continue;
+		snprintf(src, sizeof(src), "%s/%s", dir, e->d_name);
+		if (rbs_audit_deliver(sink, src, e->d_name) != 0) {
+			failed++;
+			continue;
+		}
+		unlink(src);
+		sent++;
+	}
+	closedir(d);
+	if (!sink || !*sink) {
+		printf("%d record%s queued; set ROBSON_AUDIT_SINK to deliver\n",
+		       queued, queued == 1 ? "" : "s");
+		return queued ? 1 : 0;
+	}
+	printf("synced %d, failed %d, remaining %d\n", sent, failed,
+	       queued - sent);
+	return failed ? 1 : 0;
+}
+
+#endif
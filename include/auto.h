@@ -0,0 +1,142 @@
+#ifndef RBS_AUTO_H
+#define RBS_AUTO_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "plan.h"
+#include "execute.h"
+#include "daemon.h"
+#include "deadman.h"
+
+/* Signal-to-plan automation. The backend (or anything else) drops
+ * signal files - the same flat YAML as plan --file - into
+ * ~/.robson/signals; the auto daemon converts each into a plan,
+ * validates it and runs a dry execution. Live execution only happens
+ * while the --max-live budget lasts (default 0 = paper only), and a
+ * per-strategy cooldown keeps a chatty signal source from machine-
+ * gunning plans. */
+
+long rbs_cooldown_secs() {
+	const char *s = getenv("ROBSON_COOLDOWN");
+	long v = s ? rbs_parse_duration(s) : 300;
+	return v >= 0 ? v : 300;
+}
+
+/* Most recent plan creation for a strategy, 0 when none. */
+time_t rbs_auto_last_plan(const char *strategy) {
+	static rbs_plan plans[1024];
+	time_t last = 0;
+	int n, i;
+	n = rbs_plan_load_all(plans, 1024);
+	for (i = 0; i < n; i++) {
+		if (strcmp(plans[i].strategy, strategy) == 0 &&
+		    plans[i].created > last)
+			last = plans[i].created;
+	}
+	return last;
+}
+
+/* One pass over the signals directory. Returns signals handled;
+ * signals still cooling down stay in place for the next pass. */
+int rbs_auto_pass(const char *strategy_filter, int *live_budget) {
+	char dir[512], path[700], why[200];
+	char *vargv[3];
+	DIR *d;
+	struct dirent *e;
+	FILE *f;
+	rbs_plan p;
+	time_t last;
+	int handled = 0;
+	if (rbs_paused(why, sizeof(why))) {
+		printf("auto: automation paused (%s); no new entries\n", why);
+		return 0;
+	}
+	rbs_store_dir(dir, sizeof(dir), "signals");
+	d = opendir(dir);
+	if (!d) return 0;
+	while ((e = readdir(d))) {
+		char *dot = strstr(e->d_name, ".yaml");
+		if (!dot || dot[5] != '\0') continue;	/* suffix only, skip .tmp */
+		snprintf(path, sizeof(path), "%s/%s", dir, e->d_name);
+		f = fopen(path, "r");
+		if (!f) continue;
+		if (rbs_plan_parse_yaml(f, &p, e->d_name) != 0) {
+			fclose(f);
+			printf("auto: dropping malformed signal %s\n", e->d_name);
+			unlink(path);
+			continue;
+		}
+		fclose(f);
+		if (strategy_filter && strcmp(p.strategy, strategy_filter) != 0)
+			continue;
+		last = rbs_auto_last_plan(p.strategy);
+		if (last && time(NULL) - last < rbs_cooldown_secs()) {
+			printf("auto: %s cooling down (%lds left), signal %s deferred\n",
+			       p.strategy,
+			       rbs_cooldown_secs() - (time(NULL) - last),
+			       e->d_name);
+			continue;
+		}
+		rbs_plan_gen_id(p.id, sizeof(p.id));
+		p.created = time(NULL);
+		if (rbs_plan_create(&p) != 0) {
+			printf("auto: could not save plan from %s\n", e->d_name);
+			continue;
+		}
+		unlink(path);
+		printf("auto: plan %s from signal %s (%s)\n", p.id, e->d_name,
+		       p.strategy);
+		vargv[0] = (char *)"validate";
+		vargv[1] = p.id;
+		if (rbs_validate_cmd(2, vargv) != 0) {
+			handled++;
+			continue;
+		}
+		vargv[0] = (char *)"execute";
+		vargv[1] = p.id;
+		if (*live_budget > 0) {
+			vargv[2] = (char *)"--live";
+			if (rbs_execute_cmd(3, vargv) == 0)
+				(*live_budget)--;
+		} else {
+			rbs_execute_cmd(2, vargv);
+		}
+		handled++;
+	}
+	closedir(d);
+	return handled;
+}
+
+int rbs_auto_cmd(int argc, char *argv[]) {
+	const char *strategy_filter = NULL;
+	int live_budget = 0, once = 0, interval = 10, i;
+	time_t started = time(NULL);
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--strategy-id") == 0 && i + 1 < argc)
+			strategy_filter = argv[++i];
+		else if (strcmp(argv[i], "--max-live") == 0 && i + 1 < argc)
+			live_budget = atoi(argv[++i]);
+		else if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc)
+			interval = atoi(argv[++i]);
+		else if (strcmp(argv[i], "--once") == 0) once = 1;
+	}
+	if (interval < 1) interval = 1;
+	if (once)
+		return rbs_auto_pass(strategy_filter, &live_budget) >= 0 ? 0 : 1;
+	printf("auto: watching signals (%s, max-live %d, every %ds)\n",
+	       strategy_filter ? strategy_filter : "all strategies",
+	       live_budget, interval);
+	for (;;) {
+		rbs_auto_pass(strategy_filter, &live_budget);
+		rbs_daemon_heartbeat("auto", started, 0, 0, "");
+		fflush(stdout);
+		sleep(interval);
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,123 @@
+#ifndef RBS_BACKENDS_H
+#define RBS_BACKENDS_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+#include "http.h"
+#include "config.h"
+
+/* Redundant-deployment fan-in. Operators running a primary and a DR
+ * gateway list both in ROBSON_API_BASE_URLS (comma separated, falls
+ * back to the single api_base_url); robson backends polls every one
+ * - health, latest journal entry, fee schedule - and flags where the
+ * replicas disagree, which is the first sign a replica stopped
+ * following. */
+
+#define RBS_BACKENDS_MAX 4
+
+int rbs_api_bases(char bases[][256], int max) {
+	const char *list = getenv("ROBSON_API_BASE_URLS");
+	const char *single;
+	char buf[1024], *tok, *save;
+	int n = 0;
+	if (list && *list) {
+		snprintf(buf, sizeof(buf), "%s", list);
+		for (tok = strtok_r(buf, ",", &save); tok && n < max;
+		     tok = strtok_r(NULL, ",", &save)) {
+			while (*tok == ' ') tok++;
+			if (*tok) snprintf(bases[n++], 256, "%s", tok);
+		}
+		return n;
+	}
+	single = rbs_api_base();
+	if (single && max > 0) {
+		snprintf(bases[0], 256, "%s", single);
+		return 1;
+	}
+	return 0;
+}
+
+/* Latest operation id according to one backend, "" when none. */
+void rbs_backend_head(const char *base, char *out, size_t n) {
+	static char body[8192];
+	char url[512];
+	const char *idp;
+	out[0] = '\0';
+	snprintf(url, sizeof(url), "%s/operations?limit=1", base);
+	if (rbs_http_get(url, body, sizeof(body)) != 200) return;
+	idp = strstr(body, "\"id\":\"");
+	if (!idp) return;
+	idp += 6;
+	while (*idp && *idp != '"' && n > 1) {
+		*out++ = *idp++;
+		n--;
+	}
+	*out = '\0';
+}
+
+int rbs_backends_cmd(int argc, char *argv[]) {
+	static char body[4096];
+	char bases[RBS_BACKENDS_MAX][256], url[512];
+	char heads[RBS_BACKENDS_MAX][64], fees[RBS_BACKENDS_MAX][128];
+	int up[RBS_BACKENDS_MAX];
+	int json = 0, n, i, status, diverged = 0, nup = 0;
+	for (i = 1; i < argc; i++)
+		if (strcmp(argv[i], "--json") == 0) json = 1;
+	n = rbs_api_bases(bases, RBS_BACKENDS_MAX);
+	if (n == 0) {
+		printf("no backends configured: set ROBSON_API_BASE_URLS "
+		       "(comma separated) or api_base_url\n");
+		return 1;
+	}
+	for (i = 0; i < n; i++) {
+		snprintf(url, sizeof(url), "%s/healthz", bases[i]);
+		status = rbs_http_get(url, body, sizeof(body));
+		up[i] = status == 200;
+		if (up[i]) nup++;
+		heads[i][0] = fees[i][0] = '\0';
+		if (!up[i]) continue;
+		rbs_backend_head(bases[i], heads[i], sizeof(heads[i]));
+		snprintf(url, sizeof(url), "%s/fees", bases[i]);
+		if (rbs_http_get(url, body, sizeof(body)) == 200)
+			snprintf(fees[i], sizeof(fees[i]), "%s", body);
+	}
+	/* divergence = any live replica disagreeing with the first live
+	 * one on journal head or fee schedule */
+	{
+		int ref = -1;
+		for (i = 0; i < n; i++)
+			if (up[i]) { ref = i; break; }
+		for (i = 0; ref >= 0 && i < n; i++) {
+			if (!up[i] || i == ref) continue;
+			if (strcmp(heads[i], heads[ref]) != 0 ||
+			    strcmp(fees[i], fees[ref]) != 0)
+				diverged = 1;
+		}
+	}
+	if (nup < n) diverged = 1;	/* a dead replica is never healthy */
+	if (json) {
+		printf("{\"diverged\":%s,\"backends\":[", diverged ? "true" : "false");
+		for (i = 0; i < n; i++)
+			printf("%s{\"base\":\"%s\",\"up\":%s,\"head\":\"%s\"}",
+			       i ? "," : "", bases[i], up[i] ? "true" : "false",
+			       heads[i]);
+		printf("]}\n");
+		return diverged ? 2 : 0;
+	}
+	printf("%-36s %-6s %-20s\n", "BACKEND", "STATE", "JOURNAL HEAD");
+	for (i = 0; i < n; i++)
+		printf("%-36s %-6s %-20s\n", bases[i], up[i] ? "up" : "DOWN",
+		       heads[i][0] ? heads[i] : "-");
+	if (nup < n)
+		printf("%d of %d replica%s up; bring the rest back before "
+		       "trusting a failover\n", nup, n, n == 1 ? "" : "s");
+	else if (diverged)
+		printf("DIVERGENCE: replicas disagree on journal head or fee "
+		       "schedule; check replication before failing over\n");
+	else if (n > 1)
+		printf("replicas agree\n");
+	return diverged ? 2 : 0;
+}
+
+#endif
@@ -0,0 +1,163 @@
+#ifndef RBS_BACKUP_H
+#define RBS_BACKUP_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <sys/stat.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "daemon.h"
+
+/* Backup and restore of everything under ~/.robson (plans, journals,
+ * snapshots, market data) so a dead disk does not take the robot's
+ * memory with it. Archives land in ~/.robson/backups; with
+ * ROBSON_BACKUP_PASSPHRASE set they are encrypted (openssl aes-256),
+ * and with ROBSON_BACKUP_S3 set each new archive is also pushed via
+ * the aws CLI. backup daemon takes them on a schedule. */
+
+void rbs_backup_dir(char *out, size_t n) {
+	rbs_store_dir(out, n, "backups");
+}
+
+int rbs_backup_create(void) {
+	char dir[512], name[64], cmd[1400];
+	const char *pass = getenv("ROBSON_BACKUP_PASSPHRASE");
+	const char *s3 = getenv("ROBSON_BACKUP_S3");
+	time_t now = time(NULL);
+	rbs_backup_dir(dir, sizeof(dir));
+	snprintf(name, sizeof(name), "backup-%ld.tar.gz%s", (long)now,
+	         pass && *pass ? ".enc" : "");
+	if (pass && *pass) {
+		setenv("RBS_BK_PASS", pass, 1);
+		snprintf(cmd, sizeof(cmd),
+		         "tar czf - -C '%s' --exclude .robson/backups .robson | "
+		         "openssl enc -aes-256-cbc -pbkdf2 -pass env:RBS_BK_PASS "
+		         "-out '%s/%s'", rbs_home(), dir, name);
+	} else {
+		snprintf(cmd, sizeof(cmd),
+		         "tar czf '%s/%s' -C '%s' --exclude .robson/backups .robson",
+		         dir, name, rbs_home());
+	}
+	if (system(cmd) != 0) {
+		printf("backup failed\n");
+		return 1;
+	}
+	printf("backup written: %s/%s%s\n", dir, name,
+	       pass && *pass ? " (encrypted)" : "");
+	if (s3 && *s3) {
+		if (strchr(s3, '\'') || strchr(dir, '\'')) {
+			printf("upload skipped: quote in path\n");
+			return 0;
+		}
+		snprintf(cmd, sizeof(cmd), "aws s3 cp '%s/%s' '%s/%s' 2>&1",
+		         dir, name, s3, name);
+		if (system(cmd) != 0)
+			printf("upload to %s failed; archive kept locally\n", s3);
+		else
+			printf("uploaded to %s/%s\n", s3, name);
+	}
+	return 0;
+}
+
+int rbs_backup_list(void) {
+	char dir[512], path[700];
+	struct dirent *e;
+	struct stat st;
+	DIR *d;
+	int n = 0;
+	rbs_backup_dir(dir, sizeof(dir));
+	d = opendir(dir);
+	if (!d) {
+		printf("no backups\n");
+		return 0;
+	}
+	while ((e = readdir(d))) {
+		if (strncmp(e->d_name, "backup-", 7) != 0) continue;
+		snprintf(path, sizeof(path), "%s/%s", dir, e->d_name);
+		if (stat(path, &st) != 0) continue;
+		printf("%-40s %10ld bytes\n", e->d_name, (long)st.st_size);
+		n++;
+	}
+	closedir(d);
+	if (!n) printf("no backups\n");
+	return 0;
+}
+
+int rbs_backup_restore(const char *name, int force) {
+	char dir[512], path[700], cmd[1600];
+	const char *pass = getenv("ROBSON_BACKUP_PASSPHRASE");
+	int enc;
+	if (strchr(name, '/') || strchr(name, '\'') || strchr(name, '`') ||
+	    strchr(name, '$')) {
+		printf("malformed backup name\n");
+		return 1;
+	}
+	rbs_backup_dir(dir, sizeof(dir));
+	snprintf(path, sizeof(path), "%s/%s", dir, name);
+	if (access(path, R_OK) != 0) {
+		printf("no such backup %s\n", name);
+		return 1;
+	}
+	if (!force) {
+		printf("restoring overwrites the live ~/.robson stores; re-run "
+		       "with --force to proceed\n");
+		return 1;
+	}
+	enc = strstr(name, ".enc") != NULL;
+	if (enc) {
+		if (!pass || !*pass) {
+			printf("backup is encrypted; set ROBSON_BACKUP_PASSPHRASE\n");
+			return 1;
+		}
+		setenv("RBS_BK_PASS", pass, 1);
+		snprintf(cmd, sizeof(cmd),
+		         "openssl enc -d -aes-256-cbc -pbkdf2 -pass env:RBS_BK_PASS "
+		         "-in '%s' | tar xzf - -C '%s'", path, rbs_home());
+	} else {
+		snprintf(cmd, sizeof(cmd), "tar xzf '%s' -C '%s'", path, rbs_home());
+	}
+	if (system(cmd) != 0) {
+		printf("restore failed (wrong passphrase or corrupt archive?)\n");
+		return 1;
+	}
+	printf("restored %s\n", name);
+	return 0;
+}
+
+int rbs_backup_cmd(int argc, char *argv[]) {
+	if (argc >= 2 && strcmp(argv[1], "create") == 0)
+		return rbs_backup_create();
+	if (argc >= 2 && strcmp(argv[1], "list") == 0)
+		return rbs_backup_list();
+	if (argc >= 3 && strcmp(argv[1], "restore") == 0)
+		return rbs_backup_restore(argv[2],
+		                          argc >= 4 && strcmp(argv[3], "--force") == 0);
+	if (argc >= 2 && strcmp(argv[1], "daemon") == 0) {
+		long interval = 86400;
+		time_t started = time(NULL);
+		int i;
+		for (i = 2; i < argc; i++) {
+			if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc) {
+				interval = rbs_parse_duration(argv[++i]);
+				if (interval <= 0) {
+					printf("--interval wants 1h/1d style\n");
+					return 1;
+				}
+			}
+		}
+		printf("backup daemon: every %lds\n", interval);
+		fflush(stdout);
+		for (;;) {
+			rbs_backup_create();
+			rbs_daemon_heartbeat("backup", started, 0, 0, "");
+			fflush(stdout);
+			sleep(interval);
+		}
+	}
+	printf("usage: robson backup <create|list|restore NAME [--force]|daemon [--interval 1d]>\n");
+	return 1;
+}
+
+#endif
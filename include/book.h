@@ -0,0 +1,127 @@
+#ifndef RBS_BOOK_H
+#define RBS_BOOK_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+#include "store.h"
+#include "fmt.h"
+
+/* Order book ladders. The depth feeder drops "bid <price> <qty>" and
+ * "ask <price> <qty>" lines (best first) at ~/.robson/book/<SYMBOL>;
+ * robson book renders both sides with cumulative size bars, the mid
+ * and the spread, optionally refreshing with --watch. */
+
+typedef struct {
+	double price;
+	double qty;
+} rbs_level;
+
+int rbs_book_load(const char *symbol, rbs_level *bids, rbs_level *asks,
+                  int max, int *nbids, int *nasks) {
+	char dir[512], path[640], line[128], side[8];
+	double px, q;
+	FILE *f;
+	rbs_store_dir(dir, sizeof(dir), "book");
+	snprintf(path, sizeof(path), "%s/%s", dir, symbol);
+	f = fopen(path, "r");
+	if (!f) return -1;
+	*nbids = *nasks = 0;
+	while (fgets(line, sizeof(line), f)) {
+		if (sscanf(line, "%7s %lf %lf", side, &px, &q) != 3) continue;
+		if (px <= 0 || q <= 0) continue;
+		if (strcmp(side, "bid") == 0 && *nbids < max)
+			bids[(*nbids)++] = (rbs_level){ px, q };
+		else if (strcmp(side, "ask") == 0 && *nasks < max)
+			asks[(*nasks)++] = (rbs_level){ px, q };
+	}
+	fclose(f);
+	return (*nbids || *nasks) ? 0 : -1;
+}
+
+void rbs_book_bar(double cum, double total, char *out, size_t n) {
+	int width = total > 0 ? (int)(cum / total * 20.0 + 0.5) : 0;
+	size_t i;
+	if (width > 20) width = 20;
+	for (i = 0; i < (size_t)width && i < n - 1; i++) out[i] = '#';
+	out[i] = '\0';
+}
+
+void rbs_book_draw(const char *symbol, int depth) {
+	rbs_level bids[64], asks[64];
+	char ps[32], bar[24];
+	int nbids, nasks, i;
+	double bcum = 0, acum = 0, btot = 0, atot = 0;
+	if (rbs_book_load(symbol, bids, asks, 64, &nbids, &nasks) != 0) {
+		printf("no depth for %s (is the depth feeder writing "
+		       "~/.robson/book/%s?)\n", symbol, symbol);
+		return;
+	}
+	if (depth < nbids) nbids = depth;
+	if (depth < nasks) nasks = depth;
+	for (i = 0; i < nbids; i++) btot += bids[i].qty;
+	for (i = 0; i < nasks; i++) atot += asks[i].qty;
+	printf("%s depth %d\n", symbol, depth);
+	printf("%-20s %12s | %-12s %s\n", "CUM BIDS", "BID", "ASK", "CUM ASKS");
+	for (i = 0; i < (nbids > nasks ? nbids : nasks); i++) {
+		char left[64] = "", right[64] = "";
+		if (i < nbids) {
+			bcum += bids[i].qty;
+			rbs_book_bar(bcum, btot, bar, sizeof(bar));
+			rbs_fmt_px(symbol, bids[i].price, ps, sizeof(ps));
+			snprintf(left, sizeof(left), "%-20s %12s", bar, ps);
+		} else {
+			snprintf(left, sizeof(left), "%-20s %12s", "", "");
+		}
+		if (i < nasks) {
+			acum += asks[i].qty;
+			rbs_book_bar(acum, atot, bar, sizeof(bar));
+			rbs_fmt_px(symbol, asks[i].price, ps, sizeof(ps));
+			snprintf(right, sizeof(right), "%-12s %s", ps, bar);
+		}
+		printf("%s | %s\n", left, right);
+	}
+	if (nbids && nasks) {
+		rbs_fmt_px(symbol, (bids[0].price + asks[0].price) / 2.0,
+		           ps, sizeof(ps));
+		printf("mid %s  spread %.4f (%.3f%%)\n", ps,
+		       asks[0].price - bids[0].price,
+		       (asks[0].price - bids[0].price) / bids[0].price * 100.0);
+	}
+}
+
+int rbs_book_cmd(int argc, char *argv[]) {
+	const char *symbol = NULL;
+	int depth = 20, watch = 0, interval = 2, i;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--depth") == 0 && i + 1 < argc)
+			depth = atoi(argv[++i]);
+		else if (strcmp(argv[i], "--watch") == 0) {
+			watch = 1;
+			if (i + 1 < argc && argv[i + 1][0] >= '0' &&
+			    argv[i + 1][0] <= '9')
+				interval = atoi(argv[++i]);
+		}
+		else symbol = argv[i];
+	}
+	if (!symbol || !rbs_symbol_ok(symbol)) {
+		printf("usage: robson book <symbol> [--depth 20] [--watch [N]]\n");
+		return 1;
+	}
+	if (depth < 1 || depth > 64) depth = 20;
+	if (interval < 1) interval = 1;
+	if (!watch) {
+		rbs_book_draw(symbol, depth);
+		return 0;
+	}
+	for (;;) {
+		printf("\033[2J\033[H");
+		rbs_book_draw(symbol, depth);
+		printf("\n(refreshing every %ds, Ctrl-C to quit)\n", interval);
+		fflush(stdout);
+		sleep(interval);
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,211 @@
+#ifndef RBS_BREAKER_H
+#define RBS_BREAKER_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <time.h>
+#include <math.h>
+#include <unistd.h>
+#include "store.h"
+#include "price.h"
+#include "daemon.h"
+
+/* Per-symbol circuit breaker. The watch daemon samples the feed and
+ * trips a halt file (~/.robson/halt/<SYMBOL>) when the move over the
+ * window exceeds the bound; a halted symbol refuses new live entries
+ * (exits and stops still run) until the market calms down for a full
+ * window or the operator clears it by hand. Bounds:
+ *
+ *   ROBSON_BREAKER_MAX_MOVE_PCT  move over the window that trips (10)
+ *   ROBSON_BREAKER_WINDOW        observation window (5m)
+ */
+
+double rbs_breaker_max_move_pct(void) {
+	const char *s = getenv("ROBSON_BREAKER_MAX_MOVE_PCT");
+	double v = s ? atof(s) : 10.0;
+	return v > 0 ? v : 10.0;
+}
+
+long rbs_breaker_window(void) {
+	const char *s = getenv("ROBSON_BREAKER_WINDOW");
+	long v = s ? rbs_parse_duration(s) : 300;
+	return v > 0 ? v : 300;
+}
+
+void rbs_halt_path(char *out, size_t n, const char *symbol) {
+	char dir[512];
+	rbs_store_dir(dir, sizeof(dir), "halt");
+	snprintf(out, n, "%s/%s", dir, symbol);
+}
+
+/* Nonzero when the symbol is halted; reason into buf when wanted. */
+int rbs_halted(const char *symbol, char *reason, size_t n) {
+	char path[640], line[200];
+	FILE *f;
+	if (!rbs_symbol_ok(symbol)) return 0;
+	rbs_halt_path(path, sizeof(path), symbol);
+	f = fopen(path, "r");
+	if (!f) return 0;
+	if (reason && n) {
+		reason[0] = '\0';
+		if (fgets(line, sizeof(line), f)) {
+			char *sp = strchr(line, ' ');
+			char *nl = strchr(line, '\n');
+			if (nl) *nl = '\0';
+			snprintf(reason, n, "%s", sp ? sp + 1 : line);
+		}
+	}
+	fclose(f);
+	return 1;
+}
+
+void rbs_breaker_trip(const char *symbol, const char *reason) {
+	char path[640];
+	FILE *f;
+	rbs_halt_path(path, sizeof(path), symbol);
+	f = fopen(path, "w");
+	if (!f) return;
+	fprintf(f, "%ld %s\n", (long)time(NULL), reason);
+	fclose(f);
+	fprintf(stderr, "CIRCUIT BREAKER: %s halted (%s)\n", symbol, reason);
+}
+
+int rbs_breaker_clear(const char *symbol) {
+	char path[640];
+	if (!rbs_symbol_ok(symbol)) return -1;
+	rbs_halt_path(path, sizeof(path), symbol);
+	return unlink(path);
+}
+
+/* One sample of one symbol: append to the history, trim the window,
+ * trip on an outsized move, clear a halt once the window is calm.
+ * Returns 1 when the symbol tripped on this sample. */
+int rbs_breaker_sample(const char *symbol) {
+	char dir[512], path[700], tmp[760], line[64];
+	long now = time(NULL), cutoff = now - rbs_breaker_window();
+	double px, lo = 0, hi = 0, first = 0;
+	FILE *f, *out;
+	if (rbs_price_get(symbol, &px) != 0) return 0;
+	rbs_store_dir(dir, sizeof(dir), "volhist");
+	snprintf(path, sizeof(path), "%s/%s", dir, symbol);
+	f = fopen(path, "a");
+	if (f) {
+		fprintf(f, "%ld %f\n", now, px);
+		fclose(f);
+	}
+	/* rewrite the window, tracking its extremes */
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(path, "r");
+	out = fopen(tmp, "w");
+	if (!f || !out) {
+		if (f) fclose(f);
+		if (out) fclose(out);
+		return 0;
+	}
+	while (fgets(line, sizeof(line), f)) {
+		long t;
+		double p;
+		if (sscanf(line, "%ld %lf", &t, &p) != 2 || t < cutoff)
+			continue;
+		if (first == 0) first = p;
+		if (lo == 0 || p < lo) lo = p;
+		if (p > hi) hi = p;
+		fputs(line, out);
+	}
+	fclose(f);
+	fclose(out);
+	rename(tmp, path);
+	if (first <= 0 || lo <= 0) return 0;
+	if ((hi - lo) / lo * 100.0 > rbs_breaker_max_move_pct()) {
+		if (!rbs_halted(symbol, NULL, 0)) {
+			char reason[96];
+			snprintf(reason, sizeof(reason),
+			         "moved %.2f%% inside %lds (limit %.2f%%)",
+			         (hi - lo) / lo * 100.0, rbs_breaker_window(),
+			         rbs_breaker_max_move_pct());
+			rbs_breaker_trip(symbol, reason);
+			return 1;
+		}
+		return 0;
+	}
+	/* calm for a full window: the halt lifts itself */
+	if (rbs_halted(symbol, NULL, 0)) {
+		rbs_breaker_clear(symbol);
+		fprintf(stderr, "circuit breaker: %s calmed down, halt lifted\n",
+		        symbol);
+	}
+	return 0;
+}
+
+int rbs_breaker_cmd(int argc, char *argv[]) {
+	char dir[512], reason[200];
+	DIR *d;
+	struct dirent *e;
+	int i;
+	if (argc >= 2 && strcmp(argv[1], "status") == 0) {
+		int any = 0;
+		rbs_store_dir(dir, sizeof(dir), "halt");
+		d = opendir(dir);
+		if (d) {
+			while ((e = readdir(d))) {
+				if (e->d_name[0] == '.') continue;
+				if (!rbs_halted(e->d_name, reason, sizeof(reason)))
+					continue;
+				printf("%-10s HALTED  %s\n", e->d_name, reason);
+				any = 1;
+			}
+			closedir(d);
+		}
+		if (!any) printf("no symbols halted\n");
+		return 0;
+	}
+	if (argc >= 3 && strcmp(argv[1], "clear") == 0) {
+		if (rbs_breaker_clear(argv[2]) != 0) {
+			printf("%s is not halted\n", argv[2]);
+			return 1;
+		}
+		printf("%s cleared by operator\n", argv[2]);
+		return 0;
+	}
+	if (argc >= 3 && strcmp(argv[1], "watch") == 0) {
+		long interval = 10;
+		time_t started = time(NULL);
+		int nsym = 0, once = 0;
+		const char *syms[16];
+		for (i = 2; i < argc; i++) {
+			if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc) {
+				interval = rbs_parse_duration(argv[++i]);
+				if (interval <= 0) {
+					printf("--interval wants 10s/1m style\n");
+					return 1;
+				}
+			}
+			else if (strcmp(argv[i], "--once") == 0) once = 1;
+			else if (rbs_symbol_ok(argv[i]) && nsym < 16)
+				syms[nsym++] = argv[i];
+		}
+		if (!nsym) {
+			printf("usage: robson breaker watch SYMBOL... [--interval 10s]\n");
+			return 1;
+		}
+		if (!once) {
+			printf("circuit breaker: %.2f%% over %lds on %d symbol%s\n",
+			       rbs_breaker_max_move_pct(), rbs_breaker_window(),
+			       nsym, nsym == 1 ? "" : "s");
+			fflush(stdout);
+		}
+		for (;;) {
+			for (i = 0; i < nsym; i++)
+				rbs_breaker_sample(syms[i]);
+			if (once) return 0;
+			rbs_daemon_heartbeat("breaker", started, 0, 0, "");
+			fflush(stderr);
+			sleep(interval);
+		}
+	}
+	printf("usage: robson breaker <status|watch SYMBOL...|clear SYMBOL>\n");
+	return 1;
+}
+
+#endif
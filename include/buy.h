@@ -0,0 +1,11 @@
+#ifndef RBS_BUY_H
+#define RBS_BUY_H
+#include <stdio.h>
+#include "i18n.h"
+
+void rbs_openscreen_buy() {
+	printf("%s", rbs_tr("buy.title"));
+	printf("%s", rbs_tr("buy.hint"));
+}
+
+#endif
@@ -0,0 +1,117 @@
+#ifndef RBS_CANDLES_H
+#define RBS_CANDLES_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+#include "fmt.h"
+
+/* Candles from the kline feeder: ~/.robson/klines/<SYMBOL>-<interval>
+ * holds "epoch open high low close" lines, oldest first. The chart
+ * renders each candle as a column - '#' through the body, '|' for
+ * the wicks - over a 12-row price scale; --csv/--json emit the raw
+ * series for scripts. */
+
+typedef struct {
+	long t;
+	double o, h, l, c;
+} rbs_candle;
+
+int rbs_candles_load(const char *symbol, const char *interval,
+                     rbs_candle *out, int max) {
+	char dir[512], path[700], line[160];
+	FILE *f;
+	int n = 0;
+	rbs_store_dir(dir, sizeof(dir), "klines");
+	snprintf(path, sizeof(path), "%s/%s-%s", dir, symbol, interval);
+	f = fopen(path, "r");
+	if (!f) return -1;
+	while (fgets(line, sizeof(line), f) && n < max) {
+		if (sscanf(line, "%ld %lf %lf %lf %lf", &out[n].t, &out[n].o,
+		           &out[n].h, &out[n].l, &out[n].c) != 5)
+			continue;
+		if (out[n].h < out[n].l || out[n].l <= 0) continue;
+		n++;
+	}
+	fclose(f);
+	return n;
+}
+
+#define RBS_CHART_ROWS 12
+
+void rbs_candles_chart(const char *symbol, rbs_candle *cs, int n) {
+	char ps[32];
+	double lo = cs[0].l, hi = cs[0].h, step;
+	int r, i;
+	for (i = 0; i < n; i++) {
+		if (cs[i].l < lo) lo = cs[i].l;
+		if (cs[i].h > hi) hi = cs[i].h;
+	}
+	if (hi <= lo) hi = lo + 1;
+	step = (hi - lo) / RBS_CHART_ROWS;
+	for (r = RBS_CHART_ROWS - 1; r >= 0; r--) {
+		double band_lo = lo + r * step, band_hi = band_lo + step;
+		rbs_fmt_px(symbol, band_hi, ps, sizeof(ps));
+		printf("%12s |", ps);
+		for (i = 0; i < n; i++) {
+			double body_lo = cs[i].o < cs[i].c ? cs[i].o : cs[i].c;
+			double body_hi = cs[i].o < cs[i].c ? cs[i].c : cs[i].o;
+			if (body_hi >= band_lo && body_lo < band_hi)
+				putchar('#');
+			else if (cs[i].h >= band_lo && cs[i].l < band_hi)
+				putchar('|');
+			else
+				putchar(' ');
+		}
+		printf("\n");
+	}
+	rbs_fmt_px(symbol, lo, ps, sizeof(ps));
+	printf("%12s +", ps);
+	for (i = 0; i < n; i++) putchar('-');
+	printf("\n");
+}
+
+int rbs_candles_cmd(int argc, char *argv[]) {
+	static rbs_candle cs[512];
+	const char *symbol = NULL, *interval = "1h";
+	int limit = 100, json = 0, csv = 0, n, i, from;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc) interval = argv[++i];
+		else if (strcmp(argv[i], "--limit") == 0 && i + 1 < argc) limit = atoi(argv[++i]);
+		else if (strcmp(argv[i], "--json") == 0) json = 1;
+		else if (strcmp(argv[i], "--csv") == 0) csv = 1;
+		else symbol = argv[i];
+	}
+	if (!symbol || !rbs_symbol_ok(symbol)) {
+		printf("usage: robson candles <symbol> [--interval 1h] [--limit 100] [--csv|--json]\n");
+		return 1;
+	}
+	if (limit < 1 || limit > 512) limit = 100;
+	n = rbs_candles_load(symbol, interval, cs, 512);
+	if (n <= 0) {
+		printf("no klines for %s %s (feeder writes ~/.robson/klines/%s-%s)\n",
+		       symbol, interval, symbol, interval);
+		return 1;
+	}
+	from = n > limit ? n - limit : 0;
+	if (csv) {
+		printf("time,open,high,low,close\n");
+		for (i = from; i < n; i++)
+			printf("%ld,%.8f,%.8f,%.8f,%.8f\n", cs[i].t, cs[i].o,
+			       cs[i].h, cs[i].l, cs[i].c);
+		return 0;
+	}
+	if (json) {
+		for (i = from; i < n; i++)
+			printf("{\"t\":%ld,\"o\":%.8f,\"h\":%.8f,\"l\":%.8f,"
+			       "\"c\":%.8f}\n", cs[i].t, cs[i].o, cs[i].h,
+			       cs[i].l, cs[i].c);
+		return 0;
+	}
+	printf("%s %s, %d candle%s\n", symbol, interval, n - from,
+	       n - from == 1 ? "" : "s");
+	rbs_candles_chart(symbol, cs + from, n - from);
+	return 0;
+}
+
+#endif
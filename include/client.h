@@ -0,0 +1,117 @@
+#ifndef RBS_CLIENT_H
+#define RBS_CLIENT_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "price.h"
+#include "ratelimit.h"
+
+/* The client layer: one typed surface over the backend data every
+ * command and daemon consumes - balances, quotes, patrimony - so
+ * callers stop hand-rolling file reads. Today it is backed by the
+ * local stores and the price cache; an HTTP backend slots in behind
+ * these functions without touching any caller. */
+
+#define RBS_REB_MAX 8
+
+typedef struct {
+	char asset[12];
+	double qty;
+	double pct;		/* target share, 0..100 (rebalance) */
+	double price;		/* in USDT */
+	double notional;
+} rbs_reb_line;
+
+int rbs_balances_load(rbs_reb_line *out, int max) {
+	char path[640], line[128], key[64], val[64];
+	FILE *f;
+	int n = 0;
+	rbs_state_path(path, sizeof(path), "balances");
+	f = fopen(path, "r");
+	if (!f) return -1;
+	while (fgets(line, sizeof(line), f) && n < max) {
+		if (line[0] == '#' || line[0] == '\n') continue;
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		snprintf(out[n].asset, sizeof(out[n].asset), "%s", key);
+		out[n].qty = atof(val);
+		n++;
+	}
+	fclose(f);
+	return n;
+}
+
+/* Retry policy for transient failures (source briefly unavailable or
+ * mid-rewrite): exponential backoff with jitter so concurrent
+ * commands do not retry in lockstep. */
+int rbs_retry_attempts() {
+	const char *s = getenv("ROBSON_RETRY_ATTEMPTS");
+	int v = s ? atoi(s) : 3;
+	return v > 0 ? v : 3;
+}
+
+long rbs_retry_backoff_ms() {
+	const char *s = getenv("ROBSON_RETRY_BACKOFF_MS");
+	long v = s ? atol(s) : 100;
+	return v > 0 ? v : 100;
+}
+
+int rbs_client_price(const char *symbol, double *out) {
+	static int seeded;
+	long backoff = rbs_retry_backoff_ms();
+	int attempts = rbs_retry_attempts(), i;
+	if (!seeded) {
+		srand((unsigned)(getpid() ^ time(NULL)));
+		seeded = 1;
+	}
+	for (i = 0; i < attempts; i++) {
+		rbs_rate_take("exchange");
+		if (rbs_price_get(symbol, out) == 0) return 0;
+		if (i + 1 < attempts) {
+			usleep((backoff + rand() % (backoff / 2 + 1)) * 1000);
+			backoff *= 2;
+		}
+	}
+	return -1;
+}
+
+/* Price an asset against USDT; usdt itself is 1 by definition. */
+int rbs_reb_price(rbs_reb_line *l) {
+	char sym[24];
+	size_t i;
+	if (strcasecmp(l->asset, "usdt") == 0) {
+		l->price = 1.0;
+		return 0;
+	}
+	for (i = 0; l->asset[i] && i < sizeof(sym) - 5; i++)
+		sym[i] = l->asset[i] >= 'a' && l->asset[i] <= 'z'
+		       ? l->asset[i] - 32 : l->asset[i];
+	sym[i] = '\0';
+	strcat(sym, "USDT");
+	return rbs_client_price(sym, &l->price);
+}
+
+int rbs_client_balances(rbs_reb_line *out, int max) {
+	return rbs_balances_load(out, max);
+}
+
+/* Total portfolio value in USDT. Returns -1 when balances are missing
+ * or an asset cannot be priced. */
+int rbs_client_patrimony(double *out) {
+	rbs_reb_line bals[RBS_REB_MAX];
+	double total = 0;
+	int n, i;
+	n = rbs_balances_load(bals, RBS_REB_MAX);
+	if (n <= 0) return -1;
+	for (i = 0; i < n; i++) {
+		if (rbs_reb_price(&bals[i]) != 0) return -1;
+		total += bals[i].qty * bals[i].price;
+	}
+	*out = total;
+	return 0;
+}
+
+#endif
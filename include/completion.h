@@ -0,0 +1,76 @@
+#ifndef RBS_COMPLETION_H
+#define RBS_COMPLETION_H
+#include <stdio.h>
+#include <string.h>
+#include <dirent.h>
+#include "store.h"
+#include "plan.h"
+
+/* Dynamic shell completion. robson completion bash emits a script
+ * that calls the hidden __complete helper, so <TAB> offers real
+ * symbols (whatever the feed and symbol configs know) and real plan
+ * ids from the local store instead of filenames. */
+
+void rbs_complete_dir(const char *sub, const char *strip_suffix) {
+	char dir[512];
+	DIR *d;
+	struct dirent *e;
+	char *dot;
+	rbs_store_dir(dir, sizeof(dir), sub);
+	d = opendir(dir);
+	if (!d) return;
+	while ((e = readdir(d))) {
+		if (e->d_name[0] == '.') continue;
+		if (strip_suffix) {
+			dot = strstr(e->d_name, strip_suffix);
+			if (!dot || dot[strlen(strip_suffix)] != '\0') continue;
+			printf("%.*s\n", (int)(dot - e->d_name), e->d_name);
+		} else {
+			printf("%s\n", e->d_name);
+		}
+	}
+	closedir(d);
+}
+
+int rbs_complete_cmd(int argc, char *argv[]) {
+	if (argc < 2) return 1;
+	if (strcmp(argv[1], "symbols") == 0) {
+		rbs_complete_dir("feed", NULL);
+		rbs_complete_dir("symbols", ".conf");
+		return 0;
+	}
+	if (strcmp(argv[1], "plans") == 0) {
+		rbs_complete_dir("plans", ".plan");
+		return 0;
+	}
+	return 1;
+}
+
+int rbs_completion_cmd(int argc, char *argv[]) {
+	if (argc < 2 || strcmp(argv[1], "bash") != 0) {
+		printf("usage: robson completion bash   (eval \"$(robson completion bash)\")\n");
+		return 1;
+	}
+	printf(
+"_robson() {\n"
+"  local cur prev\n"
+"  cur=\"${COMP_WORDS[COMP_CWORD]}\"\n"
+"  prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n"
+"  case \"$prev\" in\n"
+"    price|watch)\n"
+"      COMPREPLY=($(compgen -W \"$(robson __complete symbols)\" -- \"$cur\"));;\n"
+"    validate|execute|approve|show|delete|amend|export)\n"
+"      COMPREPLY=($(compgen -W \"$(robson __complete plans)\" -- \"$cur\"));;\n"
+"    robson)\n"
+"      COMPREPLY=($(compgen -W \"plan validate execute approve explain price\\\n"
+" watch top bugreport flags snapshot diff whatif stress allocate auto\\\n"
+" serve-api account positions operations margin-status margin-positions\\\n"
+" margin-buy compare-fees order script indicator http auth apikey backup\\\n"
+" vacuum config env fire-drill sync completion\" -- \"$cur\"));;\n"
+"  esac\n"
+"}\n"
+"complete -F _robson robson\n");
+	return 0;
+}
+
+#endif
@@ -0,0 +1,320 @@
+#ifndef RBS_CONFIG_H
+#define RBS_CONFIG_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <unistd.h>
+#include "store.h"
+
+/* ~/.robson/config.yaml: two-level YAML with named profiles,
+ *
+ *   profile: default
+ *   default:
+ *     api_base_url: http://127.0.0.1:8741
+ *     symbol: BTCUSDT
+ *     client_id: 7
+ *   work:
+ *     api_base_url: https://robson.example
+ *
+ * selected via --profile / ROBSON_PROFILE / the top-level profile
+ * key. Environment variables still win over config so one-off
+ * overrides keep working. */
+
+/* Load KEY=VALUE lines from a project-local .env into the
+ * environment without overriding anything already set - matching the
+ * backend's python-decouple conventions so dev setups line up. */
+void rbs_dotenv_load(void) {
+	char line[512], key[128], val[384];
+	FILE *f = fopen(".env", "r");
+	if (!f) return;
+	while (fgets(line, sizeof(line), f)) {
+		if (line[0] == '#' || line[0] == '\n') continue;
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		setenv(key, val, 0);
+	}
+	fclose(f);
+}
+
+/* Expand ${VAR} references in a config value in place. Unset
+ * variables expand to nothing. */
+void rbs_config_expand(char *buf, size_t n) {
+	char out[512];
+	size_t o = 0, i = 0;
+	while (buf[i] && o + 1 < sizeof(out) && o + 1 < n) {
+		if (buf[i] == '$' && buf[i + 1] == '{') {
+			char name[128];
+			const char *v;
+			size_t k = 0;
+			i += 2;
+			while (buf[i] && buf[i] != '}' && k < sizeof(name) - 1)
+				name[k++] = buf[i++];
+			name[k] = '\0';
+			if (buf[i] == '}') i++;
+			v = getenv(name);
+			while (v && *v && o + 1 < sizeof(out) && o + 1 < n)
+				out[o++] = *v++;
+		} else {
+			out[o++] = buf[i++];
+		}
+	}
+	out[o] = '\0';
+	snprintf(buf, n, "%s", out);
+}
+
+void rbs_config_path(char *out, size_t n) {
+	const char *xdg = getenv("XDG_CONFIG_HOME");
+	char legacy[512];
+	if (xdg && *xdg) {
+		snprintf(out, n, "%s/robson", xdg);
+		mkdir(xdg, 0700);
+		mkdir(out, 0700);
+		snprintf(out, n, "%s/robson/config.yaml", xdg);
+		snprintf(legacy, sizeof(legacy), "%s/.robson/config.yaml", rbs_home());
+		if (access(out, F_OK) != 0 && access(legacy, F_OK) == 0 &&
+		    rename(legacy, out) == 0)
+			fprintf(stderr, "migrated %s -> %s\n", legacy, out);
+		return;
+	}
+	snprintf(out, n, "%s/.robson/config.yaml", rbs_home());
+}
+
+/* Active profile name into out (default "default"). */
+void rbs_config_profile(char *out, size_t n) {
+	char path[640], line[256];
+	const char *env = getenv("ROBSON_PROFILE");
+	FILE *f;
+	snprintf(out, n, "default");
+	if (env && *env) {
+		snprintf(out, n, "%s", env);
+		return;
+	}
+	rbs_config_path(path, sizeof(path));
+	f = fopen(path, "r");
+	if (!f) return;
+	while (fgets(line, sizeof(line), f)) {
+		if (strncmp(line, "profile:", 8) == 0) {
+			char *v = line + 8, *nl;
+			while (*v == ' ') v++;
+			nl = strchr(v, '\n');
+			if (nl) *nl = '\0';
+			if (*v) snprintf(out, n, "%s", v);
+			break;
+		}
+	}
+	fclose(f);
+}
+
+/* Value of key in the active profile; 0 when found. */
+int rbs_config_get(const char *key, char *out, size_t n) {
+	char path[640], line[256], profile[64], want[80];
+	FILE *f;
+	int in_profile = 0;
+	size_t klen;
+	rbs_config_profile(profile, sizeof(profile));
+	snprintf(want, sizeof(want), "%s:", profile);
+	rbs_config_path(path, sizeof(path));
+	f = fopen(path, "r");
+	if (!f) return -1;
+	klen = strlen(key);
+	while (fgets(line, sizeof(line), f)) {
+		if (line[0] == '#' || line[0] == '\n') continue;
+		if (line[0] != ' ') {
+			in_profile = strncmp(line, want, strlen(want)) == 0 &&
+			             (line[strlen(want)] == '\n' ||
+			              line[strlen(want)] == '\0' ||
+			              line[strlen(want)] == ' ');
+			continue;
+		}
+		if (!in_profile) continue;
+		{
+			char *p = line;
+			while (*p == ' ' || *p == '\t') p++;
+			if (strncmp(p, key, klen) == 0 && p[klen] == ':') {
+				char *v = p + klen + 1, *nl;
+				while (*v == ' ') v++;
+				nl = strchr(v, '\n');
+				if (nl) *nl = '\0';
+				snprintf(out, n, "%s", v);
+				rbs_config_expand(out, n);
+				fclose(f);
+				return 0;
+			}
+		}
+	}
+	fclose(f);
+	return -1;
+}
+
+/* The API base: environment first, then the profile's api_base_url. */
+const char *rbs_api_base(void) {
+	static char buf[512];
+	const char *b = getenv("ROBSON_API_BASE_URL");
+	if (b && *b) return b;
+	b = getenv("ROBSON_API_URL");
+	if (b && *b) return b;
+	if (rbs_config_get("api_base_url", buf, sizeof(buf)) == 0 && buf[0])
+		return buf;
+	return NULL;
+}
+
+/* Profile default for a key that commands fall back to, e.g. the
+ * default symbol. Returns fallback when unset. */
+const char *rbs_config_default(const char *key, const char *fallback) {
+	static char buf[256];
+	if (rbs_config_get(key, buf, sizeof(buf)) == 0 && buf[0])
+		return buf;
+	return fallback;
+}
+
+/* Which environment this session targets: --env / ROBSON_ENV / the
+ * profile's env key, defaulting to testnet - nothing risks real
+ * money unless someone said so explicitly. */
+const char *rbs_env(void) {
+	static char buf[32];
+	const char *e = getenv("ROBSON_ENV");
+	if (e && *e) return e;
+	if (rbs_config_get("env", buf, sizeof(buf)) == 0 && buf[0])
+		return buf;
+	return "testnet";
+}
+
+int rbs_env_is_prod(void) {
+	return strcmp(rbs_env(), "prod") == 0 ||
+	       strcmp(rbs_env(), "production") == 0;
+}
+
+/* The bound tenant. No silent default: multi-tenant operators must
+ * bind client_id in the profile (or ROBSON_CLIENT_ID) and commands
+ * that touch an account refuse to guess. */
+const char *rbs_client_id(void) {
+	static char buf[64];
+	const char *e = getenv("ROBSON_CLIENT_ID");
+	if (e && *e) return e;
+	if (rbs_config_get("client_id", buf, sizeof(buf)) == 0 && buf[0])
+		return buf;
+	return NULL;
+}
+
+/* Header line naming the active tenant, printed by account-scoped
+ * commands. */
+void rbs_client_header(void) {
+	const char *id = rbs_client_id();
+	if (id) printf("client: %s (%s)\n", id, rbs_env());
+}
+
+/* Keys the config commands accept; set validates against this list
+ * so typos don't silently create dead settings. */
+static const char *rbs_config_keys[] = {
+	"api_base_url", "symbol", "client_id", "env", "color",
+};
+#define RBS_CONFIG_NKEYS (int)(sizeof(rbs_config_keys) / sizeof(rbs_config_keys[0]))
+
+int rbs_config_key_ok(const char *key) {
+	int i;
+	for (i = 0; i < RBS_CONFIG_NKEYS; i++)
+		if (strcmp(rbs_config_keys[i], key) == 0) return 1;
+	return 0;
+}
+
+/* Rewrite config.yaml with key set to value in the active profile,
+ * preserving everything else (and creating file/profile as needed). */
+int rbs_config_set(const char *key, const char *value) {
+	char path[640], tmp[700], line[256], profile[64], want[80], dir[512];
+	FILE *in, *out;
+	int in_profile = 0, wrote = 0, have_profile = 0;
+	size_t klen = strlen(key);
+	rbs_store_dir(dir, sizeof(dir), NULL);
+	rbs_config_profile(profile, sizeof(profile));
+	snprintf(want, sizeof(want), "%s:", profile);
+	rbs_config_path(path, sizeof(path));
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	out = fopen(tmp, "w");
+	if (!out) return -1;
+	in = fopen(path, "r");
+	if (in) {
+		while (fgets(line, sizeof(line), in)) {
+			if (line[0] != ' ' && line[0] != '\n' && line[0] != '#') {
+				/* leaving the target profile without having written
+				 * the key: append it before the next section */
+				if (in_profile && !wrote) {
+					fprintf(out, "  %s: %s\n", key, value);
+					wrote = 1;
+				}
+				in_profile = strncmp(line, want, strlen(want)) == 0;
+				if (in_profile) have_profile = 1;
+				fputs(line, out);
+				continue;
+			}
+			if (in_profile) {
+				char *p = line;
+				while (*p == ' ' || *p == '\t') p++;
+				if (strncmp(p, key, klen) == 0 && p[klen] == ':') {
+					fprintf(out, "  %s: %s\n", key, value);
+					wrote = 1;
+					continue;
+				}
+			}
+			fputs(line, out);
+		}
+		fclose(in);
+	}
+	if (!have_profile)
+		fprintf(out, "%s:\n", profile);
+	if (!wrote)
+		fprintf(out, "  %s: %s\n", key, value);
+	fclose(out);
+	return rename(tmp, path);
+}
+
+int rbs_config_cmd(int argc, char *argv[]) {
+	char val[256], profile[64];
+	int i, json = 0;
+	rbs_config_profile(profile, sizeof(profile));
+	if (argc >= 3 && strcmp(argv[1], "get") == 0) {
+		if (rbs_config_get(argv[2], val, sizeof(val)) != 0) {
+			printf("%s is not set in profile %s\n", argv[2], profile);
+			return 1;
+		}
+		printf("%s\n", val);
+		return 0;
+	}
+	if (argc >= 4 && strcmp(argv[1], "set") == 0) {
+		if (!rbs_config_key_ok(argv[2])) {
+			printf("unknown key %s; known keys:", argv[2]);
+			for (i = 0; i < RBS_CONFIG_NKEYS; i++)
+				printf(" %s", rbs_config_keys[i]);
+			printf("\n");
+			return 1;
+		}
+		if (strchr(argv[3], '\n')) {
+			printf("value cannot contain newlines\n");
+			return 1;
+		}
+		if (rbs_config_set(argv[2], argv[3]) != 0) {
+			printf("could not write config\n");
+			return 1;
+		}
+		printf("%s.%s = %s\n", profile, argv[2], argv[3]);
+		return 0;
+	}
+	if (argc >= 2 && strcmp(argv[1], "list") == 0) {
+		for (i = 2; i < argc; i++)
+			if (strcmp(argv[i], "--json") == 0) json = 1;
+		if (json) printf("{\"profile\":\"%s\"", profile);
+		else printf("profile: %s\n", profile);
+		for (i = 0; i < RBS_CONFIG_NKEYS; i++) {
+			if (rbs_config_get(rbs_config_keys[i], val, sizeof(val)) != 0)
+				continue;
+			if (json) printf(",\"%s\":\"%s\"", rbs_config_keys[i], val);
+			else printf("%s: %s\n", rbs_config_keys[i], val);
+		}
+		if (json) printf("}\n");
+		return 0;
+	}
+	printf("usage: robson config <get KEY|set KEY VALUE|list [--json]>\n");
+	return 1;
+}
+
+#endif
@@ -0,0 +1,123 @@
+#ifndef RBS_CRASH_H
+#define RBS_CRASH_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <execinfo.h>
+#include <fcntl.h>
+#include <signal.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "version.h"
+
+/* On a fatal signal we write what we safely can - version, signal,
+ * stack - to ~/.robson/crash so a wedged robot still leaves evidence.
+ * The handler sticks to write()/backtrace_symbols_fd; everything less
+ * async-signal-safe (env dump, redaction) happens in bugreport instead. */
+
+static char rbs_crash_path_buf[640];
+
+/* Only async-signal-safe calls in here: write, open, close,
+ * backtrace_symbols_fd, raise. The dump path is precomputed at
+ * install time and the signal number formatted by hand. */
+void rbs_crash_handler(int sig) {
+	char num[16];
+	void *frames[32];
+	int fd, n, i = sizeof(num), v = sig;
+	fd = open(rbs_crash_path_buf, O_WRONLY | O_CREAT | O_TRUNC, 0600);
+	if (fd >= 0) {
+		if (write(fd, "robson " RBS_VERSION "\nsignal ",
+		          sizeof("robson " RBS_VERSION "\nsignal ") - 1) < 0) {
+			/* nothing left to do */
+		}
+		num[--i] = '\n';
+		if (v == 0) num[--i] = '0';
+		while (v > 0 && i > 0) { num[--i] = '0' + v % 10; v /= 10; }
+		if (write(fd, num + i, sizeof(num) - i) < 0) { /* ditto */ }
+		if (write(fd, "stack:\n", 7) < 0) { /* ditto */ }
+		n = backtrace(frames, 32);
+		backtrace_symbols_fd(frames, n, fd);
+		close(fd);
+	}
+	signal(SIGSEGV, SIG_DFL);
+	signal(SIGABRT, SIG_DFL);
+	signal(SIGFPE, SIG_DFL);
+	signal(SIGBUS, SIG_DFL);
+	raise(sig);
+}
+
+void rbs_crash_install() {
+	char dir[512];
+	rbs_store_dir(dir, sizeof(dir), "crash");
+	snprintf(rbs_crash_path_buf, sizeof(rbs_crash_path_buf),
+	         "%s/crash-%ld-%d.txt", dir, (long)time(NULL), (int)getpid());
+	signal(SIGSEGV, rbs_crash_handler);
+	signal(SIGABRT, rbs_crash_handler);
+	signal(SIGFPE, rbs_crash_handler);
+	signal(SIGBUS, rbs_crash_handler);
+}
+
+/* ROBSON_* environment is useful context but may hold credentials;
+ * anything that looks like one is redacted before it leaves the box. */
+int rbs_env_sensitive(const char *name) {
+	return strstr(name, "KEY") || strstr(name, "SECRET") ||
+	       strstr(name, "TOKEN") || strstr(name, "PASS");
+}
+
+int rbs_bugreport_cmd(int argc, char *argv[]) {
+	char dir[512], out[640], path[700], line[512], when[40];
+	extern char **environ;
+	struct dirent *e;
+	DIR *d;
+	FILE *fo, *fi;
+	time_t now = time(NULL);
+	int crashes = 0, i;
+	char *eq;
+	(void)argc; (void)argv;
+	rbs_store_dir(dir, sizeof(dir), "crash");
+	snprintf(out, sizeof(out), "%s/bugreport-%ld.txt", dir, (long)now);
+	fo = fopen(out, "w");
+	if (!fo) {
+		printf("cannot write %s\n", out);
+		return 1;
+	}
+	rbs_fmt_time(now, when, sizeof(when));
+	fprintf(fo, "robson bugreport\nversion: %s\ngenerated: %s\n\n",
+	        RBS_VERSION, when);
+	fprintf(fo, "environment (ROBSON_*, secrets redacted):\n");
+	for (i = 0; environ[i]; i++) {
+		if (strncmp(environ[i], "ROBSON_", 7) != 0) continue;
+		eq = strchr(environ[i], '=');
+		if (!eq) continue;
+		if (rbs_env_sensitive(environ[i]))
+			fprintf(fo, "  %.*s=<redacted>\n", (int)(eq - environ[i]), environ[i]);
+		else
+			fprintf(fo, "  %s\n", environ[i]);
+	}
+	fprintf(fo, "\n");
+	d = opendir(dir);
+	if (d) {
+		while ((e = readdir(d))) {
+			if (strncmp(e->d_name, "crash-", 6) != 0) continue;
+			snprintf(path, sizeof(path), "%s/%s", dir, e->d_name);
+			fi = fopen(path, "r");
+			if (!fi) continue;
+			fprintf(fo, "--- %s ---\n", e->d_name);
+			while (fgets(line, sizeof(line), fi))
+				fputs(line, fo);
+			fprintf(fo, "\n");
+			fclose(fi);
+			crashes++;
+		}
+		closedir(d);
+	}
+	fclose(fo);
+	printf("bugreport written to %s (%d crash dump%s)\n",
+	       out, crashes, crashes == 1 ? "" : "s");
+	printf("attach it to a GitHub issue at https://github.com/ldamasio/robson\n");
+	return 0;
+}
+
+#endif
@@ -0,0 +1,169 @@
+#ifndef RBS_DAEMON_H
+#define RBS_DAEMON_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <errno.h>
+#include <signal.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "config.h"
+
+/* Every long-running robson process (alerts, trailing stops, scheduler,
+ * server) announces itself by rewriting a heartbeat file under
+ * ~/.robson/daemons on each loop. robson top reads those files plus
+ * /proc to show the whole local system at a glance. */
+
+typedef struct {
+	char name[32];
+	int pid;
+	time_t started;
+	time_t beat;		/* last heartbeat */
+	double msg_rate;	/* messages handled per minute */
+	double api_rate;	/* backend/exchange calls per minute */
+	char last_error[128];
+} rbs_daemon;
+
+void rbs_daemon_hb_path(char *out, size_t n, const char *name) {
+	char dir[512];
+	rbs_store_dir(dir, sizeof(dir), "daemons");
+	snprintf(out, n, "%s/%s.hb", dir, name);
+}
+
+/* Called by daemons once per loop iteration. */
+int rbs_daemon_heartbeat(const char *name, time_t started,
+                         double msg_rate, double api_rate,
+                         const char *last_error) {
+	char path[640], tmp[700];
+	FILE *f;
+	rbs_daemon_hb_path(path, sizeof(path), name);
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(tmp, "w");
+	if (!f) return -1;
+	fprintf(f, "name=%s\n", name);
+	fprintf(f, "pid=%d\n", (int)getpid());
+	fprintf(f, "started=%ld\n", (long)started);
+	fprintf(f, "beat=%ld\n", (long)time(NULL));
+	fprintf(f, "msg_rate=%f\n", msg_rate);
+	fprintf(f, "api_rate=%f\n", api_rate);
+	fprintf(f, "last_error=%s\n", last_error ? last_error : "");
+	fclose(f);
+	rename(tmp, path);
+	return 0;
+}
+
+int rbs_daemon_load(const char *name, rbs_daemon *d) {
+	char path[640], line[256], key[64], val[192];
+	FILE *f;
+	rbs_daemon_hb_path(path, sizeof(path), name);
+	f = fopen(path, "r");
+	if (!f) return -1;
+	memset(d, 0, sizeof(*d));
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, "name") == 0) snprintf(d->name, sizeof(d->name), "%s", val);
+		else if (strcmp(key, "pid") == 0) d->pid = atoi(val);
+		else if (strcmp(key, "started") == 0) d->started = (time_t)atol(val);
+		else if (strcmp(key, "beat") == 0) d->beat = (time_t)atol(val);
+		else if (strcmp(key, "msg_rate") == 0) d->msg_rate = atof(val);
+		else if (strcmp(key, "api_rate") == 0) d->api_rate = atof(val);
+		else if (strcmp(key, "last_error") == 0) snprintf(d->last_error, sizeof(d->last_error), "%s", val);
+	}
+	fclose(f);
+	return 0;
+}
+
+int rbs_daemon_load_all(rbs_daemon *out, int max) {
+	char dir[512], name[64];
+	DIR *dp;
+	struct dirent *e;
+	char *dot;
+	int n = 0;
+	rbs_store_dir(dir, sizeof(dir), "daemons");
+	dp = opendir(dir);
+	if (!dp) return 0;
+	while ((e = readdir(dp)) && n < max) {
+		dot = strstr(e->d_name, ".hb");
+		if (!dot || dot[3] != '\0') continue;
+		snprintf(name, sizeof(name), "%.*s", (int)(dot - e->d_name), e->d_name);
+		if (rbs_daemon_load(name, &out[n]) == 0) n++;
+	}
+	closedir(dp);
+	return n;
+}
+
+/* A daemon counts as alive if we can signal it or only lack the
+ * permission to (it may run as another user). */
+int rbs_daemon_alive(int pid) {
+	if (pid <= 0) return 0;
+	return kill(pid, 0) == 0 || errno == EPERM;
+}
+
+/* Resident set size in kB from /proc, 0 when unreadable. */
+long rbs_daemon_rss_kb(int pid) {
+	char path[64], line[256];
+	FILE *f;
+	long kb = 0;
+	snprintf(path, sizeof(path), "/proc/%d/status", pid);
+	f = fopen(path, "r");
+	if (!f) return 0;
+	while (fgets(line, sizeof(line), f)) {
+		if (sscanf(line, "VmRSS: %ld", &kb) == 1) break;
+	}
+	fclose(f);
+	return kb;
+}
+
+void rbs_top_draw() {
+	rbs_daemon ds[64];
+	char when[40];
+	const char *state;
+	int n, i;
+	time_t now = time(NULL);
+	n = rbs_daemon_load_all(ds, 64);
+	rbs_fmt_time(now, when, sizeof(when));
+	printf("robson top - %s - %d daemon%s%s\n", when, n, n == 1 ? "" : "s",
+	       rbs_env_is_prod() ? "  [PROD]" : "");
+	if (n == 0) {
+		printf("no robson daemons running\n");
+		return;
+	}
+	printf("%-14s %-6s %-8s %8s %8s %9s  %s\n",
+	       "NAME", "PID", "STATE", "MSG/MIN", "API/MIN", "RSS(KB)", "LAST ERROR");
+	for (i = 0; i < n; i++) {
+		if (!rbs_daemon_alive(ds[i].pid)) state = "dead";
+		else if (now - ds[i].beat > 30) state = "stale";
+		else state = "up";
+		printf("%-14s %-6d %-8s %8.1f %8.1f %9ld  %s\n",
+		       ds[i].name, ds[i].pid, state, ds[i].msg_rate,
+		       ds[i].api_rate, rbs_daemon_rss_kb(ds[i].pid),
+		       ds[i].last_error[0] ? ds[i].last_error : "-");
+	}
+}
+
+int rbs_top_cmd(int argc, char *argv[]) {
+	int once = 0, i, interval = 2;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--once") == 0) once = 1;
+		else if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc)
+			interval = atoi(argv[++i]);
+	}
+	if (interval < 1) interval = 1;
+	if (once) {
+		rbs_top_draw();
+		return 0;
+	}
+	for (;;) {
+		printf("\033[2J\033[H");
+		rbs_top_draw();
+		printf("\n(refreshing every %ds, Ctrl-C to quit)\n", interval);
+		fflush(stdout);
+		sleep(interval);
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,100 @@
+#ifndef RBS_DASHBOARD_H
+#define RBS_DASHBOARD_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "client.h"
+#include "config.h"
+#include "positions.h"
+#include "operation.h"
+#include "fmt.h"
+
+/* At-a-glance terminal dashboard: account value, open positions with
+ * live P&L against break-even, the most recent operations and a
+ * ticker for every asset on the book, redrawn in place. */
+
+void rbs_dashboard_draw(void) {
+	rbs_position pos[32];
+	static rbs_operation ops[1024];
+	rbs_reb_line bals[RBS_REB_MAX];
+	char when[40], qs[32], ps[32];
+	double total, px, pnl;
+	int n, i, shown;
+	time_t now = time(NULL);
+	rbs_fmt_time(now, when, sizeof(when));
+	printf("robson dashboard - %s%s\n", when,
+	       rbs_env_is_prod() ? "  [PROD]" : "");
+	rbs_client_header();
+	if (rbs_client_patrimony(&total) == 0)
+		printf("patrimony: %.2f USDT\n", total);
+	else
+		printf("patrimony: unavailable\n");
+
+	printf("\npositions:\n");
+	n = rbs_positions_build(pos, 32);
+	shown = 0;
+	for (i = 0; i < n; i++) {
+		if (pos[i].qty <= 0.0000001) continue;
+		rbs_fmt_qty(pos[i].symbol, pos[i].qty, qs, sizeof(qs));
+		if (rbs_client_price(pos[i].symbol, &px) == 0) {
+			pnl = (px * pos[i].qty) - pos[i].cost - pos[i].fees;
+			printf("  %-10s %10s  pnl %+10.2f %s\n", pos[i].symbol,
+			       qs, pnl, pnl >= 0 ? "" : "(losing)");
+		} else {
+			printf("  %-10s %10s  pnl          - (no quote)\n",
+			       pos[i].symbol, qs);
+		}
+		shown++;
+	}
+	if (!shown) printf("  none\n");
+
+	printf("\nrecent operations:\n");
+	n = rbs_operation_load_all(ops, 1024);
+	for (i = 0; i < n && i < 5; i++) {
+		rbs_fmt_time_short(ops[i].executed, when, sizeof(when));
+		rbs_fmt_qty(ops[i].symbol, ops[i].qty, qs, sizeof(qs));
+		rbs_fmt_px(ops[i].symbol, ops[i].price, ps, sizeof(ps));
+		printf("  %-20s %-5s %-10s %10s @ %-10s %s\n", ops[i].id,
+		       ops[i].side, ops[i].symbol, qs, ps, when);
+	}
+	if (!n) printf("  none\n");
+
+	printf("\nticker:\n");
+	n = rbs_client_balances(bals, RBS_REB_MAX);
+	for (i = 0; i < n; i++) {
+		if (rbs_reb_price(&bals[i]) == 0) {
+			rbs_fmt_px(NULL, bals[i].price, ps, sizeof(ps));
+			printf("  %-8s %12s\n", bals[i].asset, ps);
+		} else {
+			printf("  %-8s %12s\n", bals[i].asset, "-");
+		}
+	}
+	if (n <= 0) printf("  no balances\n");
+}
+
+int rbs_dashboard_cmd(int argc, char *argv[]) {
+	int once = 0, interval = 2, i;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--once") == 0) once = 1;
+		else if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc)
+			interval = atoi(argv[++i]);
+	}
+	if (interval < 1) interval = 1;
+	if (once) {
+		rbs_dashboard_draw();
+		return 0;
+	}
+	for (;;) {
+		printf("\033[2J\033[H");
+		rbs_dashboard_draw();
+		printf("\n(refreshing every %ds, Ctrl-C to quit)\n", interval);
+		fflush(stdout);
+		sleep(interval);
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,143 @@
+#ifndef RBS_DEADMAN_H
+#define RBS_DEADMAN_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "daemon.h"
+#include "hooks.h"
+
+/* Dead-man's switch. The operator checks in with robson heartbeat;
+ * the deadman daemon pauses automation (writing the shared
+ * ~/.robson/paused flag with its reason) when no check-in lands
+ * within the timeout while automation runs unattended. Daemons that
+ * open new entries honor the flag. */
+
+void rbs_pause_set(const char *reason) {
+	char path[640];
+	FILE *f;
+	rbs_state_path(path, sizeof(path), "paused");
+	f = fopen(path, "w");
+	if (f) {
+		fprintf(f, "%ld %s\n", (long)time(NULL), reason);
+		fclose(f);
+	}
+	rbs_hook_fire("pause", reason);
+}
+
+void rbs_pause_clear(void) {
+	char path[640];
+	rbs_state_path(path, sizeof(path), "paused");
+	unlink(path);
+}
+
+/* Nonzero when automation is paused; reason (when wanted) into buf. */
+int rbs_paused(char *reason, size_t n) {
+	char path[640], line[200];
+	FILE *f;
+	rbs_state_path(path, sizeof(path), "paused");
+	if (reason && n) reason[0] = '\0';
+	f = fopen(path, "r");
+	if (!f) return 0;
+	if (reason && fgets(line, sizeof(line), f)) {
+		char *sp = strchr(line, ' ');
+		char *nl = strchr(line, '\n');
+		if (nl) *nl = '\0';
+		snprintf(reason, n, "%s", sp ? sp + 1 : line);
+	}
+	fclose(f);
+	return 1;
+}
+
+/* Maintenance mode: robson pause / robson resume flip the same flag
+ * the dead-man switch uses, so every daemon honors both through one
+ * check. Sells still execute while paused; only new entries stop. */
+int rbs_pause_cmd(int argc, char *argv[]) {
+	char reason[160], why[200];
+	if (rbs_paused(why, sizeof(why))) {
+		printf("already paused (%s)\n", why);
+		return 0;
+	}
+	if (argc >= 2)
+		snprintf(reason, sizeof(reason), "maintenance: %s", argv[1]);
+	else
+		snprintf(reason, sizeof(reason), "maintenance");
+	rbs_pause_set(reason);
+	printf("automation paused (%s); robson resume to clear\n", reason);
+	return 0;
+}
+
+int rbs_resume_cmd(void) {
+	char why[200];
+	if (!rbs_paused(why, sizeof(why))) {
+		printf("automation is not paused\n");
+		return 0;
+	}
+	rbs_pause_clear();
+	printf("automation resumed (was: %s)\n", why);
+	return 0;
+}
+
+int rbs_heartbeat_cmd(void) {
+	char path[640];
+	FILE *f;
+	rbs_state_path(path, sizeof(path), "operator.beat");
+	f = fopen(path, "w");
+	if (!f) {
+		printf("cannot record heartbeat\n");
+		return 1;
+	}
+	fprintf(f, "%ld\n", (long)time(NULL));
+	fclose(f);
+	printf("operator check-in recorded\n");
+	return 0;
+}
+
+int rbs_deadman_cmd(int argc, char *argv[]) {
+	char path[640], line[64];
+	long timeout = 3600, interval = 30, last;
+	time_t started = time(NULL);
+	FILE *f;
+	int i;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--timeout") == 0 && i + 1 < argc) {
+			timeout = rbs_parse_duration(argv[++i]);
+			if (timeout <= 0) {
+				printf("--timeout wants 30m/1h style\n");
+				return 1;
+			}
+		}
+		else if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc) {
+			interval = rbs_parse_duration(argv[++i]);
+			if (interval <= 0) interval = 30;
+		}
+	}
+	printf("dead-man switch: pause automation after %lds without a "
+	       "robson heartbeat\n", timeout);
+	fflush(stdout);
+	for (;;) {
+		rbs_state_path(path, sizeof(path), "operator.beat");
+		last = 0;
+		f = fopen(path, "r");
+		if (f) {
+			if (fgets(line, sizeof(line), f)) last = atol(line);
+			fclose(f);
+		}
+		if (last == 0) last = started;	/* daemon start counts as check-in */
+		if (time(NULL) - last > timeout) {
+			if (!rbs_paused(NULL, 0)) {
+				rbs_pause_set("dead-man switch: operator missing");
+				fprintf(stderr, "ALERT: no operator check-in within "
+				        "%lds; automation paused\n", timeout);
+			}
+		}
+		rbs_daemon_heartbeat("deadman", started, 0, 0, "");
+		fflush(stderr);
+		sleep(interval);
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,96 @@
+#ifndef RBS_EXEC_PREFS_H
+#define RBS_EXEC_PREFS_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+
+/* Per-strategy execution preferences, one file per strategy under
+ * ~/.robson/strategies/<name>.conf:
+ *
+ *   post_only=1          prefer maker entries
+ *   max_wait=30s         how long to rest before escalating to taker
+ *   maker_fee_pct=0.02
+ *   taker_fee_pct=0.1
+ *   partial_fill=wait    wait | cancel-remainder | convert-to-market
+ *   partial_wait=30s     patience before the policy acts
+ *
+ * The execution path honors them and reports the fee outcome in the
+ * receipt; strategies without a file execute as plain takers. */
+
+typedef struct {
+	int post_only;
+	long max_wait;
+	double maker_fee_pct;
+	double taker_fee_pct;
+	char partial_fill[20];	/* wait, cancel-remainder, convert-to-market */
+	long partial_wait;
+} rbs_exec_prefs;
+
+int rbs_exec_prefs_load(const char *strategy, rbs_exec_prefs *p) {
+	char dir[512], path[640], line[128], key[64], val[64];
+	FILE *f;
+	if (!*strategy || strchr(strategy, '/') || strchr(strategy, '.'))
+		return -1;	/* same charset rules as plan ids */
+	rbs_store_dir(dir, sizeof(dir), "strategies");
+	snprintf(path, sizeof(path), "%s/%s.conf", dir, strategy);
+	f = fopen(path, "r");
+	if (!f) return -1;
+	memset(p, 0, sizeof(*p));
+	p->max_wait = 30;
+	p->maker_fee_pct = 0.02;
+	p->taker_fee_pct = 0.1;
+	/* undefined behavior on partials was the old bug; wait is the
+	 * explicit default now */
+	snprintf(p->partial_fill, sizeof(p->partial_fill), "wait");
+	p->partial_wait = 30;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, "post_only") == 0) p->post_only = atoi(val);
+		else if (strcmp(key, "max_wait") == 0) {
+			long d = rbs_parse_duration(val);
+			if (d >= 0) p->max_wait = d;
+		}
+		else if (strcmp(key, "maker_fee_pct") == 0) p->maker_fee_pct = atof(val);
+		else if (strcmp(key, "taker_fee_pct") == 0) p->taker_fee_pct = atof(val);
+		else if (strcmp(key, "partial_fill") == 0) {
+			if (strcmp(val, "wait") == 0 ||
+			    strcmp(val, "cancel-remainder") == 0 ||
+			    strcmp(val, "convert-to-market") == 0)
+				snprintf(p->partial_fill, sizeof(p->partial_fill), "%s", val);
+			else
+				printf("warning: unknown partial_fill %s for %s; using wait\n",
+				       val, strategy);
+		}
+		else if (strcmp(key, "partial_wait") == 0) {
+			long d = rbs_parse_duration(val);
+			if (d >= 0) p->partial_wait = d;
+		}
+	}
+	fclose(f);
+	return 0;
+}
+
+/* Receipt line for one filled notional under the strategy's
+ * preferences. In paper mode post-only always rests successfully
+ * within max_wait; live escalation arrives with real order flow. */
+void rbs_exec_prefs_receipt(const char *strategy, double notional) {
+	rbs_exec_prefs p;
+	if (rbs_exec_prefs_load(strategy, &p) != 0)
+		return;
+	if (p.post_only)
+		printf("execution style: post-only maker (max wait %lds), "
+		       "fee %.4f vs taker %.4f, saved %.4f\n",
+		       p.max_wait,
+		       notional * p.maker_fee_pct / 100.0,
+		       notional * p.taker_fee_pct / 100.0,
+		       notional * (p.taker_fee_pct - p.maker_fee_pct) / 100.0);
+	printf("partial-fill policy: %s%s", p.partial_fill,
+	       strcmp(p.partial_fill, "wait") == 0 ? "" : " after ");
+	if (strcmp(p.partial_fill, "wait") != 0)
+		printf("%lds", p.partial_wait);
+	printf(" (paper fills are always complete)\n");
+}
+
+#endif
@@ -0,0 +1,401 @@
+#ifndef RBS_EXECUTE_H
+#define RBS_EXECUTE_H
+#include <stdio.h>
+#include <string.h>
+#include <time.h>
+#include <math.h>
+#include <stdlib.h>
+#include "plan.h"
+#include "operation.h"
+#include "client.h"
+#include "audit.h"
+#include "allocate.h"
+#include "exec_prefs.h"
+#include "config.h"
+#include "golive.h"
+#include "deadman.h"
+#include "breaker.h"
+#include "gap.h"
+#include "promote.h"
+#include "risk.h"
+
+/* plan -> validate -> execute. Validation is the gate where a plan
+ * earns the right to run; execution records the operation and retires
+ * the plan. Both refuse expired plans outright. */
+
+int rbs_validate_cmd(int argc, char *argv[]) {
+	rbs_plan p;
+	int i;
+	if (argc < 2) {
+		printf("usage: robson validate <plan-id>\n");
+		return 1;
+	}
+	if (rbs_plan_load(argv[1], &p) != 0) {
+		printf("no such plan %s\n", argv[1]);
+		return 1;
+	}
+	if (rbs_plan_expired(&p)) {
+		printf("[V001] plan %s expired, re-plan (robson explain V001)\n", p.id);
+		return 1;
+	}
+	if (strcmp(p.status, "pending") != 0) {
+		printf("[V002] plan %s is %s, only pending plans validate (robson explain V002)\n", p.id, p.status);
+		return 1;
+	}
+	if (!rbs_symbol_ok(p.symbol)) {
+		printf("[V003] plan %s has a malformed symbol %s (robson explain V003)\n", p.id, p.symbol);
+		return 1;
+	}
+	if (p.qty <= 0 || p.price <= 0) {
+		printf("[V004] plan %s has non-positive qty or price (robson explain V004)\n", p.id);
+		return 1;
+	}
+	for (i = 0; i < p.nlegs; i++) {
+		if (p.legs[i].qty <= 0 || p.legs[i].price <= 0) {
+			printf("[V004] plan %s leg %d has non-positive qty or price (robson explain V004)\n", p.id, i);
+			return 1;
+		}
+	}
+	if (p.nlegs == 3 && strcmp(p.legs[0].role, "entry") == 0 &&
+	    strcmp(p.legs[0].side, "buy") == 0 &&
+	    !(p.legs[1].price < p.legs[0].price && p.legs[0].price < p.legs[2].price)) {
+		printf("[V005] plan %s bracket is inconsistent: want stop < entry < take-profit (robson explain V005)\n", p.id);
+		return 1;
+	}
+	{
+		double alloc = rbs_alloc_for(p.strategy);
+		double used = alloc >= 0 ? rbs_alloc_used(p.strategy, p.id) : 0;
+		if (alloc >= 0 && used + p.qty * p.price > alloc) {
+			printf("[V006] plan %s would push strategy %s to %.2f of %.2f allocated (robson explain V006)\n",
+			       p.id, p.strategy, used + p.qty * p.price, alloc);
+			return 1;
+		}
+	}
+	snprintf(p.status, sizeof(p.status), "validated");
+	p.validated_at = time(NULL);
+	if (rbs_plan_save(&p) != 0) {
+		printf("could not save plan\n");
+		return 1;
+	}
+	{
+		char banner[40];
+		rbs_risk_banner(banner, sizeof(banner));
+		printf("plan %s validated (%s)\n", p.id, banner);
+	}
+	return 0;
+}
+
+/* Who is approving/operating: ROBSON_USER, then the login name. */
+const char *rbs_whoami() {
+	const char *u = getenv("ROBSON_USER");
+	if (u && *u) return u;
+	u = getenv("USER");
+	return u && *u ? u : "unknown";
+}
+
+/* Approvals required before execute --live is allowed. */
+int rbs_required_approvals() {
+	const char *s = getenv("ROBSON_REQUIRED_APPROVALS");
+	return s ? atoi(s) : 0;
+}
+
+int rbs_approve_cmd(int argc, char *argv[]) {
+	rbs_plan p;
+	const char *id = NULL, *note = "";
+	int i, n;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--note") == 0 && i + 1 < argc) note = argv[++i];
+		else id = argv[i];
+	}
+	if (!id) {
+		printf("usage: robson approve <plan-id> [--note TEXT]\n");
+		return 1;
+	}
+	if (rbs_plan_load(id, &p) != 0) {
+		printf("no such plan %s\n", id);
+		return 1;
+	}
+	if (rbs_plan_expired(&p)) {
+		printf("[V001] plan %s expired, re-plan (robson explain V001)\n", p.id);
+		return 1;
+	}
+	for (i = 0; i < p.napprovals; i++) {
+		if (strcmp(p.approvals[i].user, rbs_whoami()) == 0) {
+			printf("%s already approved plan %s; a second person must approve\n",
+			       rbs_whoami(), p.id);
+			return 1;
+		}
+	}
+	if (p.napprovals >= 8) {
+		printf("plan %s already has the maximum number of approvals\n", p.id);
+		return 1;
+	}
+	n = p.napprovals++;
+	snprintf(p.approvals[n].user, sizeof(p.approvals[n].user), "%s", rbs_whoami());
+	p.approvals[n].at = time(NULL);
+	snprintf(p.approvals[n].note, sizeof(p.approvals[n].note), "%s", note);
+	/* The record is line-oriented; a newline in the note would let it
+	 * forge unrelated fields. */
+	for (i = 0; p.approvals[n].note[i]; i++)
+		if (p.approvals[n].note[i] == '\n' || p.approvals[n].note[i] == '\r')
+			p.approvals[n].note[i] = ' ';
+	if (rbs_plan_save(&p) != 0) {
+		printf("could not save plan\n");
+		return 1;
+	}
+	printf("plan %s approved by %s (%d approval%s)\n", p.id,
+	       rbs_whoami(), p.napprovals, p.napprovals == 1 ? "" : "s");
+	return 0;
+}
+
+/* Live orders can optionally demand a second opinion: with
+ * ROBSON_DUAL_SOURCE=1 the primary quote must agree with the
+ * secondary feed (~/.robson/feed2/<symbol>) within
+ * ROBSON_SOURCE_DIVERGENCE_PCT (default 0.5%), and a missing
+ * secondary aborts - fail closed, a poisoned primary is exactly the
+ * case this protects against. */
+double rbs_source_divergence_pct() {
+	const char *s = getenv("ROBSON_SOURCE_DIVERGENCE_PCT");
+	double v = s ? atof(s) : 0.5;
+	return v > 0 ? v : 0.5;
+}
+
+int rbs_dual_source_check(const char *symbol, double primary) {
+	char dir[512], path[640];
+	FILE *f;
+	double secondary;
+	double div;
+	const char *on = getenv("ROBSON_DUAL_SOURCE");
+	if (!on || strcmp(on, "1") != 0) return 0;
+	rbs_store_dir(dir, sizeof(dir), "feed2");
+	snprintf(path, sizeof(path), "%s/%s", dir, symbol);
+	f = fopen(path, "r");
+	if (!f || fscanf(f, "%lf", &secondary) != 1 || secondary <= 0) {
+		if (f) fclose(f);
+		printf("dual-source: no secondary quote for %s; aborting live "
+		       "execution (fail closed)\n", symbol);
+		return -1;
+	}
+	fclose(f);
+	div = fabs(primary - secondary) / secondary * 100.0;
+	if (div > rbs_source_divergence_pct()) {
+		printf("dual-source: primary %f and secondary %f diverge %.2f%% "
+		       "(limit %.2f%%); aborting live execution\n",
+		       primary, secondary, div, rbs_source_divergence_pct());
+		return -1;
+	}
+	return 0;
+}
+
+/* Maximum move between plan price and current quote before execute
+ * refuses, in percent. */
+double rbs_max_drift_pct() {
+	const char *s = getenv("ROBSON_MAX_DRIFT_PCT");
+	double v;
+	if (s) {
+		v = atof(s);
+		return v > 0 ? v : 1.0;
+	}
+	v = rbs_risk_value("max_drift_pct", 1.0);
+	return v > 0 ? v : 1.0;
+}
+
+int rbs_execute_cmd(int argc, char *argv[]) {
+	rbs_plan p;
+	rbs_operation o;
+	double px, drift;
+	int i, accept_drift = 0, live = 0, ack = 0;
+	const char *id = NULL;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--accept-drift") == 0) accept_drift = 1;
+		else if (strcmp(argv[i], "--live") == 0) live = 1;
+		else if (strcmp(argv[i], "--ack") == 0) ack = 1;
+		else id = argv[i];
+	}
+	if (!id) {
+		printf("usage: robson execute <plan-id> [--live] [--accept-drift]\n");
+		return 1;
+	}
+	if (rbs_plan_load(id, &p) != 0) {
+		printf("no such plan %s\n", id);
+		return 1;
+	}
+	if (rbs_plan_expired(&p)) {
+		printf("[V001] plan %s expired, re-plan (robson explain V001)\n", p.id);
+		return 1;
+	}
+	if (strcmp(p.status, "validated") != 0) {
+		printf("[V002] plan %s is %s, validate it first (robson explain V002)\n", p.id, p.status);
+		return 1;
+	}
+	/* Maintenance/dead-man pause stops new entries; sells stay allowed
+	 * so positions can still be reduced while paused. */
+	if (live && strcmp(p.side, "buy") == 0) {
+		char why[200];
+		if (rbs_paused(why, sizeof(why))) {
+			printf("automation paused (%s); no new entries until "
+			       "robson resume\n", why);
+			return 1;
+		}
+	}
+	if (live && strcmp(p.side, "buy") == 0) {
+		char why[200];
+		if (rbs_halted(p.symbol, why, sizeof(why))) {
+			printf("circuit breaker: %s is halted (%s); exits still "
+			       "run, new entries wait for robson breaker clear %s\n",
+			       p.symbol, why, p.symbol);
+			return 1;
+		}
+	}
+	if (live && strcmp(p.strategy, "manual") != 0 &&
+	    !rbs_strategy_live_eligible(p.strategy)) {
+		printf("strategy %s is not live-eligible; earn promotion with "
+		       "robson strategy promote %s\n", p.strategy, p.strategy);
+		return 1;
+	}
+	if (live && !rbs_golive_cleared()) {
+		printf("this profile has not passed the go-live checklist; run "
+		       "robson go-live first\n");
+		return 1;
+	}
+	if (live && !rbs_client_id()) {
+		printf("no client bound for a live order: set client_id in the "
+		       "profile or ROBSON_CLIENT_ID\n");
+		return 1;
+	}
+	/* In prod a live order is real money: demand the explicit --ack on
+	 * top of every other gate so testnet habits cannot leak through. */
+	if (live && rbs_env_is_prod() && !ack) {
+		printf("[PROD] this is a live order against production; re-run "
+		       "with --ack to confirm\n");
+		return 1;
+	}
+	/* Real-money orders can demand a two-person rule: N distinct
+	 * approvers recorded on the plan before --live runs. */
+	if (live && p.napprovals < rbs_required_approvals()) {
+		printf("[E002] plan %s has %d of %d required approvals for live execution\n",
+		       p.id, p.napprovals, rbs_required_approvals());
+		for (i = 0; i < p.napprovals; i++)
+			printf("  approved by %s\n", p.approvals[i].user);
+		printf("get another approval with: robson approve %s (robson explain E002)\n", p.id);
+		return 1;
+	}
+	/* A validated plan can become unsafe in minutes; say how long it
+	 * sat in validation and compare the planned price against the
+	 * market before pulling the trigger. */
+	if (rbs_client_price(p.symbol, &px) == 0) {
+		if (live && rbs_dual_source_check(p.symbol, px) != 0)
+			return 1;
+		drift = fabs(px - p.price) / p.price * 100.0;
+		if (p.validated_at)
+			printf("validated %ldm ago, market moved %+.2f%% since planning\n",
+			       (time(NULL) - p.validated_at) / 60,
+			       (px - p.price) / p.price * 100.0);
+		if (drift > rbs_max_drift_pct() && !accept_drift) {
+			printf("[E001] plan %s blocked: %s moved %.2f%% since planning "
+			       "(planned %f, now %f, limit %.2f%%)\n",
+			       p.id, p.symbol, drift, p.price, px, rbs_max_drift_pct());
+			printf("re-plan, or pass --accept-drift to execute anyway (robson explain E001)\n");
+			{
+				char detail[64];
+				snprintf(detail, sizeof(detail), "%s drift %.2f%%",
+				         p.symbol, drift);
+				rbs_hook_fire("drift-abort", detail);
+			}
+			return 1;
+		}
+	} else {
+		if (live && getenv("ROBSON_DUAL_SOURCE") &&
+		    strcmp(getenv("ROBSON_DUAL_SOURCE"), "1") == 0) {
+			printf("dual-source: no primary quote for %s; aborting live "
+			       "execution (fail closed)\n", p.symbol);
+			return 1;
+		}
+		printf("no current quote for %s; drift check skipped\n", p.symbol);
+	}
+	/* a position opened now rides through whatever the next 12h hold;
+	 * say so before the order goes out, sized against the stop leg
+	 * when the plan carries one */
+	{
+		char gapline[200];
+		double stop_dist = 0;
+		for (i = 0; i < p.nlegs; i++)
+			if (strcmp(p.legs[i].role, "stop") == 0 && p.price > 0)
+				stop_dist = fabs(p.price - p.legs[i].price)
+				          / p.price * 100.0;
+		if (rbs_gap_warn(p.symbol, stop_dist, 12 * 3600, gapline,
+		                 sizeof(gapline)))
+			printf("%s\n", gapline);
+	}
+	if (p.nlegs > 0) {
+		/* Legs run in order; the first failure stops the chain and
+		 * leaves the per-leg statuses telling the operator exactly
+		 * which exposure is open. */
+		for (i = 0; i < p.nlegs; i++) {
+			memset(&o, 0, sizeof(o));
+			snprintf(o.id, sizeof(o.id), "%s-L%d", p.id, i);
+			snprintf(o.symbol, sizeof(o.symbol), "%s", p.symbol);
+			snprintf(o.side, sizeof(o.side), "%s", p.legs[i].side);
+			o.qty = p.legs[i].qty;
+			o.price = p.legs[i].price;
+			o.executed = time(NULL);
+			if (rbs_operation_save(&o) != 0) {
+				snprintf(p.legs[i].status, sizeof(p.legs[i].status), "failed");
+				snprintf(p.status, sizeof(p.status), "partial");
+				rbs_plan_save(&p);
+				rbs_audit_enqueue(&p, "partial");
+				if (i == 0)
+					printf("leg 0 (%s) failed; nothing executed\n",
+					       p.legs[0].role);
+				else {
+					printf("leg %d (%s) failed; legs 0..%d executed, %d..%d not placed\n",
+					       i, p.legs[i].role, i - 1, i, p.nlegs - 1);
+					printf("open exposure from the executed legs is unprotected: "
+					       "close it manually or re-plan the remaining legs\n");
+				}
+				return 1;
+			}
+			snprintf(p.legs[i].status, sizeof(p.legs[i].status), "executed");
+			printf("leg %d %-12s %s %s %f @ %f\n", i, p.legs[i].role,
+			       p.legs[i].side, p.symbol, p.legs[i].qty, p.legs[i].price);
+		}
+		snprintf(p.status, sizeof(p.status), "executed");
+		p.executed_at = time(NULL);
+		if (rbs_plan_save(&p) != 0) {
+			printf("could not save plan\n");
+			return 1;
+		}
+		rbs_audit_enqueue(&p, "executed");
+		rbs_exec_prefs_receipt(p.strategy, p.qty * p.price);
+		printf("plan %s executed (%d legs)\n", p.id, p.nlegs);
+		return 0;
+	}
+	memset(&o, 0, sizeof(o));
+	snprintf(o.id, sizeof(o.id), "%s", p.id);
+	snprintf(o.symbol, sizeof(o.symbol), "%s", p.symbol);
+	snprintf(o.side, sizeof(o.side), "%s", p.side);
+	o.qty = p.qty;
+	o.price = p.price;
+	o.executed = time(NULL);
+	if (rbs_operation_save(&o) != 0) {
+		printf("plan %s already executed or operation not writable\n", p.id);
+		return 1;
+	}
+	snprintf(p.status, sizeof(p.status), "executed");
+	p.executed_at = time(NULL);
+	if (rbs_plan_save(&p) != 0) {
+		printf("could not save plan\n");
+		return 1;
+	}
+	rbs_audit_enqueue(&p, "executed");
+	rbs_exec_prefs_receipt(p.strategy, p.qty * p.price);
+	{
+		char banner[40];
+		rbs_risk_banner(banner, sizeof(banner));
+		printf("plan %s executed: %s %s %f @ %f (%s)\n",
+		       p.id, p.side, p.symbol, p.qty, p.price, banner);
+	}
+	return 0;
+}
+
+#endif
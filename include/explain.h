@@ -0,0 +1,173 @@
+#ifndef RBS_EXPLAIN_H
+#define RBS_EXPLAIN_H
+#include <stdio.h>
+#include <string.h>
+#include <time.h>
+#include "plan.h"
+#include "price.h"
+#include "execute.h"
+
+/* Every check that can refuse a plan has an id printed with its
+ * failure. robson explain <check-id> [plan-id] turns that refusal
+ * into a lesson: what the check protects against, the exact numbers
+ * when a plan id is given, and what to do about it. */
+
+typedef struct {
+	const char *id;
+	const char *name;
+	const char *what;
+	const char *fix;
+} rbs_check;
+
+static const rbs_check rbs_checks[] = {
+	{ "V001", "plan expired",
+	  "The plan is older than its ttl. Limit prices decided hours or days\n"
+	  "ago no longer reflect the market, so stale plans never validate or\n"
+	  "execute.",
+	  "Re-plan at current prices, or use a longer --ttl at plan time if\n"
+	  "the intent really is long-lived." },
+	{ "V002", "wrong status",
+	  "Only pending plans validate and only validated plans execute. A plan\n"
+	  "that is executed, amended or partial has already moved on.",
+	  "Check plan show <id>; create or amend to get a fresh pending version." },
+	{ "V003", "malformed symbol",
+	  "Symbols must be exchange-style tickers (letters and digits only).\n"
+	  "Anything else usually means a hand-edited record.",
+	  "Fix the symbol in a new plan; records are not edited in place." },
+	{ "V004", "non-positive size",
+	  "Quantity and price must both be positive on the plan and on every\n"
+	  "leg. Zero or negative values are always a data error.",
+	  "Re-plan with a positive quantity and price, or reduce size to a\n"
+	  "small positive amount if you were probing." },
+	{ "V005", "bracket inconsistent",
+	  "A long bracket needs stop < entry < take-profit, otherwise the\n"
+	  "protective orders trigger immediately or never.",
+	  "Widen the stop below the entry and the take-profit above it, then\n"
+	  "re-plan the bracket." },
+	{ "V006", "allocation exceeded",
+	  "Each strategy may only commit the capital allocated to it via\n"
+	  "robson allocate; validated and executed plans count against the\n"
+	  "budget so one strategy cannot consume the whole account.",
+	  "Reduce size, retire older plans of the strategy, or raise its\n"
+	  "share with robson allocate." },
+	{ "E001", "market drift",
+	  "Between validation and execution the market moved more than the\n"
+	  "configured limit away from the planned price, so the fill you would\n"
+	  "get is not the trade you approved.",
+	  "Re-plan at the current price, raise ROBSON_MAX_DRIFT_PCT if your\n"
+	  "strategy tolerates it, or pass --accept-drift for a one-off." },
+	{ "E002", "approvals missing",
+	  "Live execution is gated on ROBSON_REQUIRED_APPROVALS distinct\n"
+	  "sign-offs recorded on the plan - the two-person rule for real\n"
+	  "money.",
+	  "Have another operator run robson approve <plan-id>, or drop --live\n"
+	  "for a paper execution." },
+};
+
+/* Binance-style exchange error codes mapped to plain language and a
+ * remedy, so raw "-2010" never reaches an operator unexplained. */
+typedef struct {
+	int code;
+	const char *what;
+	const char *fix;
+} rbs_exch_err;
+
+static const rbs_exch_err rbs_exch_errs[] = {
+	{ -1013, "Order rejected: quantity or price violates the symbol's "
+	         "filters (lot size, tick size or min notional).",
+	  "Round quantity to the lot step, price to the tick, and keep "
+	  "notional above the minimum; see the symbol's exchangeInfo." },
+	{ -1021, "Request timestamp outside the server's receive window "
+	         "(clock drift).",
+	  "Sync the system clock (ntp/chrony) or widen recvWindow slightly." },
+	{ -2010, "New order rejected: most often insufficient balance, "
+	         "sometimes a closed or restricted market.",
+	  "Check free balance for the quote asset, reduce size, or verify "
+	  "the market is open for trading." },
+	{ -2011, "Cancel rejected: the order is already gone (filled or "
+	         "previously cancelled).",
+	  "Refresh open orders before cancelling; treat as success if the "
+	  "goal was a flat book." },
+	{ -2015, "Invalid API key, IP not allowlisted, or missing "
+	         "permission for this endpoint.",
+	  "Re-check the key, its IP allowlist and its enabled permissions." },
+	{ -1003, "Request weight limit exceeded; the exchange is "
+	         "throttling this key or IP.",
+	  "Back off, lower ROBSON_RATE_EXCHANGE, and consolidate watch "
+	  "loops behind the shared cache." },
+};
+
+int rbs_exchange_explain(int code) {
+	size_t i;
+	for (i = 0; i < sizeof(rbs_exch_errs) / sizeof(rbs_exch_errs[0]); i++) {
+		if (rbs_exch_errs[i].code == code) {
+			printf("exchange error %d\n\n%s\n\nhow to fix:\n%s\n",
+			       code, rbs_exch_errs[i].what, rbs_exch_errs[i].fix);
+			return 0;
+		}
+	}
+	printf("unknown exchange error %d; raw code list: "
+	       "https://binance-docs.github.io/apidocs\n", code);
+	return 1;
+}
+
+int rbs_explain_cmd(int argc, char *argv[]) {
+	const rbs_check *c = NULL;
+	rbs_plan p;
+	double px;
+	size_t i;
+	if (argc < 2) {
+		printf("usage: robson explain <check-id|exchange-code> [plan-id]\n");
+		for (i = 0; i < sizeof(rbs_checks) / sizeof(rbs_checks[0]); i++)
+			printf("  %s  %s\n", rbs_checks[i].id, rbs_checks[i].name);
+		printf("  -NNNN exchange error codes (e.g. explain -2010)\n");
+		return 1;
+	}
+	if (argv[1][0] == '-' && atoi(argv[1]) < 0)
+		return rbs_exchange_explain(atoi(argv[1]));
+	for (i = 0; i < sizeof(rbs_checks) / sizeof(rbs_checks[0]); i++)
+		if (strcasecmp(rbs_checks[i].id, argv[1]) == 0) c = &rbs_checks[i];
+	if (!c) {
+		printf("unknown check %s; run robson explain for the list\n", argv[1]);
+		return 1;
+	}
+	printf("%s - %s\n\n%s\n\nhow to fix:\n%s\n", c->id, c->name, c->what, c->fix);
+	if (argc >= 3 && rbs_plan_load(argv[2], &p) == 0) {
+		printf("\nnumbers for plan %s:\n", p.id);
+		if (strcmp(c->id, "V001") == 0) {
+			long ttl = p.ttl > 0 ? p.ttl : RBS_PLAN_TTL_DEFAULT;
+			printf("  created %ld, ttl %lds, expires %ld, now %ld (%s)\n",
+			       (long)p.created, ttl, (long)p.created + ttl,
+			       (long)time(NULL),
+			       rbs_plan_expired(&p) ? "expired" : "still valid");
+		} else if (strcmp(c->id, "V002") == 0) {
+			printf("  status is %s\n", p.status);
+		} else if (strcmp(c->id, "V004") == 0) {
+			printf("  qty %f, price %f\n", p.qty, p.price);
+		} else if (strcmp(c->id, "V005") == 0 && p.nlegs == 3) {
+			printf("  stop %f, entry %f, take-profit %f\n",
+			       p.legs[1].price, p.legs[0].price, p.legs[2].price);
+		} else if (strcmp(c->id, "V006") == 0) {
+			double alloc = rbs_alloc_for(p.strategy);
+			printf("  strategy %s: %.2f committed, %.2f allocated, plan adds %.2f\n",
+			       p.strategy, rbs_alloc_used(p.strategy, p.id),
+			       alloc < 0 ? 0 : alloc, p.qty * p.price);
+		} else if (strcmp(c->id, "E001") == 0) {
+			if (rbs_price_get(p.symbol, &px) == 0)
+				printf("  planned %f, now %f, drift %.2f%%, limit %.2f%%\n",
+				       p.price, px,
+				       (px > p.price ? px - p.price : p.price - px)
+				       / p.price * 100.0, rbs_max_drift_pct());
+			else
+				printf("  planned %f, no current quote for %s\n",
+				       p.price, p.symbol);
+		} else if (strcmp(c->id, "E002") == 0) {
+			printf("  %d approval%s recorded, %d required\n",
+			       p.napprovals, p.napprovals == 1 ? "" : "s",
+			       rbs_required_approvals());
+		}
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,139 @@
+#ifndef RBS_EXPORT_H
+#define RBS_EXPORT_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include "store.h"
+#include "operation.h"
+#include "positions.h"
+
+/* Data export for spreadsheets and tax tools: robson export
+ * <trades|operations|positions> with an optional --from/--to date
+ * window and csv or json output, to stdout or -o FILE. Parquet is
+ * refused with a pointer to csv - a columnar writer is not worth a
+ * dependency here and every downstream tool reads csv anyway. */
+
+/* YYYY-MM-DD at local midnight; -1 on parse failure. */
+time_t rbs_export_date(const char *s, int end_of_day) {
+	struct tm tmv;
+	memset(&tmv, 0, sizeof(tmv));
+	if (!strptime(s, "%Y-%m-%d", &tmv)) return -1;
+	tmv.tm_isdst = -1;
+	if (end_of_day) {
+		tmv.tm_hour = 23;
+		tmv.tm_min = 59;
+		tmv.tm_sec = 59;
+	}
+	return mktime(&tmv);
+}
+
+int rbs_export_cmd(int argc, char *argv[]) {
+	static rbs_operation ops[1024];
+	static rbs_position pos[64];
+	const char *what = NULL, *format = "csv", *outfile = NULL;
+	FILE *out = stdout;
+	time_t from = 0, to = 0;
+	int json, n, i, rows = 0;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--from") == 0 && i + 1 < argc) {
+			from = rbs_export_date(argv[++i], 0);
+			if (from < 0) {
+				printf("--from wants YYYY-MM-DD, got %s\n", argv[i]);
+				return 1;
+			}
+		}
+		else if (strcmp(argv[i], "--to") == 0 && i + 1 < argc) {
+			to = rbs_export_date(argv[++i], 1);
+			if (to < 0) {
+				printf("--to wants YYYY-MM-DD, got %s\n", argv[i]);
+				return 1;
+			}
+		}
+		else if (strcmp(argv[i], "--format") == 0 && i + 1 < argc) format = argv[++i];
+		else if (strcmp(argv[i], "-o") == 0 && i + 1 < argc) outfile = argv[++i];
+		else if (!what) what = argv[i];
+	}
+	if (!what || (strcmp(what, "trades") != 0 &&
+	              strcmp(what, "operations") != 0 &&
+	              strcmp(what, "positions") != 0)) {
+		printf("usage: robson export <trades|operations|positions> "
+		       "[--from YYYY-MM-DD] [--to YYYY-MM-DD] "
+		       "[--format csv|json] [-o FILE]\n");
+		return 1;
+	}
+	if (strcmp(format, "parquet") == 0) {
+		printf("parquet is not supported (no columnar writer here); "
+		       "use --format csv, it imports everywhere\n");
+		return 1;
+	}
+	if (strcmp(format, "csv") != 0 && strcmp(format, "json") != 0) {
+		printf("--format wants csv or json, got %s\n", format);
+		return 1;
+	}
+	json = strcmp(format, "json") == 0;
+	if (outfile) {
+		out = fopen(outfile, "w");
+		if (!out) {
+			printf("cannot write %s\n", outfile);
+			return 1;
+		}
+	}
+	if (strcmp(what, "positions") == 0) {
+		n = rbs_positions_build(pos, 64);
+		if (!json) fprintf(out, "symbol,qty,cost,fees\n");
+		for (i = 0; i < n; i++) {
+			if (pos[i].qty == 0) continue;
+			if (json)
+				fprintf(out, "{\"symbol\":\"%s\",\"qty\":%.8f,"
+				        "\"cost\":%.8f,\"fees\":%.4f}\n", pos[i].symbol,
+				        pos[i].qty, pos[i].cost, pos[i].fees);
+			else
+				fprintf(out, "%s,%.8f,%.8f,%.4f\n", pos[i].symbol,
+				        pos[i].qty, pos[i].cost, pos[i].fees);
+			rows++;
+		}
+	} else {
+		/* trades and operations share the fill log; trades adds the
+		 * estimated fee column the tax tools ask for */
+		int trades = strcmp(what, "trades") == 0;
+		n = rbs_operation_load_all(ops, 1024);
+		if (!json)
+			fprintf(out, trades ? "time,symbol,side,qty,price,fee\n"
+			                    : "time,id,symbol,side,qty,price\n");
+		for (i = n - 1; i >= 0; i--) {	/* oldest first */
+			if (from && ops[i].executed < from) continue;
+			if (to && ops[i].executed > to) continue;
+			if (json && trades)
+				fprintf(out, "{\"time\":%ld,\"symbol\":\"%s\","
+				        "\"side\":\"%s\",\"qty\":%.8f,\"price\":%.8f,"
+				        "\"fee\":%.4f}\n", (long)ops[i].executed,
+				        ops[i].symbol, ops[i].side, ops[i].qty,
+				        ops[i].price,
+				        ops[i].qty * ops[i].price * rbs_fee_pct() / 100.0);
+			else if (json)
+				fprintf(out, "{\"time\":%ld,\"id\":\"%s\",\"symbol\":\"%s\","
+				        "\"side\":\"%s\",\"qty\":%.8f,\"price\":%.8f}\n",
+				        (long)ops[i].executed, ops[i].id, ops[i].symbol,
+				        ops[i].side, ops[i].qty, ops[i].price);
+			else if (trades)
+				fprintf(out, "%ld,%s,%s,%.8f,%.8f,%.4f\n",
+				        (long)ops[i].executed, ops[i].symbol, ops[i].side,
+				        ops[i].qty, ops[i].price,
+				        ops[i].qty * ops[i].price * rbs_fee_pct() / 100.0);
+			else
+				fprintf(out, "%ld,%s,%s,%s,%.8f,%.8f\n",
+				        (long)ops[i].executed, ops[i].id, ops[i].symbol,
+				        ops[i].side, ops[i].qty, ops[i].price);
+			rows++;
+		}
+	}
+	if (outfile) {
+		fclose(out);
+		printf("exported %d %s row%s to %s\n", rows, what,
+		       rows == 1 ? "" : "s", outfile);
+	}
+	return 0;
+}
+
+#endif
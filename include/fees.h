@@ -0,0 +1,284 @@
+#ifndef RBS_FEES_H
+#define RBS_FEES_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+#include "store.h"
+#include "plan.h"
+#include "http.h"
+#include "config.h"
+#include "operation.h"
+
+/* Cost comparison across instruments for one position held over one
+ * horizon: spot pays fees only, isolated margin adds hourly borrow
+ * interest on the borrowed share, futures adds the 8h funding rate.
+ * Rates are config knobs until the exchange schedule sync exists:
+ *
+ *   ROBSON_MARGIN_HOURLY_PCT  borrow interest per hour (default 0.002)
+ *   ROBSON_FUNDING_8H_PCT     funding per 8h slot (default 0.01)
+ */
+
+double rbs_margin_hourly_pct() {
+	const char *s = getenv("ROBSON_MARGIN_HOURLY_PCT");
+	if (s) return atof(s) >= 0 ? atof(s) : 0.002;
+	return rbs_fee_sched("margin_hourly_pct", 0.002);
+}
+
+double rbs_funding_8h_pct() {
+	const char *s = getenv("ROBSON_FUNDING_8H_PCT");
+	if (s) return atof(s) >= 0 ? atof(s) : 0.01;
+	return rbs_fee_sched("funding_8h_pct", 0.01);
+}
+
+/* robson sync fees: pull the current schedule (from the gateway's
+ * /fees when an API base is configured, otherwise the exchange
+ * schedule dropped at ~/.robson/feed/fees), show what changed and
+ * update ~/.robson/fees for sizing, dry-runs and reports. */
+int rbs_sync_fees_cmd(void) {
+	static char body[4096];
+	char path[640], tmp[700], line[128], k[64], v[64], src[640];
+	const char *base = rbs_api_base();
+	FILE *in, *out;
+	double oldv, newv;
+	int status, changed = 0;
+	const char *keys[] = { "fee_pct", "margin_hourly_pct", "funding_8h_pct" };
+	size_t i;
+	if (base) {
+		char url[768];
+		snprintf(url, sizeof(url), "%s/fees", base);
+		status = rbs_http_get(url, body, sizeof(body));
+		if (status != 200) {
+			printf("fee sync from gateway failed: %s (HTTP %d)\n",
+			       rbs_api_error_code(status), status);
+			return 1;
+		}
+		rbs_state_path(src, sizeof(src), "fees.sync");
+		out = fopen(src, "w");
+		if (!out) return 1;
+		/* gateway replies with the same key=value lines */
+		fputs(body, out);
+		fclose(out);
+	} else {
+		rbs_state_path(src, sizeof(src), "feed/fees");
+		if (access(src, R_OK) != 0) {
+			printf("no fee source: configure an API base or drop the "
+			       "exchange schedule at ~/.robson/feed/fees\n");
+			return 1;
+		}
+	}
+	rbs_state_path(path, sizeof(path), "fees");
+	in = fopen(src, "r");
+	if (!in) return 1;
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	out = fopen(tmp, "w");
+	if (!out) {
+		fclose(in);
+		return 1;
+	}
+	while (fgets(line, sizeof(line), in)) {
+		char parse[128];
+		snprintf(parse, sizeof(parse), "%s", line);
+		if (rbs_kv_line(parse, k, sizeof(k), v, sizeof(v)) != 0)
+			continue;
+		for (i = 0; i < 3; i++) {
+			if (strcmp(k, keys[i]) != 0) continue;
+			newv = atof(v);
+			oldv = rbs_fee_sched(keys[i],
+			                     i == 0 ? 0.1 : i == 1 ? 0.002 : 0.01);
+			if (newv != oldv) {
+				printf("%s: %.4f -> %.4f\n", keys[i], oldv, newv);
+				changed++;
+			}
+			fprintf(out, "%s=%f\n", keys[i], newv);
+		}
+	}
+	fclose(in);
+	fclose(out);
+	rename(tmp, path);
+	if (base) unlink(src);	/* gateway staging file */
+	if (!changed) printf("fee schedule unchanged\n");
+	else printf("fee schedule updated (%d change%s)\n", changed,
+	            changed == 1 ? "" : "s");
+	return 0;
+}
+
+int rbs_compare_fees_cmd(int argc, char *argv[]) {
+	double size = 0, lev[3] = { 1, 3, 5 };
+	long horizon = 0;
+	double trade_fees, hold, total, hours;
+	char label[24];
+	int i, l;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--size") == 0 && i + 1 < argc) size = atof(argv[++i]);
+		else if (strcmp(argv[i], "--horizon") == 0 && i + 1 < argc) {
+			horizon = rbs_parse_duration(argv[++i]);
+			if (horizon < 0) {
+				printf("--horizon wants 30m/24h/7d style, got %s\n", argv[i]);
+				return 1;
+			}
+		}
+	}
+	if (size <= 0 || horizon <= 0) {
+		printf("usage: robson compare-fees --size 10000 --horizon 7d\n");
+		return 1;
+	}
+	hours = horizon / 3600.0;
+	/* entry + exit */
+	trade_fees = size * rbs_fee_pct() / 100.0 * 2;
+	printf("cost of holding %.2f notional for %.1fh "
+	       "(fee %.2f%%, borrow %.4f%%/h, funding %.3f%%/8h):\n",
+	       size, hours, rbs_fee_pct(), rbs_margin_hourly_pct(),
+	       rbs_funding_8h_pct());
+	printf("%-22s %10s %12s %12s\n", "INSTRUMENT", "FEES", "HOLDING", "TOTAL");
+	printf("%-22s %10.2f %12.2f %12.2f\n", "spot", trade_fees, 0.0,
+	       trade_fees);
+	for (l = 0; l < 3; l++) {
+		if (lev[l] <= 1) continue;
+		/* margin: interest on the borrowed share of the notional */
+		hold = size * (1.0 - 1.0 / lev[l]) *
+		       rbs_margin_hourly_pct() / 100.0 * hours;
+		total = trade_fees + hold;
+		snprintf(label, sizeof(label), "margin %.0fx", lev[l]);
+		printf("%-22s %10.2f %12.2f %12.2f\n", label,
+		       trade_fees, hold, total);
+	}
+	/* futures: funding applies to the full notional every 8h slot */
+	hold = size * rbs_funding_8h_pct() / 100.0 * (hours / 8.0);
+	total = trade_fees + hold;
+	printf("%-22s %10.2f %12.2f %12.2f\n", "futures (any lev)",
+	       trade_fees, hold, total);
+	printf("cheapest wins only if funding stays near %.3f%%; check the\n"
+	       "live rate before a long hold\n", rbs_funding_8h_pct());
+	return 0;
+}
+
+
+/* robson fees --period month: what trading actually cost. Commission
+ * per symbol from the active schedule, the BNB discount that applied
+ * when bnb fee payment is on (bnb_discount_pct, default 25, enabled
+ * by ROBSON_BNB_FEES=1), and the estimated isolated-margin borrow
+ * interest accrued over each position's hold time. */
+int rbs_fees_cmd(int argc, char *argv[]) {
+	static rbs_operation ops[1024];
+	struct {
+		char symbol[16];
+		double commission, discount, interest;
+		double qty, cost;
+		time_t opened;
+	} rows[32];
+	const char *period = "month";
+	double disc_pct = rbs_fee_sched("bnb_discount_pct", 25.0);
+	int bnb = getenv("ROBSON_BNB_FEES") &&
+	          strcmp(getenv("ROBSON_BNB_FEES"), "1") == 0;
+	double t_comm = 0, t_disc = 0, t_int = 0;
+	time_t cutoff = 0;
+	long span = 0;
+	int json = 0, n, i, j, nrows = 0, shown = 0;
+	memset(rows, 0, sizeof(rows));
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--period") == 0 && i + 1 < argc) period = argv[++i];
+		else if (strcmp(argv[i], "--json") == 0) json = 1;
+	}
+	if (strcmp(period, "day") == 0) span = 86400;
+	else if (strcmp(period, "week") == 0) span = 7 * 86400;
+	else if (strcmp(period, "month") == 0) span = 30 * 86400;
+	else if (strcmp(period, "all") != 0) {
+		printf("--period wants day, week, month or all\n");
+		return 1;
+	}
+	if (span) cutoff = time(NULL) - span;
+	n = rbs_operation_load_all(ops, 1024);
+	/* oldest first so hold times pair buys with the sells that close
+	 * them */
+	for (i = n - 1; i >= 0; i--) {
+		double notional = ops[i].qty * ops[i].price;
+		double fee = notional * rbs_fee_pct() / 100.0;
+		for (j = 0; j < nrows; j++)
+			if (strcmp(rows[j].symbol, ops[i].symbol) == 0) break;
+		if (j == nrows) {
+			if (nrows >= 32) continue;
+			snprintf(rows[j].symbol, sizeof(rows[j].symbol), "%s",
+			         ops[i].symbol);
+			nrows++;
+		}
+		if (strcmp(ops[i].side, "buy") == 0) {
+			rows[j].cost += notional;
+			rows[j].qty += ops[i].qty;
+			if (rows[j].opened == 0) rows[j].opened = ops[i].executed;
+		} else if (rows[j].qty > 0) {
+			/* interest accrues on the borrowed entry notional for the
+			 * hold time this sell closes */
+			double avg = rows[j].cost / rows[j].qty;
+			double entry = avg * ops[i].qty;
+			time_t from = rows[j].opened > cutoff ? rows[j].opened
+			                                      : cutoff;
+			double hours = rows[j].opened && ops[i].executed > from
+			             ? (ops[i].executed - from) / 3600.0 : 0;
+			double interest = entry * rbs_margin_hourly_pct() / 100.0 * hours;
+			rows[j].cost -= entry;
+			rows[j].qty -= ops[i].qty;
+			if (rows[j].qty <= 0) rows[j].opened = 0;
+			if (ops[i].executed >= cutoff) {
+				rows[j].interest += interest;
+				t_int += interest;
+			}
+		}
+		if (ops[i].executed < cutoff) continue;
+		rows[j].commission += fee;
+		t_comm += fee;
+		if (bnb) {
+			rows[j].discount += fee * disc_pct / 100.0;
+			t_disc += fee * disc_pct / 100.0;
+		}
+	}
+	/* open remainders keep accruing until now; only the slice inside
+	 * the period counts toward its total */
+	for (j = 0; j < nrows; j++) {
+		time_t from;
+		if (rows[j].qty <= 0 || !rows[j].opened) continue;
+		from = rows[j].opened > cutoff ? rows[j].opened : cutoff;
+		{
+			double hours = (time(NULL) - from) / 3600.0;
+			double interest = rows[j].cost * rbs_margin_hourly_pct()
+			                  / 100.0 * hours;
+			rows[j].interest += interest;
+			t_int += interest;
+		}
+	}
+	if (json) {
+		printf("{\"period\":\"%s\",\"commission\":%.4f,"
+		       "\"bnb_discount\":%.4f,\"est_interest\":%.4f,"
+		       "\"symbols\":[", period, t_comm, t_disc, t_int);
+		for (j = 0; j < nrows; j++) {
+			if (rows[j].commission == 0 && rows[j].interest == 0)
+				continue;
+			printf("%s{\"symbol\":\"%s\",\"commission\":%.4f,"
+			       "\"bnb_discount\":%.4f,\"est_interest\":%.4f}",
+			       shown++ ? "," : "", rows[j].symbol, rows[j].commission,
+			       rows[j].discount, rows[j].interest);
+		}
+		printf("]}\n");
+		return 0;
+	}
+	printf("fees and interest, last %s%s\n",
+	       span ? period : "all time",
+	       bnb ? " (bnb fee payment on)" : "");
+	printf("%-10s %12s %12s %12s\n", "SYMBOL", "COMMISSION",
+	       "BNB DISC", "EST INT");
+	for (j = 0; j < nrows; j++) {
+		if (rows[j].commission == 0 && rows[j].interest == 0) continue;
+		printf("%-10s %12.4f %12.4f %12.4f\n", rows[j].symbol,
+		       rows[j].commission, rows[j].discount, rows[j].interest);
+		shown++;
+	}
+	printf("%-10s %12.4f %12.4f %12.4f\n", "TOTAL", t_comm, t_disc,
+	       t_int);
+	if (!bnb && shown)
+		printf("(set ROBSON_BNB_FEES=1 if fees are paid in BNB; the "
+		       "%.0f%% discount will be shown)\n", disc_pct);
+	if (!shown) printf("no fills in the period\n");
+	return 0;
+}
+
+#endif
@@ -0,0 +1,85 @@
+#ifndef RBS_FLAGS_H
+#define RBS_FLAGS_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+
+/* Experimental subsystems ship dark behind feature flags so the
+ * production trading path stays stable. A flag is on when it appears
+ * in ~/.robson/experimental (one name per line, # comments) or in the
+ * comma-separated ROBSON_EXPERIMENTAL environment variable. */
+
+int rbs_flag_in_csv(const char *csv, const char *name) {
+	size_t len = strlen(name);
+	const char *p = csv;
+	while (p && *p) {
+		while (*p == ',' || *p == ' ') p++;
+		if (strncmp(p, name, len) == 0 &&
+		    (p[len] == '\0' || p[len] == ','))
+			return 1;
+		p = strchr(p, ',');
+	}
+	return 0;
+}
+
+int rbs_flag_enabled(const char *name) {
+	char path[640], line[128];
+	const char *env = getenv("ROBSON_EXPERIMENTAL");
+	char *nl;
+	FILE *f;
+	if (env && rbs_flag_in_csv(env, name)) return 1;
+	rbs_state_path(path, sizeof(path), "experimental");
+	f = fopen(path, "r");
+	if (!f) return 0;
+	while (fgets(line, sizeof(line), f)) {
+		if (line[0] == '#') continue;
+		nl = strchr(line, '\n');
+		if (nl) *nl = '\0';
+		if (strcmp(line, name) == 0) {
+			fclose(f);
+			return 1;
+		}
+	}
+	fclose(f);
+	return 0;
+}
+
+/* Guard for experimental commands: prints how to opt in and returns
+ * nonzero when the flag is off. */
+int rbs_flag_require(const char *name) {
+	if (rbs_flag_enabled(name)) return 0;
+	printf("%s is experimental; enable it by adding '%s' to\n"
+	       "~/.robson/experimental or ROBSON_EXPERIMENTAL=%s\n",
+	       name, name, name);
+	return 1;
+}
+
+int rbs_flags_cmd(int argc, char *argv[]) {
+	char path[640], line[128];
+	const char *env = getenv("ROBSON_EXPERIMENTAL");
+	char *nl;
+	FILE *f;
+	int any = 0;
+	(void)argc; (void)argv;
+	rbs_state_path(path, sizeof(path), "experimental");
+	f = fopen(path, "r");
+	if (f) {
+		while (fgets(line, sizeof(line), f)) {
+			if (line[0] == '#' || line[0] == '\n') continue;
+			nl = strchr(line, '\n');
+			if (nl) *nl = '\0';
+			printf("%s (file)\n", line);
+			any = 1;
+		}
+		fclose(f);
+	}
+	if (env && *env) {
+		printf("%s (environment)\n", env);
+		any = 1;
+	}
+	if (!any) printf("no experimental features enabled\n");
+	return 0;
+}
+
+#endif
@@ -0,0 +1,55 @@
+#ifndef RBS_FMT_H
+#define RBS_FMT_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+
+/* Display precision per symbol, replacing blanket %f/%.2f: the
+ * symbol config (~/.robson/symbols/<SYM>.conf) may set qty_dp and
+ * price_dp; otherwise quantities print with 8 decimals and prices
+ * with 2, both with trailing zeros trimmed so BTC amounts stop being
+ * truncated and stable quotes stop shouting six zeros. */
+
+void rbs_fmt_trim(char *s) {
+	char *dot = strchr(s, '.');
+	char *end;
+	if (!dot) return;
+	end = s + strlen(s) - 1;
+	while (end > dot && *end == '0') *end-- = '\0';
+	if (end == dot) *end = '\0';
+}
+
+int rbs_symbol_dp(const char *symbol, const char *key, int fallback) {
+	char dir[512], path[640], line[128], k[64], v[64];
+	FILE *f;
+	int dp = fallback;
+	if (!symbol || !rbs_symbol_ok(symbol)) return fallback;
+	rbs_store_dir(dir, sizeof(dir), "symbols");
+	snprintf(path, sizeof(path), "%s/%s.conf", dir, symbol);
+	f = fopen(path, "r");
+	if (!f) return fallback;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0)
+			continue;
+		if (strcmp(k, key) == 0) dp = atoi(v);
+	}
+	fclose(f);
+	return dp >= 0 && dp <= 12 ? dp : fallback;
+}
+
+void rbs_fmt_qty(const char *symbol, double v, char *out, size_t n) {
+	int dp = rbs_symbol_dp(symbol, "qty_dp", -1);
+	if (dp < 0) {
+		snprintf(out, n, "%.8f", v);
+		rbs_fmt_trim(out);
+	} else {
+		snprintf(out, n, "%.*f", dp, v);
+	}
+}
+
+void rbs_fmt_px(const char *symbol, double v, char *out, size_t n) {
+	snprintf(out, n, "%.*f", rbs_symbol_dp(symbol, "price_dp", 2), v);
+}
+
+#endif
@@ -0,0 +1,93 @@
+#ifndef RBS_GAP_H
+#define RBS_GAP_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include "store.h"
+
+/* Gap risk over low-liquidity hours. Crypto never closes, but books
+ * thin out badly overnight (UTC) and over the weekend, and a stop
+ * resting inside a thin book fills far from its price. The estimated
+ * gap sizes are knobs until enough kline history exists to fit them:
+ *
+ *   ROBSON_GAP_NIGHT_PCT    est. overnight gap, default 1.0
+ *   ROBSON_GAP_WEEKEND_PCT  est. weekend gap, default 2.0
+ *   ROBSON_ILLIQUID_HOURS   nightly window in UTC, default 0-6
+ */
+
+double rbs_gap_night_pct(void) {
+	const char *s = getenv("ROBSON_GAP_NIGHT_PCT");
+	double v = s ? atof(s) : 1.0;
+	return v > 0 ? v : 1.0;
+}
+
+double rbs_gap_weekend_pct(void) {
+	const char *s = getenv("ROBSON_GAP_WEEKEND_PCT");
+	double v = s ? atof(s) : 2.0;
+	return v > 0 ? v : 2.0;
+}
+
+/* Next (or current) illiquid window within the horizon. Fills name
+ * and estimated gap, returns seconds until it opens (0 = inside it),
+ * or -1 when none starts inside horizon_s. */
+long rbs_gap_next_window(time_t now, long horizon_s, char *name,
+                         size_t namelen, double *gap_pct) {
+	struct tm utc;
+	int h0 = 0, h1 = 6;
+	long best = -1;
+	const char *hours = getenv("ROBSON_ILLIQUID_HOURS");
+	long t;
+	if (hours && sscanf(hours, "%d-%d", &h0, &h1) != 2) {
+		h0 = 0;
+		h1 = 6;
+	}
+	/* walk the horizon hour by hour; windows are coarse enough */
+	for (t = 0; t <= horizon_s; t += 3600) {
+		time_t at = now + t;
+		gmtime_r(&at, &utc);
+		if (utc.tm_wday == 0 || utc.tm_wday == 6) {
+			snprintf(name, namelen, "the weekend");
+			*gap_pct = rbs_gap_weekend_pct();
+			best = t;
+			break;
+		}
+		if (h0 <= h1 ? (utc.tm_hour >= h0 && utc.tm_hour < h1)
+		             : (utc.tm_hour >= h0 || utc.tm_hour < h1)) {
+			snprintf(name, namelen, "overnight hours (%02d-%02d UTC)",
+			         h0, h1);
+			*gap_pct = rbs_gap_night_pct();
+			best = t;
+			break;
+		}
+	}
+	return best;
+}
+
+/* Warning line when an estimated gap eats a meaningful share of the
+ * stop distance. stop_dist_pct <= 0 means no stop is known; the
+ * warning then speaks in absolute gap terms. Returns 1 when a
+ * warning was written. */
+int rbs_gap_warn(const char *symbol, double stop_dist_pct, long horizon_s,
+                 char *out, size_t n) {
+	char wname[48];
+	double gap = 0;
+	long in = rbs_gap_next_window(time(NULL), horizon_s, wname,
+	                              sizeof(wname), &gap);
+	if (in < 0) return 0;
+	if (stop_dist_pct > 0) {
+		double share = gap / stop_dist_pct * 100.0;
+		if (share < 50.0) return 0;
+		snprintf(out, n, "gap risk: %s held into %s%s; est. %.1f%% gap "
+		         "covers %.0f%% of the %.2f%% stop distance",
+		         symbol, wname, in == 0 ? " (now)" : "", gap, share,
+		         stop_dist_pct);
+		return 1;
+	}
+	snprintf(out, n, "gap risk: %s held into %s%s without a known stop; "
+	         "books thin out, expect gaps near %.1f%%", symbol, wname,
+	         in == 0 ? " (now)" : "", gap);
+	return 1;
+}
+
+#endif
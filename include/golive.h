@@ -0,0 +1,97 @@
+#ifndef RBS_GOLIVE_H
+#define RBS_GOLIVE_H
+#include <stdio.h>
+#include <string.h>
+#include <time.h>
+#include "store.h"
+#include "config.h"
+#include "operation.h"
+#include "daemon.h"
+
+/* Cold-start safety: a profile may not place live orders until the
+ * go-live checklist has passed once - a completed paper trade, a
+ * recent fire-drill, the withdrawal watch running, and a confirmed
+ * notification channel. robson go-live runs the checks and records
+ * the clearance; execute --live refuses without it. */
+
+void rbs_golive_path(char *out, size_t n) {
+	char profile[64], rel[96];
+	rbs_config_profile(profile, sizeof(profile));
+	snprintf(rel, sizeof(rel), "golive-%s", profile);
+	rbs_state_path(out, n, rel);
+}
+
+int rbs_golive_cleared(void) {
+	char path[640];
+	rbs_golive_path(path, sizeof(path));
+	return access(path, F_OK) == 0;
+}
+
+int rbs_golive_cmd(int argc, char *argv[]) {
+	static rbs_operation ops[8];
+	char path[640], profile[64];
+	rbs_daemon d;
+	FILE *f;
+	int i, ok = 1, check;
+	long drill = 0;
+	time_t now = time(NULL);
+	rbs_config_profile(profile, sizeof(profile));
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--notify-ok") == 0) {
+			rbs_state_path(path, sizeof(path), "notify_test");
+			f = fopen(path, "w");
+			if (f) {
+				fprintf(f, "%ld\n", (long)now);
+				fclose(f);
+			}
+			printf("notification channel marked as tested\n");
+			return 0;
+		}
+	}
+	printf("go-live checklist for profile %s:\n", profile);
+
+	check = rbs_operation_load_all(ops, 8) > 0;
+	printf("[%s] at least one paper trade completed\n", check ? "pass" : "FAIL");
+	ok &= check;
+
+	rbs_state_path(path, sizeof(path), "firedrill_pass");
+	drill = 0;
+	f = fopen(path, "r");
+	if (f) {
+		if (fscanf(f, "%ld", &drill) != 1) drill = 0;
+		fclose(f);
+	}
+	check = drill > 0 && now - drill < 7 * 86400;
+	printf("[%s] fire-drill passed within 7 days%s\n",
+	       check ? "pass" : "FAIL",
+	       check ? "" : " (run robson fire-drill)");
+	ok &= check;
+
+	check = rbs_daemon_load((char *)"withdrawals", &d) == 0 &&
+	        rbs_daemon_alive(d.pid);
+	printf("[%s] withdrawal watch running%s\n", check ? "pass" : "FAIL",
+	       check ? "" : " (start robson withdrawals watch)");
+	ok &= check;
+
+	rbs_state_path(path, sizeof(path), "notify_test");
+	check = access(path, F_OK) == 0;
+	printf("[%s] notification channel tested%s\n", check ? "pass" : "FAIL",
+	       check ? "" : " (send a test alert, then robson go-live --notify-ok)");
+	ok &= check;
+
+	if (!ok) {
+		printf("not cleared for live trading; fix the failed items and "
+		       "re-run robson go-live\n");
+		return 1;
+	}
+	rbs_golive_path(path, sizeof(path));
+	f = fopen(path, "w");
+	if (f) {
+		fprintf(f, "cleared=%ld\n", (long)now);
+		fclose(f);
+	}
+	printf("profile %s cleared for live trading\n", profile);
+	return 0;
+}
+
+#endif
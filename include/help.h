@@ -0,0 +1,64 @@
+#ifndef RBS_HELP_H
+#define RBS_HELP_H
+#include <stdio.h>
+#include "i18n.h"
+#include "version.h"
+
+void rbs_openscreen_help() {
+	printf(rbs_tr("help.title"), RBS_VERSION);
+	printf("\n");
+	printf("%s", rbs_tr("help.usage"));
+	printf("%s", rbs_tr("help.usage2"));
+	printf("\n");
+	printf("%s", rbs_tr("help.help"));
+	printf("%s", rbs_tr("help.report"));
+	printf("%s", rbs_tr("help.say"));
+	printf("%s", rbs_tr("help.buy"));
+	printf("%s", rbs_tr("help.sell"));
+	printf("\n");
+	printf("  plan new <symbol> <buy|sell> <qty> <price> [--strategy NAME] [--ttl 24h]\n");
+	printf("  plan list [--status S] [--strategy NAME] [--since YYYY-MM-DD] [--json]\n");
+	printf("  plan show <id> [--json]\n");
+	printf("  plan delete <id>\n");
+	printf("  plan --file <plan.yaml>\n");
+	printf("  plan export <id> [--format yaml]\n");
+	printf("  plan amend <id> [--quantity N] [--price N]\n");
+	printf("  plan multi <symbol> --entry <qty>@<price> --stop <price> --tp <price>\n");
+	printf("  plan template <list|show|apply> ...\n");
+	printf("  plan stats [--since YYYY-MM-DD]\n");
+	printf("  plan gc [--older-than 30d] [--status executed,expired] [--force]\n");
+	printf("  plan sync        deliver queued audit records (ROBSON_AUDIT_SINK)\n");
+	printf("  plan rebalance --target-allocation btc:50,eth:30,usdt:20\n");
+	printf("\n");
+	printf("%s", rbs_tr("help.validate"));
+	printf("%s", rbs_tr("help.execute"));
+	printf("  explain <check-id> [plan-id]  why a validation check failed and how to fix it\n");
+	printf("\n");
+	printf("%s", rbs_tr("help.price"));
+	printf("  indicator <name> [--period N] <values...>   sma/ema/rsi + plugins\n");
+	printf("  script eval <name> [var=value ...]     (experimental: scripting)\n");
+	printf("  order amend <plan-id> --stop <price>   adjust a live stop\n");
+	printf("  positions        open positions with fee-true break-even\n");
+	printf("  account          balances, quotes and patrimony (concurrent fetch)\n");
+	printf("  compare-fees --size N --horizon 7d   cost across instruments\n");
+	printf("  margin-status | margin-positions | margin-buy <sym> <qty> <price>\n");
+	printf("  operations [--limit N|--all]  recorded operations (local or API)\n");
+	printf("  fire-drill       paper readiness drill through the whole pipeline\n");
+	printf("  http get <url>   shared transport (timeout/proxy/CA knobs)\n");
+	printf("  auth set-token <token> [--refresh T] [--expires-in 1h]\n");
+	printf("  auth show        where the token lives and whether it is valid\n");
+	printf("  auth token       print the access token, auto-renewing when expired\n");
+	printf("  serve-api [--port 8741]       local REST gateway (/simulate, /healthz)\n");
+	printf("  auto [--strategy-id S] [--max-live N] [--once]   signal-to-plan daemon\n");
+	printf("  allocate --capital N --strategies trend:60,meanrev:40\n");
+	printf("  stress --scenario \"-20%% btc, -30%% alts\" (or may-2021, ftx-week)\n");
+	printf("  whatif --symbol S --entry P --stop P --capital N [--leverage N]\n");
+	printf("  snapshot         capture balances and operations for later diff\n");
+	printf("  diff [--since 24h]            what changed since a prior snapshot\n");
+	printf("  watch <symbol> [--once]       price with candle-close and funding countdowns\n");
+	printf("%s", rbs_tr("help.top"));
+	printf("%s", rbs_tr("help.bugreport"));
+	printf("%s", rbs_tr("help.flags"));
+}
+
+#endif
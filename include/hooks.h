@@ -0,0 +1,169 @@
+#ifndef RBS_HOOKS_H
+#define RBS_HOOKS_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include <sys/wait.h>
+#include "store.h"
+
+/* Runbook hooks: ~/.robson/hooks.conf maps an event to a command that
+ * runs when the event fires (margin-warning -> execute a hedge plan,
+ * feed-stale -> restart the feeder, ...). The event and its detail
+ * reach the command through ROBSON_EVENT / ROBSON_EVENT_DETAIL rather
+ * than the command line, so detail text cannot grow shell syntax.
+ * Everything the command prints lands in ~/.robson/hooks.log with a
+ * timestamp - the runbook's own audit trail. */
+
+static const char *rbs_hook_events[] = {
+	"margin-warning", "feed-stale", "feed-quarantine", "pause",
+	"drift-abort",
+};
+#define RBS_HOOK_NEVENTS 5
+
+int rbs_hook_event_ok(const char *event) {
+	int i;
+	for (i = 0; i < RBS_HOOK_NEVENTS; i++)
+		if (strcmp(rbs_hook_events[i], event) == 0) return 1;
+	return 0;
+}
+
+/* Command configured for an event, or NULL. */
+const char *rbs_hook_lookup(const char *event, char *buf, size_t n) {
+	char path[640], line[512], k[64], v[448];
+	FILE *f;
+	int found = 0;
+	rbs_state_path(path, sizeof(path), "hooks.conf");
+	f = fopen(path, "r");
+	if (!f) return NULL;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0)
+			continue;
+		if (strcmp(k, event) == 0) {
+			snprintf(buf, n, "%s", v);
+			found = 1;
+		}
+	}
+	fclose(f);
+	return found ? buf : NULL;
+}
+
+/* Fire an event: run its hook (if any) and log the output. A depth
+ * guard stops a hook that shells back into robson from re-firing
+ * hooks forever. */
+void rbs_hook_fire(const char *event, const char *detail) {
+	char cmd[448], logp[640], line[512], when[40];
+	const char *depth = getenv("ROBSON_HOOK_DEPTH");
+	FILE *p, *log;
+	int status;
+	if (depth && atoi(depth) >= 1) return;
+	if (!rbs_hook_lookup(event, cmd, sizeof(cmd))) return;
+	setenv("ROBSON_EVENT", event, 1);
+	setenv("ROBSON_EVENT_DETAIL", detail ? detail : "", 1);
+	setenv("ROBSON_HOOK_DEPTH", "1", 1);
+	p = popen(cmd, "r");
+	rbs_state_path(logp, sizeof(logp), "hooks.log");
+	log = fopen(logp, "a");
+	if (log) {
+		rbs_fmt_time_short(time(NULL), when, sizeof(when));
+		fprintf(log, "%s %s (%s) -> %s\n", when, event,
+		        detail ? detail : "", cmd);
+	}
+	if (p) {
+		while (fgets(line, sizeof(line), p))
+			if (log) fprintf(log, "  %s", line);
+		status = pclose(p);
+		if (log)
+			fprintf(log, "  exit %d\n",
+			        WIFEXITED(status) ? WEXITSTATUS(status) : 1);
+	} else if (log) {
+		fprintf(log, "  could not start hook\n");
+	}
+	if (log) fclose(log);
+	unsetenv("ROBSON_HOOK_DEPTH");
+	unsetenv("ROBSON_EVENT");
+	unsetenv("ROBSON_EVENT_DETAIL");
+}
+
+int rbs_hook_cmd(int argc, char *argv[]) {
+	char path[640], tmp[700], line[512], k[64], v[448], buf[448];
+	FILE *in, *out;
+	int i;
+	rbs_state_path(path, sizeof(path), "hooks.conf");
+	if (argc >= 2 && strcmp(argv[1], "list") == 0) {
+		for (i = 0; i < RBS_HOOK_NEVENTS; i++) {
+			if (rbs_hook_lookup(rbs_hook_events[i], buf, sizeof(buf)))
+				printf("%-16s %s\n", rbs_hook_events[i], buf);
+			else
+				printf("%-16s -\n", rbs_hook_events[i]);
+		}
+		return 0;
+	}
+	if (argc >= 4 && strcmp(argv[1], "set") == 0) {
+		if (!rbs_hook_event_ok(argv[2])) {
+			printf("unknown event %s; known:", argv[2]);
+			for (i = 0; i < RBS_HOOK_NEVENTS; i++)
+				printf(" %s", rbs_hook_events[i]);
+			printf("\n");
+			return 1;
+		}
+		snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+		out = fopen(tmp, "w");
+		if (!out) return 1;
+		in = fopen(path, "r");
+		if (in) {
+			while (fgets(line, sizeof(line), in)) {
+				char parse[512];
+				snprintf(parse, sizeof(parse), "%s", line);
+				if (rbs_kv_line(parse, k, sizeof(k), v, sizeof(v)) == 0 &&
+				    strcmp(k, argv[2]) == 0)
+					continue;
+				fputs(line, out);
+			}
+			fclose(in);
+		}
+		fprintf(out, "%s=%s\n", argv[2], argv[3]);
+		fclose(out);
+		rename(tmp, path);
+		printf("hook %s -> %s\n", argv[2], argv[3]);
+		return 0;
+	}
+	if (argc >= 3 && strcmp(argv[1], "unset") == 0) {
+		snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+		out = fopen(tmp, "w");
+		if (!out) return 1;
+		in = fopen(path, "r");
+		if (in) {
+			while (fgets(line, sizeof(line), in)) {
+				char parse[512];
+				snprintf(parse, sizeof(parse), "%s", line);
+				if (rbs_kv_line(parse, k, sizeof(k), v, sizeof(v)) == 0 &&
+				    strcmp(k, argv[2]) == 0)
+					continue;
+				fputs(line, out);
+			}
+			fclose(in);
+		}
+		fclose(out);
+		rename(tmp, path);
+		printf("hook %s cleared\n", argv[2]);
+		return 0;
+	}
+	if (argc >= 3 && strcmp(argv[1], "run") == 0) {
+		if (!rbs_hook_event_ok(argv[2])) {
+			printf("unknown event %s\n", argv[2]);
+			return 1;
+		}
+		if (!rbs_hook_lookup(argv[2], buf, sizeof(buf))) {
+			printf("no hook for %s\n", argv[2]);
+			return 1;
+		}
+		rbs_hook_fire(argv[2], argc >= 4 ? argv[3] : "manual test");
+		printf("fired %s; output in ~/.robson/hooks.log\n", argv[2]);
+		return 0;
+	}
+	printf("usage: robson hook <list|set EVENT CMD|unset EVENT|run EVENT [detail]>\n");
+	return 1;
+}
+
+#endif
@@ -0,0 +1,444 @@
+#ifndef RBS_HTTP_H
+#define RBS_HTTP_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <arpa/inet.h>
+#include <netdb.h>
+#include <netinet/in.h>
+#include <poll.h>
+#include <sys/socket.h>
+#include <sys/time.h>
+#include <unistd.h>
+#include "store.h"
+#include "ratelimit.h"
+
+/* Shared HTTP transport. Plain http:// goes over our own socket with
+ * the configured timeouts; https:// (and anything needing a proxy or
+ * custom CA) is delegated to curl, which gets the same transport
+ * settings mapped onto its flags. Knobs:
+ *
+ *   ROBSON_HTTP_TIMEOUT_MS   request timeout (default 5000)
+ *   ROBSON_HTTP_PROXY        http(s) proxy URL
+ *   ROBSON_CA_BUNDLE         custom CA bundle file
+ *   ROBSON_CLIENT_CERT       client certificate for mTLS deployments
+ *   ROBSON_CLIENT_KEY        matching private key
+ *   ROBSON_TLS_SKIP_VERIFY   1 = do not verify peers (loud warning)
+ */
+
+long rbs_http_timeout_ms() {
+	const char *s = getenv("ROBSON_HTTP_TIMEOUT_MS");
+	long v = s ? atol(s) : 5000;
+	return v > 0 ? v : 5000;
+}
+
+int rbs_tls_skip_verify() {
+	const char *s = getenv("ROBSON_TLS_SKIP_VERIFY");
+	return s && strcmp(s, "1") == 0;
+}
+
+/* Typed error model: every non-2xx or failed request maps to a
+ * stable machine-readable code so callers and --json consumers can
+ * branch without parsing prose. */
+static long rbs_http_retry_after;	/* seconds, from the last 429 */
+
+/* 0 = quiet, 1 = --verbose (method, URL, status, latency),
+ * 2 = --trace (adds redacted bodies). */
+int rbs_http_verbosity() {
+	const char *s = getenv("ROBSON_VERBOSE");
+	return s ? atoi(s) : 0;
+}
+
+/* Mask anything that looks like a credential value before a body hits
+ * the trace log. */
+void rbs_http_redact(const char *in, char *out, size_t n) {
+	static const char *needles[] = { "token", "secret", "password", "key" };
+	size_t i = 0, o = 0, k;
+	int hot;
+	while (in[i] && o + 2 < n) {
+		out[o++] = in[i];
+		for (k = 0, hot = 0; k < 4; k++) {
+			size_t l = strlen(needles[k]);
+			if (i + 1 >= l && strncasecmp(in + i + 1 - l, needles[k], l) == 0)
+				hot = 1;
+		}
+		i++;
+		if (hot && (in[i] == '"' || in[i] == '=' || in[i] == ':')) {
+			/* copy the delimiter run, then mask the value */
+			while (in[i] && strchr("\":= ", in[i]) && o + 2 < n)
+				out[o++] = in[i++];
+			while (in[i] && !strchr("\",}&\n", in[i]) && in[i] != '"')
+				i++;
+			if (o + 10 < n) {	/* mask plus the closing NUL */
+				memcpy(out + o, "<redacted>", 10);
+				o += 10;
+			}
+		}
+	}
+	out[o] = '\0';
+}
+
+const char *rbs_api_error_code(int status) {
+	if (status < 0) return "unreachable";
+	if (status == 401) return "unauthorized";
+	if (status == 403) return "forbidden";
+	if (status == 404) return "not_found";
+	if (status == 400 || status == 422) return "validation";
+	if (status == 429) return "rate_limited";
+	if (status >= 500) return "server_error";
+	return "error";
+}
+
+/* Response cache under ~/.robson/httpcache keyed by URL hash:
+ * first line the ETag, then the body. A hit is revalidated with
+ * If-None-Match; 304 serves the cached body for free. */
+unsigned long rbs_url_hash(const char *s) {
+	unsigned long h = 1469598103934665603UL;
+	for (; *s; s++) h = (h ^ (unsigned char)*s) * 1099511628211UL;
+	return h;
+}
+
+void rbs_httpcache_path(const char *url, char *out, size_t n) {
+	char dir[512];
+	rbs_store_dir(dir, sizeof(dir), "httpcache");
+	snprintf(out, n, "%s/%lx", dir, rbs_url_hash(url));
+}
+
+int rbs_httpcache_load(const char *url, char *etag, size_t en,
+                       char *body, size_t bn) {
+	char path[640];
+	FILE *f;
+	size_t got;
+	char *nl;
+	rbs_httpcache_path(url, path, sizeof(path));
+	f = fopen(path, "r");
+	if (!f) return -1;
+	if (!fgets(etag, en, f)) {
+		fclose(f);
+		return -1;
+	}
+	nl = strchr(etag, '\n');
+	if (nl) *nl = '\0';
+	got = fread(body, 1, bn - 1, f);
+	body[got] = '\0';
+	fclose(f);
+	return 0;
+}
+
+void rbs_httpcache_store(const char *url, const char *etag, const char *body) {
+	char path[640], tmp[700];
+	FILE *f;
+	rbs_httpcache_path(url, path, sizeof(path));
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(tmp, "w");
+	if (!f) return;
+	fprintf(f, "%s\n%s", etag, body);
+	fclose(f);
+	rename(tmp, path);
+}
+
+/* GET over a raw socket for plain http:// URLs. Body (headers
+ * stripped) lands in out. inm, when set, is sent as If-None-Match;
+ * the response ETag (if any) is copied to etag_out. Returns the HTTP
+ * status, or -1. */
+int rbs_http_get_raw2(const char *url, const char *inm,
+                      char *etag_out, size_t etag_n,
+                      char *out, size_t n) {
+	char host[256], path[512], portbuf[16];
+	const char *p, *slash, *colon;
+	struct addrinfo hints, *res, *ai;
+	struct pollfd pfd;
+	char req[1024], buf[8192], *body;
+	size_t o = 0;
+	ssize_t got;
+	int fd = -1, status = -1, port = 80;
+	if (strncmp(url, "http://", 7) != 0) return -1;
+	p = url + 7;
+	slash = strchr(p, '/');
+	snprintf(host, sizeof(host), "%.*s",
+	         slash ? (int)(slash - p) : (int)strlen(p), p);
+	snprintf(path, sizeof(path), "%s", slash ? slash : "/");
+	colon = strchr(host, ':');
+	if (colon) {
+		port = atoi(colon + 1);
+		host[colon - host] = '\0';
+	}
+	snprintf(portbuf, sizeof(portbuf), "%d", port);
+	memset(&hints, 0, sizeof(hints));
+	hints.ai_family = AF_INET;
+	hints.ai_socktype = SOCK_STREAM;
+	if (getaddrinfo(host, portbuf, &hints, &res) != 0) return -1;
+	for (ai = res; ai; ai = ai->ai_next) {
+		struct timeval tv;
+		fd = socket(ai->ai_family, ai->ai_socktype, ai->ai_protocol);
+		if (fd < 0) continue;
+		tv.tv_sec = rbs_http_timeout_ms() / 1000;
+		tv.tv_usec = (rbs_http_timeout_ms() % 1000) * 1000;
+		/* bounds connect and any blocking writes too */
+		setsockopt(fd, SOL_SOCKET, SO_SNDTIMEO, &tv, sizeof(tv));
+		setsockopt(fd, SOL_SOCKET, SO_RCVTIMEO, &tv, sizeof(tv));
+		if (connect(fd, ai->ai_addr, ai->ai_addrlen) == 0) break;
+		close(fd);
+		fd = -1;
+	}
+	freeaddrinfo(res);
+	if (fd < 0) return -1;
+	if (inm && *inm)
+		snprintf(req, sizeof(req),
+		         "GET %s HTTP/1.0\r\nHost: %s\r\nUser-Agent: robson\r\n"
+		         "If-None-Match: %s\r\n\r\n", path, host, inm);
+	else
+		snprintf(req, sizeof(req),
+		         "GET %s HTTP/1.0\r\nHost: %s\r\nUser-Agent: robson\r\n\r\n",
+		         path, host);
+	if (write(fd, req, strlen(req)) < 0) {
+		close(fd);
+		return -1;
+	}
+	pfd.fd = fd;
+	pfd.events = POLLIN;
+	out[0] = '\0';
+	/* headers (plus whatever body arrived with them) land in buf;
+	 * the rest of the body streams straight into the caller's buffer
+	 * so the response is bounded by n, not by this scratch space */
+	while (o + 1 < sizeof(buf)) {
+		if (poll(&pfd, 1, (int)rbs_http_timeout_ms()) <= 0) break;
+		got = read(fd, buf + o, sizeof(buf) - 1 - o);
+		if (got <= 0) break;
+		o += got;
+		buf[o] = '\0';
+		if (strstr(buf, "\r\n\r\n")) break;
+	}
+	buf[o] = '\0';
+	if (sscanf(buf, "HTTP/%*s %d", &status) != 1) {
+		close(fd);
+		return -1;
+	}
+	body = strstr(buf, "\r\n\r\n");
+	if (body) {
+		size_t have = o - (size_t)(body + 4 - buf), bo;
+		if (have > n - 1) have = n - 1;
+		memcpy(out, body + 4, have);
+		bo = have;
+		while (bo + 1 < n) {
+			if (poll(&pfd, 1, (int)rbs_http_timeout_ms()) <= 0) break;
+			got = read(fd, out + bo, n - 1 - bo);
+			if (got <= 0) break;
+			bo += got;
+		}
+		out[bo] = '\0';
+		*body = '\0';	/* header scans below must not see the body */
+	}
+	close(fd);
+	{
+		const char *ra = strcasestr(buf, "Retry-After:");
+		rbs_http_retry_after = ra ? atol(ra + 12) : 0;
+	}
+	if (etag_out) {
+		const char *e = strcasestr(buf, "ETag:");
+		etag_out[0] = '\0';
+		if (e) {
+			e += 5;
+			while (*e == ' ') e++;
+			for (o = 0; e[o] && e[o] != '\r' && e[o] != '\n' &&
+			     o < (ssize_t)etag_n - 1; o++)
+				etag_out[o] = e[o];
+			etag_out[o] = '\0';
+		}
+	}
+	if (!body) out[0] = '\0';
+	return status;
+}
+
+int rbs_http_get_raw(const char *url, char *out, size_t n) {
+	return rbs_http_get_raw2(url, NULL, NULL, 0, out, n);
+}
+
+/* POST a body over a raw socket (plain http only); returns the HTTP
+ * status or -1. Used by shippers that push rather than pull. */
+int rbs_http_post_raw(const char *url, const char *data, size_t len) {
+	char host[256], path[512], portbuf[16], head[700], buf[1024];
+	const char *p, *slash, *colon;
+	struct addrinfo hints, *res, *ai;
+	int fd = -1, status = -1, port = 80, hn;
+	if (strncmp(url, "http://", 7) != 0) return -1;
+	p = url + 7;
+	slash = strchr(p, '/');
+	snprintf(host, sizeof(host), "%.*s",
+	         slash ? (int)(slash - p) : (int)strlen(p), p);
+	snprintf(path, sizeof(path), "%s", slash ? slash : "/");
+	colon = strchr(host, ':');
+	if (colon) {
+		port = atoi(colon + 1);
+		host[colon - host] = '\0';
+	}
+	snprintf(portbuf, sizeof(portbuf), "%d", port);
+	memset(&hints, 0, sizeof(hints));
+	hints.ai_family = AF_INET;
+	hints.ai_socktype = SOCK_STREAM;
+	if (getaddrinfo(host, portbuf, &hints, &res) != 0) return -1;
+	for (ai = res; ai; ai = ai->ai_next) {
+		struct timeval tv;
+		fd = socket(ai->ai_family, ai->ai_socktype, ai->ai_protocol);
+		if (fd < 0) continue;
+		tv.tv_sec = rbs_http_timeout_ms() / 1000;
+		tv.tv_usec = (rbs_http_timeout_ms() % 1000) * 1000;
+		setsockopt(fd, SOL_SOCKET, SO_SNDTIMEO, &tv, sizeof(tv));
+		setsockopt(fd, SOL_SOCKET, SO_RCVTIMEO, &tv, sizeof(tv));
+		if (connect(fd, ai->ai_addr, ai->ai_addrlen) == 0) break;
+		close(fd);
+		fd = -1;
+	}
+	freeaddrinfo(res);
+	if (fd < 0) return -1;
+	hn = snprintf(head, sizeof(head),
+	              "POST %s HTTP/1.0\r\nHost: %s\r\nUser-Agent: robson\r\n"
+	              "Content-Type: text/plain\r\nContent-Length: %zu\r\n\r\n",
+	              path, host, len);
+	if (write(fd, head, hn) < 0 || write(fd, data, len) < 0) {
+		close(fd);
+		return -1;
+	}
+	if (read(fd, buf, sizeof(buf) - 1) > 0)
+		sscanf(buf, "HTTP/%*s %d", &status);
+	close(fd);
+	return status;
+}
+
+/* GET via curl for https and proxied requests; same contract. */
+int rbs_http_get_curl(const char *url, char *out, size_t n) {
+	char cmd[1024];
+	/* url and settings land in a shell line; a quote would break out
+	 * of its quoting */
+	if (strchr(url, '\'')) return -1;
+	const char *proxy = getenv("ROBSON_HTTP_PROXY");
+	const char *ca = getenv("ROBSON_CA_BUNDLE");
+	const char *cert = getenv("ROBSON_CLIENT_CERT");
+	const char *ckey = getenv("ROBSON_CLIENT_KEY");
+	FILE *p;
+	size_t got;
+	int o = snprintf(cmd, sizeof(cmd),
+	                 "curl -s -m %ld -w '\\n%%{http_code}'",
+	                 rbs_http_timeout_ms() / 1000 + 1);
+	/* snprintf reports desired, not written: a setting too long to fit
+	 * would push o past the buffer and make the next length underflow,
+	 * so a command that stopped fitting is refused rather than run
+	 * truncated */
+	if (proxy && *proxy)
+		o += snprintf(cmd + o, sizeof(cmd) - o, " -x '%s'", proxy);
+	if (o >= (int)sizeof(cmd)) return -1;
+	if (ca && *ca)
+		o += snprintf(cmd + o, sizeof(cmd) - o, " --cacert '%s'", ca);
+	if (o >= (int)sizeof(cmd)) return -1;
+	if (cert && *cert && (strchr(cert, '\'') || (ckey && strchr(ckey, '\''))))
+		return -1;
+	if (cert && *cert) {
+		o += snprintf(cmd + o, sizeof(cmd) - o, " --cert '%s'", cert);
+		if (o >= (int)sizeof(cmd)) return -1;
+		if (ckey && *ckey)
+			o += snprintf(cmd + o, sizeof(cmd) - o, " --key '%s'", ckey);
+		if (o >= (int)sizeof(cmd)) return -1;
+	}
+	if (rbs_tls_skip_verify()) {
+		fprintf(stderr, "WARNING: TLS verification disabled "
+		        "(ROBSON_TLS_SKIP_VERIFY=1); do not use with real keys\n");
+		o += snprintf(cmd + o, sizeof(cmd) - o, " -k");
+		if (o >= (int)sizeof(cmd)) return -1;
+	}
+	if (o + strlen(url) + 16 >= sizeof(cmd)) return -1;
+	snprintf(cmd + o, sizeof(cmd) - o, " '%s' 2>/dev/null", url);
+	p = popen(cmd, "r");
+	if (!p) return -1;
+	got = fread(out, 1, n - 1, p);
+	out[got] = '\0';
+	if (pclose(p) != 0) return -1;
+	/* last line is the status code */
+	{
+		char *nl = strrchr(out, '\n');
+		int status = -1;
+		if (!nl) return -1;
+		status = atoi(nl + 1);
+		*nl = '\0';
+		return status;
+	}
+}
+
+int rbs_http_get_inner(const char *url, char *out, size_t n) {
+	static char cached_etag[128], cached_body[65536], new_etag[128];
+	const char *proxy = getenv("ROBSON_HTTP_PROXY");
+	const char *nocache = getenv("ROBSON_HTTP_NO_CACHE");
+	const char *cert = getenv("ROBSON_CLIENT_CERT");
+	int have_cache, status;
+	rbs_rate_take("backend");
+	if (strncmp(url, "http://", 7) != 0 || (proxy && *proxy) ||
+	    (cert && *cert))
+		return rbs_http_get_curl(url, out, n);
+	if (nocache && strcmp(nocache, "1") == 0)
+		return rbs_http_get_raw(url, out, n);
+	have_cache = rbs_httpcache_load(url, cached_etag, sizeof(cached_etag),
+	                                cached_body, sizeof(cached_body)) == 0;
+	status = rbs_http_get_raw2(url, have_cache ? cached_etag : NULL,
+	                           new_etag, sizeof(new_etag), out, n);
+	if (status == 304 && have_cache) {
+		snprintf(out, n, "%s", cached_body);
+		return 200;
+	}
+	if (status == 200 && new_etag[0])
+		rbs_httpcache_store(url, new_etag, out);
+	return status;
+}
+
+int rbs_http_get(const char *url, char *out, size_t n) {
+	static char redacted[4096];
+	long t0, ms;
+	int status, v = rbs_http_verbosity();
+	if (!v)
+		return rbs_http_get_inner(url, out, n);
+	t0 = rbs_rl_now_ms();
+	status = rbs_http_get_inner(url, out, n);
+	ms = rbs_rl_now_ms() - t0;
+	fprintf(stderr, "GET %s -> %d (%ldms)\n", url, status, ms);
+	if (v >= 2) {
+		rbs_http_redact(out, redacted, sizeof(redacted));
+		fprintf(stderr, "  body: %s\n", redacted);
+	}
+	return status;
+}
+
+int rbs_http_cmd(int argc, char *argv[]) {
+	static char body[65536];
+	const char *url = NULL;
+	int status, i, json = 0;
+	for (i = 2; i < argc; i++) {
+		if (strcmp(argv[i], "--no-cache") == 0)
+			setenv("ROBSON_HTTP_NO_CACHE", "1", 1);
+		else if (strcmp(argv[i], "--json") == 0) json = 1;
+		else url = argv[i];
+	}
+	if (argc < 3 || strcmp(argv[1], "get") != 0 || !url) {
+		printf("usage: robson http get <url> [--no-cache]\n");
+		return 1;
+	}
+	status = rbs_http_get(url, body, sizeof(body));
+	if (status >= 200 && status < 300) {
+		printf("%s\n", body);
+		return 0;
+	}
+	if (json) {
+		printf("{\"error\":{\"code\":\"%s\",\"status\":%d",
+		       rbs_api_error_code(status), status);
+		if (rbs_http_retry_after > 0)
+			printf(",\"retry_after\":%ld", rbs_http_retry_after);
+		printf("}}\n");
+	} else {
+		printf("error: %s (HTTP %d)%s", rbs_api_error_code(status), status,
+		       status >= 0 && body[0] ? ": " : "");
+		if (status >= 0 && body[0]) printf("%s", body);
+		printf("\n");
+		if (rbs_http_retry_after > 0)
+			printf("retry after %lds\n", rbs_http_retry_after);
+	}
+	return 1;
+}
+
+#endif
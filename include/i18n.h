@@ -0,0 +1,61 @@
+#ifndef RBS_I18N_H
+#define RBS_I18N_H
+#include <stdlib.h>
+#include <string.h>
+
+/* Message catalog. Every human-facing screen line gets a key here with
+ * its English and Brazilian Portuguese texts; rbs_tr falls back to
+ * English and, for a key nobody added yet, to the key itself so a
+ * missing translation never blanks output. Locale comes from --lang,
+ * then ROBSON_LANG, then LANG. */
+
+typedef struct {
+	const char *key;
+	const char *en;
+	const char *pt;
+} rbs_msg;
+
+static const rbs_msg rbs_msgs[] = {
+	{ "welcome", "Welcome to Robson %s\n", "Bem-vindo ao Robson %s\n" },
+	{ "help.title", "Robson %s - your trading robot\n", "Robson %s - seu robo de operacoes\n" },
+	{ "help.usage", "usage: robson [--help|--report|--say|--buy|--sell]\n", "uso: robson [--help|--report|--say|--buy|--sell]\n" },
+	{ "help.usage2", "       robson <command> ... (--lang pt-BR for Portuguese)\n", "       robson <comando> ... (--lang en para ingles)\n" },
+	{ "help.help", "  --help     this screen\n", "  --help     esta tela\n" },
+	{ "help.report", "  --report   operations report\n", "  --report   relatorio de operacoes\n" },
+	{ "help.say", "  --say      robson says hello\n", "  --say      robson diz ola\n" },
+	{ "help.buy", "  --buy      buy screen\n", "  --buy      tela de compra\n" },
+	{ "help.sell", "  --sell     sell screen\n", "  --sell     tela de venda\n" },
+	{ "help.validate", "  validate <plan-id>   gate a pending plan for execution\n", "  validate <plan-id>   libera um plano pendente para execucao\n" },
+	{ "help.execute", "  execute <plan-id>    run a validated plan and record the operation\n", "  execute <plan-id>    executa um plano validado e registra a operacao\n" },
+	{ "help.price", "  price <symbol>   cached quote (TTL via ROBSON_PRICE_TTL_MS)\n", "  price <symbol>   cotacao em cache (TTL via ROBSON_PRICE_TTL_MS)\n" },
+	{ "help.top", "  top [--once] [--interval N]   monitor running robson daemons\n", "  top [--once] [--interval N]   monitora os daemons do robson\n" },
+	{ "help.bugreport", "  bugreport        bundle crash dumps from ~/.robson/crash for an issue\n", "  bugreport        empacota os dumps de ~/.robson/crash para uma issue\n" },
+	{ "help.flags", "  flags            list enabled experimental features\n", "  flags            lista os recursos experimentais ativos\n" },
+	{ "say.hello", "Robson says: hello! Ready to trade.\n", "Robson diz: ola! Pronto para operar.\n" },
+	{ "buy.title", "Robson buy\n", "Robson compra\n" },
+	{ "buy.hint", "Register a buy intent with: robson plan new <symbol> buy <qty> <price>\n", "Registre uma intencao de compra com: robson plan new <simbolo> buy <qtde> <preco>\n" },
+	{ "sell.title", "Robson sell\n", "Robson venda\n" },
+	{ "sell.hint", "Register a sell intent with: robson plan new <symbol> sell <qty> <price>\n", "Registre uma intencao de venda com: robson plan new <simbolo> sell <qtde> <preco>\n" },
+	{ "report.title", "Robson report\n", "Relatorio do Robson\n" },
+	{ "report.empty", "No operations yet.\n", "Nenhuma operacao ainda.\n" },
+	{ "bad.argument", "Type --help. Invalid argument %s\n", "Use --help. Argumento invalido %s\n" },
+};
+
+int rbs_lang_is_pt() {
+	const char *l = getenv("ROBSON_LANG");
+	if (!l || !*l) l = getenv("LANG");
+	if (!l) return 0;
+	return strncmp(l, "pt", 2) == 0;
+}
+
+const char *rbs_tr(const char *key) {
+	size_t i;
+	int pt = rbs_lang_is_pt();
+	for (i = 0; i < sizeof(rbs_msgs) / sizeof(rbs_msgs[0]); i++) {
+		if (strcmp(rbs_msgs[i].key, key) == 0)
+			return pt && rbs_msgs[i].pt ? rbs_msgs[i].pt : rbs_msgs[i].en;
+	}
+	return key;
+}
+
+#endif
@@ -0,0 +1,71 @@
+#ifndef RBS_INIT_H
+#define RBS_INIT_H
+#include <stdio.h>
+#include <string.h>
+#include "store.h"
+#include "config.h"
+#include "http.h"
+#include "token.h"
+
+/* First-run wizard: ask for the backend, auth, tenant and
+ * environment, check connectivity before trusting the URL, and write
+ * the profile - so a new user never has to reverse-engineer
+ * environment variables from source. */
+
+void rbs_init_prompt(const char *q, const char *def, char *out, size_t n) {
+	char *nl;
+	printf("%s%s%s%s: ", q, def && *def ? " [" : "",
+	       def ? def : "", def && *def ? "]" : "");
+	fflush(stdout);
+	if (!fgets(out, n, stdin)) {
+		out[0] = '\0';
+		return;
+	}
+	nl = strchr(out, '\n');
+	if (nl) *nl = '\0';
+	if (!out[0] && def) snprintf(out, n, "%s", def);
+}
+
+int rbs_init_cmd(void) {
+	static char body[4096];
+	char url[256], tok[512], client[64], env[32], profile[64];
+	rbs_token t;
+	rbs_config_profile(profile, sizeof(profile));
+	printf("robson init (profile %s)\n", profile);
+	rbs_init_prompt("backend URL (empty for local-only)", "", url, sizeof(url));
+	if (url[0]) {
+		char probe[320];
+		snprintf(probe, sizeof(probe), "%s/healthz", url);
+		if (rbs_http_get(probe, body, sizeof(body)) == 200) {
+			printf("  backend reachable\n");
+		} else {
+			char yn[8];
+			printf("  WARNING: %s did not answer /healthz\n", url);
+			rbs_init_prompt("  keep it anyway? (y/N)", "n", yn, sizeof(yn));
+			if (yn[0] != 'y' && yn[0] != 'Y') url[0] = '\0';
+		}
+	}
+	rbs_init_prompt("access token (empty to skip)", "", tok, sizeof(tok));
+	rbs_init_prompt("client id (empty for none)", "", client, sizeof(client));
+	rbs_init_prompt("environment (testnet/prod)", "testnet", env, sizeof(env));
+	if (strcmp(env, "testnet") != 0 && strcmp(env, "prod") != 0) {
+		printf("unknown environment %s; keeping testnet\n", env);
+		snprintf(env, sizeof(env), "testnet");
+	}
+	if (url[0] && rbs_config_set("api_base_url", url) != 0) {
+		printf("could not write config\n");
+		return 1;
+	}
+	if (client[0]) rbs_config_set("client_id", client);
+	rbs_config_set("env", env);
+	if (tok[0]) {
+		memset(&t, 0, sizeof(t));
+		snprintf(t.access, sizeof(t.access), "%s", tok);
+		if (rbs_token_save(&t) == 0)
+			printf("  token stored\n");
+	}
+	printf("profile %s written; check it with robson config list\n", profile);
+	return 0;
+}
+
+#endif
@@ -0,0 +1,128 @@
+#ifndef RBS_KEYS_H
+#define RBS_KEYS_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <dirent.h>
+#include "store.h"
+#include "flags.h"
+
+/* Exchange key hygiene. Keys live one file per label under
+ * ~/.robson/keys (0600) with their permission metadata; storing a
+ * key with withdrawal rights is refused unless explicitly overridden,
+ * and keys audit flags every dangerous configuration so a trading
+ * key can never silently double as an exfiltration key. */
+
+int rbs_keys_store(int argc, char *argv[]) {
+	char dir[512], path[640];
+	const char *label = NULL, *key = NULL, *perms = "spot";
+	int ip_restricted = 0, allow_withdrawals = 0, i;
+	FILE *f;
+	for (i = 0; i < argc; i++) {
+		if (strcmp(argv[i], "--key") == 0 && i + 1 < argc) key = argv[++i];
+		else if (strcmp(argv[i], "--permissions") == 0 && i + 1 < argc) perms = argv[++i];
+		else if (strcmp(argv[i], "--ip-restricted") == 0) ip_restricted = 1;
+		else if (strcmp(argv[i], "--allow-withdrawals") == 0) allow_withdrawals = 1;
+		else label = argv[i];
+	}
+	if (!label || !key) {
+		printf("usage: robson keys store <label> --key K "
+		       "[--permissions spot,margin] [--ip-restricted] "
+		       "[--allow-withdrawals]\n");
+		return 1;
+	}
+	if (strchr(label, '/') || strchr(label, '.')) {
+		printf("malformed label %s\n", label);
+		return 1;
+	}
+	if (rbs_flag_in_csv(perms, "withdraw") && !allow_withdrawals) {
+		printf("REFUSED: this key has withdrawal rights. A trading robot "
+		       "should never hold one.\n");
+		printf("create a withdrawal-free key, or override with "
+		       "--allow-withdrawals if you accept the risk\n");
+		return 1;
+	}
+	rbs_store_dir(dir, sizeof(dir), "keys");
+	snprintf(path, sizeof(path), "%s/%s.conf", dir, label);
+	f = fopen(path, "w");
+	if (!f) {
+		printf("cannot write %s\n", path);
+		return 1;
+	}
+	fchmod(fileno(f), 0600);
+	fprintf(f, "api_key=%s\n", key);
+	fprintf(f, "permissions=%s\n", perms);
+	fprintf(f, "ip_restricted=%d\n", ip_restricted);
+	fclose(f);
+	printf("key %s stored (%s%s)\n", label, perms,
+	       ip_restricted ? ", IP restricted" : "");
+	return 0;
+}
+
+int rbs_keys_audit(void) {
+	char dir[512], path[700], line[200], k[64], v[128], perms[128];
+	DIR *d;
+	struct dirent *e;
+	FILE *f;
+	char *dot;
+	int ip_restricted, nkeys = 0, findings = 0;
+	rbs_store_dir(dir, sizeof(dir), "keys");
+	d = opendir(dir);
+	if (!d) {
+		printf("no exchange keys stored\n");
+		return 0;
+	}
+	while ((e = readdir(d))) {
+		dot = strstr(e->d_name, ".conf");
+		if (!dot || dot[5] != '\0') continue;
+		snprintf(path, sizeof(path), "%s/%s", dir, e->d_name);
+		f = fopen(path, "r");
+		if (!f) continue;
+		perms[0] = '\0';
+		ip_restricted = 0;
+		while (fgets(line, sizeof(line), f)) {
+			if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0)
+				continue;
+			if (strcmp(k, "permissions") == 0)
+				snprintf(perms, sizeof(perms), "%s", v);
+			else if (strcmp(k, "ip_restricted") == 0)
+				ip_restricted = atoi(v);
+		}
+		fclose(f);
+		nkeys++;
+		printf("%.*s: %s%s\n", (int)(dot - e->d_name), e->d_name,
+		       perms[0] ? perms : "(no permission metadata)",
+		       ip_restricted ? ", IP restricted" : "");
+		if (rbs_flag_in_csv(perms, "withdraw")) {
+			printf("  DANGER: withdrawal rights on a robot key\n");
+			findings++;
+		}
+		if (!ip_restricted) {
+			printf("  warning: no IP restriction; a leaked key works "
+			       "from anywhere\n");
+			findings++;
+		}
+		if (!perms[0]) {
+			printf("  warning: unknown permissions; re-store with "
+			       "--permissions\n");
+			findings++;
+		}
+	}
+	closedir(d);
+	if (!nkeys) printf("no exchange keys stored\n");
+	else printf("audit: %d key%s, %d finding%s\n", nkeys,
+	            nkeys == 1 ? "" : "s", findings, findings == 1 ? "" : "s");
+	return findings ? 1 : 0;
+}
+
+int rbs_keys_cmd(int argc, char *argv[]) {
+	if (argc >= 2 && strcmp(argv[1], "store") == 0)
+		return rbs_keys_store(argc - 2, argv + 2);
+	if (argc >= 2 && strcmp(argv[1], "audit") == 0)
+		return rbs_keys_audit();
+	printf("usage: robson keys <store|audit>\n");
+	return 1;
+}
+
+#endif
@@ -0,0 +1,116 @@
+#ifndef RBS_MARGIN_H
+#define RBS_MARGIN_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+#include "hooks.h"
+#include "http.h"
+#include "plan.h"
+#include "config.h"
+
+/* Margin account state. Locally ~/.robson/margin holds
+ * collateral=/borrowed= (USDT) plus asset=qty position lines; with
+ * ROBSON_API_BASE_URL (or ROBSON_API_URL) set the same data comes
+ * from the gateway's /margin endpoint, so remote operators get the
+ * commands without local stores. */
+
+int rbs_margin_read(double *collateral, double *borrowed,
+                    void (*position)(const char *, double)) {
+	char path[640], line[128], key[64], val[64];
+	FILE *f;
+	rbs_state_path(path, sizeof(path), "margin");
+	f = fopen(path, "r");
+	if (!f) return -1;
+	*collateral = 0;
+	*borrowed = 0;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, "collateral") == 0) *collateral = atof(val);
+		else if (strcmp(key, "borrowed") == 0) *borrowed = atof(val);
+		else if (position) position(key, atof(val));
+	}
+	fclose(f);
+	return 0;
+}
+
+void rbs_margin_print_pos(const char *asset, double qty) {
+	printf("  %-8s %f\n", asset, qty);
+}
+
+int rbs_margin_status_cmd(void) {
+	static char body[8192];
+	double collateral, borrowed, ratio;
+	const char *base = rbs_api_base();
+	int status;
+	rbs_client_header();
+	if (base) {
+		char url[768];
+		snprintf(url, sizeof(url), "%s/margin", base);
+		status = rbs_http_get(url, body, sizeof(body));
+		if (status == 200) {
+			printf("%s\n", body);
+			return 0;
+		}
+		printf("gateway margin fetch failed: %s (HTTP %d); trying local\n",
+		       rbs_api_error_code(status), status);
+	}
+	if (rbs_margin_read(&collateral, &borrowed, NULL) != 0) {
+		printf("no margin account on file (~/.robson/margin)\n");
+		return 1;
+	}
+	ratio = borrowed > 0 ? collateral / borrowed : 0;
+	printf("collateral: %.2f USDT\n", collateral);
+	printf("borrowed:   %.2f USDT\n", borrowed);
+	if (borrowed > 0) {
+		printf("margin level: %.2f%s\n", ratio,
+		       ratio < 1.3 ? "  (DANGER: approaching liquidation)" :
+		       ratio < 2.0 ? "  (warning: thin)" : "");
+		if (ratio < 2.0) {
+			char detail[48];
+			snprintf(detail, sizeof(detail), "margin level %.2f", ratio);
+			rbs_hook_fire("margin-warning", detail);
+		}
+	}
+	else
+		printf("margin level: no borrowings\n");
+	return 0;
+}
+
+int rbs_margin_positions_cmd(void) {
+	double collateral, borrowed;
+	rbs_client_header();
+	printf("margin positions:\n");
+	if (rbs_margin_read(&collateral, &borrowed, rbs_margin_print_pos) != 0) {
+		printf("  none (~/.robson/margin missing)\n");
+		return 1;
+	}
+	return 0;
+}
+
+/* margin-buy registers a margin-strategy plan through the normal
+ * pipeline; nothing borrows until execution goes live. */
+int rbs_margin_buy_cmd(int argc, char *argv[]) {
+	char *vargv[8];
+	if (!rbs_client_id()) {
+		printf("no client bound: set client_id in the profile "
+		       "(robson config set client_id N) or ROBSON_CLIENT_ID\n");
+		return 1;
+	}
+	rbs_client_header();
+	if (argc < 4) {
+		printf("usage: robson margin-buy <symbol> <qty> <price>\n");
+		return 1;
+	}
+	vargv[0] = (char *)"new";
+	vargv[1] = argv[1];
+	vargv[2] = (char *)"buy";
+	vargv[3] = argv[2];
+	vargv[4] = argv[3];
+	vargv[5] = (char *)"--strategy";
+	vargv[6] = (char *)"margin";
+	return rbs_plan_new(6, vargv + 1) == 0 ? 0 : 1;
+}
+
+#endif
@@ -0,0 +1,102 @@
+#ifndef RBS_OPERATION_H
+#define RBS_OPERATION_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <fcntl.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+
+/* An operation is what actually happened: one executed plan leg.
+ * Same key=value layout as plans, one file per operation under
+ * ~/.robson/operations. */
+
+typedef struct {
+	char id[32];		/* same id as the plan that produced it */
+	char symbol[16];
+	char side[8];
+	double qty;
+	double price;
+	time_t executed;
+} rbs_operation;
+
+void rbs_operation_path(char *out, size_t n, const char *id) {
+	char dir[512];
+	rbs_store_dir(dir, sizeof(dir), "operations");
+	snprintf(out, n, "%s/%s.op", dir, id);
+}
+
+/* The operation file doubles as the execution claim: O_EXCL means the
+ * first writer wins and a concurrent execute of the same plan loses. */
+int rbs_operation_save(rbs_operation *o) {
+	char path[640];
+	int fd;
+	FILE *f;
+	rbs_operation_path(path, sizeof(path), o->id);
+	fd = open(path, O_WRONLY | O_CREAT | O_EXCL, 0600);
+	if (fd < 0) return -1;
+	f = fdopen(fd, "w");
+	if (!f) {
+		close(fd);
+		return -1;
+	}
+	fprintf(f, "id=%s\n", o->id);
+	fprintf(f, "symbol=%s\n", o->symbol);
+	fprintf(f, "side=%s\n", o->side);
+	fprintf(f, "qty=%.8f\n", o->qty);
+	fprintf(f, "price=%.8f\n", o->price);
+	fprintf(f, "executed=%ld\n", (long)o->executed);
+	fclose(f);
+	return 0;
+}
+
+int rbs_operation_load(const char *id, rbs_operation *o) {
+	char path[640], line[256], key[64], val[192];
+	FILE *f;
+	rbs_operation_path(path, sizeof(path), id);
+	f = fopen(path, "r");
+	if (!f) return -1;
+	memset(o, 0, sizeof(*o));
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, "id") == 0) snprintf(o->id, sizeof(o->id), "%s", val);
+		else if (strcmp(key, "symbol") == 0) snprintf(o->symbol, sizeof(o->symbol), "%s", val);
+		else if (strcmp(key, "side") == 0) snprintf(o->side, sizeof(o->side), "%s", val);
+		else if (strcmp(key, "qty") == 0) o->qty = atof(val);
+		else if (strcmp(key, "price") == 0) o->price = atof(val);
+		else if (strcmp(key, "executed") == 0) o->executed = (time_t)atol(val);
+	}
+	fclose(f);
+	return 0;
+}
+
+int rbs_operation_cmp(const void *a, const void *b) {
+	const rbs_operation *oa = (const rbs_operation *)a, *ob = (const rbs_operation *)b;
+	if (oa->executed == ob->executed) return strcmp(oa->id, ob->id);
+	return oa->executed < ob->executed ? 1 : -1;
+}
+
+int rbs_operation_load_all(rbs_operation *out, int max) {
+	char dir[512], id[64];
+	DIR *d;
+	struct dirent *e;
+	char *dot;
+	int n = 0;
+	rbs_store_dir(dir, sizeof(dir), "operations");
+	d = opendir(dir);
+	if (!d) return 0;
+	while ((e = readdir(d)) && n < max) {
+		dot = strstr(e->d_name, ".op");
+		if (!dot || dot[3] != '\0') continue;
+		snprintf(id, sizeof(id), "%.*s", (int)(dot - e->d_name), e->d_name);
+		if (rbs_operation_load(id, &out[n]) == 0) n++;
+	}
+	closedir(d);
+	qsort(out, n, sizeof(rbs_operation), rbs_operation_cmp);
+	return n;
+}
+
+#endif
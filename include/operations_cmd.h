@@ -0,0 +1,86 @@
+#ifndef RBS_OPERATIONS_CMD_H
+#define RBS_OPERATIONS_CMD_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+#include "operation.h"
+#include "http.h"
+#include "config.h"
+
+/* List recorded operations. With ROBSON_API_URL set the list comes
+ * from the backend's paginated /operations endpoint, transparently
+ * following next links until --limit is satisfied (or everything with
+ * --all); otherwise the local store answers directly. */
+
+int rbs_operations_cmd(int argc, char *argv[]) {
+	static rbs_operation ops[1024];
+	static char body[65536];
+	char url[768], when[40], *items, *next, *p;
+	const char *base = rbs_api_base();
+	int limit = 50, all = 0, i, n, shown = 0, status;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--all") == 0) all = 1;
+		else if (strcmp(argv[i], "--limit") == 0 && i + 1 < argc) {
+			limit = atoi(argv[++i]);
+			if (limit <= 0) {
+				printf("--limit wants a positive number\n");
+				return 1;
+			}
+		}
+	}
+	if (base && *base) {
+		/* page size stays small; the iterator follows next links */
+		int pages = 0;
+		snprintf(url, sizeof(url), "%s/operations?offset=0&limit=10", base);
+		for (;;) {
+			if (++pages > 1000) {
+				printf("giving up after 1000 pages (broken next links?)\n");
+				return 1;
+			}
+			status = rbs_http_get(url, body, sizeof(body));
+			if (status != 200) {
+				printf("backend list failed: %s (HTTP %d)\n",
+				       rbs_api_error_code(status), status);
+				return 1;
+			}
+			items = strstr(body, "\"items\":[");
+			if (!items) {
+				printf("malformed backend response\n");
+				return 1;
+			}
+			/* one item object per line, raw JSON */
+			for (p = strchr(items, '{'); p && (!all ? shown < limit : 1);
+			     p = strchr(p + 1, '{')) {
+				char *end = strchr(p, '}');
+				if (!end || end > strrchr(body, ']')) break;
+				printf("%.*s}\n", (int)(end - p), p);
+				shown++;
+				p = end;
+			}
+			next = strstr(body, "\"next\":\"");
+			if (!next || (!all && shown >= limit)) break;
+			next += 8;
+			p = strchr(next, '"');
+			if (!p) break;
+			snprintf(url, sizeof(url), "%s%.*s", base,
+			         (int)(p - next), next);
+		}
+		if (!shown) printf("no operations\n");
+		return 0;
+	}
+	n = rbs_operation_load_all(ops, 1024);
+	for (i = 0; i < n && (all || i < limit); i++) {
+		rbs_fmt_time_short(ops[i].executed, when, sizeof(when));
+		printf("%-20s %-5s %-8s %12f %12f  %s\n", ops[i].id,
+		       ops[i].side, ops[i].symbol, ops[i].qty, ops[i].price,
+		       when);
+		shown++;
+	}
+	if (!shown) printf("no operations\n");
+	else if (!all && n > limit)
+		printf("(%d more; use --all or --limit)\n", n - limit);
+	return 0;
+}
+
+#endif
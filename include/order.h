@@ -0,0 +1,126 @@
+#ifndef RBS_ORDER_H
+#define RBS_ORDER_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "plan.h"
+#include "operation.h"
+
+/* Adjusting a live protective stop. When the venue supports order
+ * amendment (ROBSON_EXCHANGE_AMEND=1 until capability discovery
+ * exists) the resting order is modified in place; otherwise we fall
+ * back to cancel+replace and say so, because that path has a brief
+ * window with no protection at all. */
+
+int rbs_order_amend_cmd(int argc, char *argv[]) {
+	rbs_plan p;
+	rbs_operation op;
+	char opid[40], path[640];
+	const char *id = NULL, *amend_env = getenv("ROBSON_EXCHANGE_AMEND");
+	double new_stop = 0;
+	int i, stop_leg = -1, can_amend;
+	for (i = 0; i < argc; i++) {
+		if (strcmp(argv[i], "--stop") == 0 && i + 1 < argc)
+			new_stop = atof(argv[++i]);
+		else id = argv[i];
+	}
+	if (!id || new_stop <= 0) {
+		printf("usage: robson order amend <plan-id> --stop <price>\n");
+		return 1;
+	}
+	if (rbs_plan_load(id, &p) != 0) {
+		printf("no such plan %s\n", id);
+		return 1;
+	}
+	if (strcmp(p.status, "executed") != 0) {
+		printf("plan %s is %s; only executed plans have live stops to amend\n",
+		       p.id, p.status);
+		return 1;
+	}
+	for (i = 0; i < p.nlegs; i++)
+		if (strncmp(p.legs[i].role, "stop", 4) == 0 &&
+		    strcmp(p.legs[i].role, "take-profit") != 0) stop_leg = i;
+	if (stop_leg < 0) {
+		printf("plan %s has no stop leg\n", p.id);
+		return 1;
+	}
+	if (strcmp(p.side, "buy") == 0 && new_stop >= p.legs[0].price) {
+		printf("new stop %f would sit above the entry %f\n",
+		       new_stop, p.legs[0].price);
+		return 1;
+	}
+	snprintf(opid, sizeof(opid), "%s-L%d", p.id, stop_leg);
+	can_amend = amend_env && strcmp(amend_env, "1") == 0;
+	if (can_amend) {
+		/* modify the resting order in place: write the updated record
+		 * beside it and rename over, so there is no instant without a
+		 * stop on disk */
+		char tmp[700];
+		FILE *f;
+		if (rbs_operation_load(opid, &op) != 0) {
+			printf("stop operation %s missing\n", opid);
+			return 1;
+		}
+		op.price = new_stop;
+		op.executed = time(NULL);
+		rbs_operation_path(path, sizeof(path), opid);
+		snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+		f = fopen(tmp, "w");
+		if (!f) {
+			printf("could not rewrite stop operation\n");
+			return 1;
+		}
+		fprintf(f, "id=%s\n", op.id);
+		fprintf(f, "symbol=%s\n", op.symbol);
+		fprintf(f, "side=%s\n", op.side);
+		fprintf(f, "qty=%f\n", op.qty);
+		fprintf(f, "price=%f\n", op.price);
+		fprintf(f, "executed=%ld\n", (long)op.executed);
+		fclose(f);
+		if (rename(tmp, path) != 0) {
+			printf("could not rewrite stop operation\n");
+			return 1;
+		}
+		printf("stop amended in place to %f (no unprotected window)\n",
+		       new_stop);
+	} else {
+		/* cancel + replace: old order gone before the new one rests */
+		rbs_operation_path(path, sizeof(path), opid);
+		if (unlink(path) != 0) {
+			printf("stop operation %s missing\n", opid);
+			return 1;
+		}
+		memset(&op, 0, sizeof(op));
+		snprintf(op.id, sizeof(op.id), "%s", opid);
+		snprintf(op.symbol, sizeof(op.symbol), "%s", p.symbol);
+		snprintf(op.side, sizeof(op.side), "%s", p.legs[stop_leg].side);
+		op.qty = p.legs[stop_leg].qty;
+		op.price = new_stop;
+		op.executed = time(NULL);
+		if (rbs_operation_save(&op) != 0) {
+			printf("REPLACE FAILED after cancel: stop is gone, re-place "
+			       "manually at %f\n", new_stop);
+			return 1;
+		}
+		printf("stop cancel/replaced to %f (venue lacks amend: brief "
+		       "unprotected window)\n", new_stop);
+	}
+	p.legs[stop_leg].price = new_stop;
+	if (rbs_plan_save(&p) != 0) {
+		printf("could not save plan\n");
+		return 1;
+	}
+	return 0;
+}
+
+int rbs_order_cmd(int argc, char *argv[]) {
+	if (argc >= 2 && strcmp(argv[1], "amend") == 0)
+		return rbs_order_amend_cmd(argc - 2, argv + 2);
+	printf("usage: robson order amend <plan-id> --stop <price>\n");
+	return 1;
+}
+
+#endif
@@ -0,0 +1,93 @@
+#ifndef RBS_PAPER_H
+#define RBS_PAPER_H
+#include <stdio.h>
+#include <string.h>
+#include <time.h>
+#include "store.h"
+#include "plan.h"
+#include "operation.h"
+#include "client.h"
+
+/* Paper performance comparison: every executed operation maps back to
+ * its plan's strategy, so different configurations running side by
+ * side on the same box can be ranked before one graduates to live.
+ * P&L marks open inventory at the current quote. */
+
+typedef struct {
+	char strategy[32];
+	int trades;
+	double bought;		/* notional spent */
+	double sold;		/* notional recovered */
+	double qty;		/* open inventory */
+	char symbol[16];	/* last symbol, for marking */
+} rbs_paper_row;
+
+int rbs_paper_compare_cmd(int argc, char *argv[]) {
+	static rbs_operation ops[1024];
+	rbs_paper_row rows[32];
+	rbs_plan p;
+	char base[40];
+	double px, pnl;
+	long since = 0;
+	time_t cutoff = 0;
+	int n, i, j, nrows = 0;
+	char *dash;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--since") == 0 && i + 1 < argc) {
+			since = rbs_parse_duration(argv[++i]);
+			if (since < 0) {
+				printf("--since wants 7d style, got %s\n", argv[i]);
+				return 1;
+			}
+			cutoff = time(NULL) - since;
+		}
+	}
+	n = rbs_operation_load_all(ops, 1024);
+	for (i = n - 1; i >= 0; i--) {
+		if (cutoff && ops[i].executed < cutoff) continue;
+		/* leg operations carry the plan id before "-L" */
+		snprintf(base, sizeof(base), "%s", ops[i].id);
+		dash = strstr(base, "-L");
+		if (dash) *dash = '\0';
+		if (rbs_plan_load(base, &p) != 0)
+			snprintf(p.strategy, sizeof(p.strategy), "unknown");
+		for (j = 0; j < nrows; j++)
+			if (strcmp(rows[j].strategy, p.strategy) == 0) break;
+		if (j == nrows) {
+			if (nrows >= 32) continue;
+			memset(&rows[j], 0, sizeof(rows[j]));
+			snprintf(rows[j].strategy, sizeof(rows[j].strategy),
+			         "%s", p.strategy);
+			nrows++;
+		}
+		rows[j].trades++;
+		snprintf(rows[j].symbol, sizeof(rows[j].symbol), "%s",
+		         ops[i].symbol);
+		if (strcmp(ops[i].side, "buy") == 0) {
+			rows[j].bought += ops[i].qty * ops[i].price;
+			rows[j].qty += ops[i].qty;
+		} else {
+			rows[j].sold += ops[i].qty * ops[i].price;
+			rows[j].qty -= ops[i].qty;
+		}
+	}
+	if (!nrows) {
+		printf("no paper trades%s\n", cutoff ? " in range" : "");
+		return 0;
+	}
+	printf("%-12s %7s %12s %12s %12s %12s\n", "STRATEGY", "TRADES",
+	       "BOUGHT", "SOLD", "OPEN QTY", "P&L");
+	for (j = 0; j < nrows; j++) {
+		pnl = rows[j].sold - rows[j].bought;
+		if (rows[j].qty > 0.0000001 &&
+		    rbs_client_price(rows[j].symbol, &px) == 0)
+			pnl += rows[j].qty * px;
+		printf("%-12s %7d %12.2f %12.2f %12f %+12.2f\n",
+		       rows[j].strategy, rows[j].trades, rows[j].bought,
+		       rows[j].sold, rows[j].qty, pnl);
+	}
+	printf("(open inventory marked at the current quote when available)\n");
+	return 0;
+}
+
+#endif
@@ -0,0 +1,154 @@
+#ifndef RBS_PERF_H
+#define RBS_PERF_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <math.h>
+#include <time.h>
+#include "store.h"
+#include "operation.h"
+#include "plan.h"
+
+/* Performance metrics over closed trades (robson stats perf). Each
+ * sell against the running average cost is one closed trade; its
+ * return is realized P&L over the entry notional it closed. From
+ * that series: win rate, profit factor, annualized return, Sharpe
+ * and Sortino (per-trade returns scaled by observed trade frequency)
+ * and max drawdown on the cumulative realized curve. Unrealized P&L
+ * is deliberately out - open risk belongs to robson positions. */
+
+int rbs_perf_cmd(int argc, char *argv[]) {
+	static rbs_operation ops[1024];
+	static double rets[1024], pnls[1024];
+	struct { char symbol[16]; double qty, cost; } inv[32];
+	rbs_plan plan;
+	const char *f_strategy = NULL;
+	double gross_win = 0, gross_loss = 0, cum = 0, peak = 0, dd = 0;
+	double mean = 0, var = 0, dvar = 0, total_ret = 0;
+	double ann_factor, years;
+	time_t first = 0, last = 0;
+	int json = 0, n, i, j, ntr = 0, wins = 0;
+	memset(inv, 0, sizeof(inv));
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--strategy-id") == 0 && i + 1 < argc)
+			f_strategy = argv[++i];
+		else if (strcmp(argv[i], "--json") == 0) json = 1;
+	}
+	n = rbs_operation_load_all(ops, 1024);
+	/* oldest first so the average cost evolves correctly */
+	for (i = n - 1; i >= 0; i--) {
+		double notional = ops[i].qty * ops[i].price;
+		if (f_strategy) {
+			/* multi-leg operation ids carry a -L suffix; the plan id
+			 * is the part before it */
+			char pid[32], *dash;
+			snprintf(pid, sizeof(pid), "%s", ops[i].id);
+			dash = strstr(pid, "-L");
+			if (dash) *dash = '\0';
+			if (rbs_plan_load(pid, &plan) != 0 ||
+			    strcmp(plan.strategy, f_strategy) != 0)
+				continue;
+		}
+		for (j = 0; j < 32; j++)
+			if (inv[j].symbol[0] == '\0' ||
+			    strcmp(inv[j].symbol, ops[i].symbol) == 0)
+				break;
+		if (j == 32) continue;
+		if (inv[j].symbol[0] == '\0')
+			snprintf(inv[j].symbol, sizeof(inv[j].symbol), "%s",
+			         ops[i].symbol);
+		if (strcmp(ops[i].side, "buy") == 0) {
+			inv[j].cost += notional;
+			inv[j].qty += ops[i].qty;
+			continue;
+		}
+		if (inv[j].qty <= 0 || ntr >= 1024) continue;
+		{
+			double avg = inv[j].cost / inv[j].qty;
+			double entry = avg * ops[i].qty;
+			double pnl = (ops[i].price - avg) * ops[i].qty;
+			inv[j].cost -= entry;
+			inv[j].qty -= ops[i].qty;
+			pnls[ntr] = pnl;
+			rets[ntr] = entry > 0 ? pnl / entry : 0;
+			ntr++;
+			if (pnl > 0) {
+				wins++;
+				gross_win += pnl;
+			} else {
+				gross_loss += -pnl;
+			}
+			if (first == 0) first = ops[i].executed;
+			last = ops[i].executed;
+		}
+	}
+	if (!ntr) {
+		printf("no closed trades%s%s\n", f_strategy ? " for " : "",
+		       f_strategy ? f_strategy : "");
+		return 1;
+	}
+	for (i = 0; i < ntr; i++) {
+		mean += rets[i];
+		total_ret += rets[i];
+		cum += pnls[i];
+		if (cum > peak) peak = cum;
+		if (peak > 0 && (peak - cum) / peak * 100.0 > dd)
+			dd = (peak - cum) / peak * 100.0;
+	}
+	mean /= ntr;
+	for (i = 0; i < ntr; i++) {
+		double d = rets[i] - mean;
+		var += d * d;
+		if (rets[i] < 0)
+			dvar += rets[i] * rets[i];
+	}
+	var = ntr > 1 ? var / (ntr - 1) : 0;
+	dvar = ntr > 0 ? dvar / ntr : 0;
+	/* annualize by the observed trade frequency */
+	years = first < last ? (last - first) / (365.25 * 86400.0) : 0;
+	ann_factor = years > 0 ? ntr / years : 0;
+	if (json) {
+		printf("{\"trades\":%d,\"win_rate\":%.4f,", ntr,
+		       (double)wins / ntr);
+		if (gross_loss > 0)
+			printf("\"profit_factor\":%.4f,", gross_win / gross_loss);
+		printf("\"avg_return\":%.6f,\"annualized_return\":%.4f,"
+		       "\"sharpe\":%.4f,\"sortino\":%.4f,\"max_drawdown_pct\":%.2f,"
+		       "\"realized\":%.4f}\n",
+		       mean,
+		       ann_factor > 0 ? mean * ann_factor : total_ret,
+		       var > 0 && ann_factor > 0
+		           ? mean / sqrt(var) * sqrt(ann_factor) : 0,
+		       dvar > 0 && ann_factor > 0
+		           ? mean / sqrt(dvar) * sqrt(ann_factor) : 0,
+		       dd, cum);
+		return 0;
+	}
+	printf("performance over %d closed trade%s%s%s\n", ntr,
+	       ntr == 1 ? "" : "s", f_strategy ? ", strategy " : "",
+	       f_strategy ? f_strategy : "");
+	printf("  realized P&L       %+.4f\n", cum);
+	printf("  win rate           %.1f%% (%d/%d)\n",
+	       (double)wins / ntr * 100.0, wins, ntr);
+	if (gross_loss > 0)
+		printf("  profit factor      %.2f\n", gross_win / gross_loss);
+	else
+		printf("  profit factor      inf (no losing trades)\n");
+	printf("  avg trade return   %+.3f%%\n", mean * 100.0);
+	if (ann_factor > 0) {
+		printf("  annualized return  %+.1f%% (%.0f trades/yr observed)\n",
+		       mean * ann_factor * 100.0, ann_factor);
+		if (var > 0)
+			printf("  sharpe             %.2f\n",
+			       mean / sqrt(var) * sqrt(ann_factor));
+		if (dvar > 0)
+			printf("  sortino            %.2f\n",
+			       mean / sqrt(dvar) * sqrt(ann_factor));
+	} else {
+		printf("  annualized return  - (all trades in one instant)\n");
+	}
+	printf("  max drawdown       %.2f%% of peak realized\n", dd);
+	return 0;
+}
+
+#endif
@@ -0,0 +1,786 @@
+#ifndef RBS_PLAN_H
+#define RBS_PLAN_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "flags.h"
+#include "stoptype.h"
+#include "fmt.h"
+
+/* A plan is a persisted trading intent: what we want to do, not yet
+ * what we did. Stored one file per plan under ~/.robson/plans as
+ * key=value lines so it stays greppable and editable. */
+
+typedef struct {
+	char id[32];
+	char status[16];	/* pending, validated, executed, abandoned */
+	char strategy[32];
+	char symbol[16];
+	char side[8];		/* buy or sell */
+	double qty;
+	double price;
+	time_t created;
+	long ttl;		/* seconds the plan stays executable */
+	time_t validated_at;
+	time_t executed_at;
+	int version;		/* 1 for a fresh plan, bumped by amend */
+	char parent[32];	/* id this version amends, empty for v1 */
+	int napprovals;
+	struct {
+		char user[32];
+		time_t at;
+		char note[64];
+	} approvals[8];
+	int nlegs;		/* 0 = plain single-order plan */
+	struct {
+		char role[12];	/* entry, stop, take-profit */
+		char side[8];
+		double qty;
+		double price;
+		char status[12];	/* pending, executed, failed */
+	} legs[8];
+} rbs_plan;
+
+#define RBS_PLAN_TTL_DEFAULT 86400
+
+/* Cost model knobs. Environment wins, then the synced schedule in
+ * ~/.robson/fees (robson sync fees), then the defaults. */
+double rbs_fee_sched(const char *key, double fallback) {
+	char path[640], line[128], k[64], v[64];
+	FILE *f;
+	double out = fallback;
+	rbs_state_path(path, sizeof(path), "fees");
+	f = fopen(path, "r");
+	if (!f) return fallback;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0)
+			continue;
+		if (strcmp(k, key) == 0) out = atof(v);
+	}
+	fclose(f);
+	return out;
+}
+
+double rbs_fee_pct() {
+	const char *s = getenv("ROBSON_FEE_PCT");
+	if (s) return atof(s) >= 0 ? atof(s) : 0.1;
+	return rbs_fee_sched("fee_pct", 0.1);
+}
+
+double rbs_spread_pct() {
+	const char *s = getenv("ROBSON_SPREAD_PCT");
+	double v = s ? atof(s) : 0.05;
+	return v >= 0 ? v : 0.05;
+}
+
+/* A plan past created+ttl must not validate or execute; stale limit
+ * prices firing days later is exactly what the TTL prevents. */
+int rbs_plan_expired(rbs_plan *p) {
+	long ttl = p->ttl > 0 ? p->ttl : RBS_PLAN_TTL_DEFAULT;
+	return time(NULL) > p->created + ttl;
+}
+
+/* Plan ids are generated by us and never contain path separators;
+ * refuse anything else so a crafted id cannot leave the store. */
+int rbs_plan_id_ok(const char *id) {
+	return *id && !strchr(id, '/') && !strchr(id, '.');
+}
+
+/* Unique within the process: the sequence keeps two plans created in
+ * the same second (the auto daemon, fire-drill) from sharing an id. */
+void rbs_plan_gen_id(char *out, size_t n) {
+	static int seq;
+	snprintf(out, n, "P%ld%02d%02d", (long)time(NULL),
+	         (int)(getpid() % 100), seq++ % 100);
+}
+
+void rbs_plan_path(char *out, size_t n, const char *id) {
+	char dir[512];
+	rbs_store_dir(dir, sizeof(dir), "plans");
+	snprintf(out, n, "%s/%s.plan", dir, id);
+}
+
+/* Written to a temp file and renamed so a concurrent reader never
+ * sees a half-written record. */
+int rbs_plan_save(rbs_plan *p);
+
+/* Save a brand-new plan; an id collision fails loudly instead of
+ * silently overwriting another plan's record. */
+int rbs_plan_create(rbs_plan *p) {
+	char path[640];
+	rbs_plan_path(path, sizeof(path), p->id);
+	if (access(path, F_OK) == 0) return -1;
+	return rbs_plan_save(p);
+}
+
+int rbs_plan_save(rbs_plan *p) {
+	char path[640], tmp[700];
+	FILE *f;
+	int i;
+	rbs_plan_path(path, sizeof(path), p->id);
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(tmp, "w");
+	if (!f) return -1;
+	fprintf(f, "id=%s\n", p->id);
+	fprintf(f, "status=%s\n", p->status);
+	fprintf(f, "strategy=%s\n", p->strategy);
+	fprintf(f, "symbol=%s\n", p->symbol);
+	fprintf(f, "side=%s\n", p->side);
+	fprintf(f, "qty=%.8f\n", p->qty);
+	fprintf(f, "price=%.8f\n", p->price);
+	fprintf(f, "created=%ld\n", (long)p->created);
+	fprintf(f, "ttl=%ld\n", p->ttl);
+	if (p->validated_at) fprintf(f, "validated_at=%ld\n", (long)p->validated_at);
+	if (p->executed_at) fprintf(f, "executed_at=%ld\n", (long)p->executed_at);
+	fprintf(f, "version=%d\n", p->version > 0 ? p->version : 1);
+	if (p->parent[0]) fprintf(f, "parent=%s\n", p->parent);
+	for (i = 0; i < p->nlegs; i++)
+		fprintf(f, "leg%d=%s,%s,%.8f,%.8f,%s\n", i, p->legs[i].role,
+		        p->legs[i].side, p->legs[i].qty, p->legs[i].price,
+		        p->legs[i].status);
+	for (i = 0; i < p->napprovals; i++)
+		fprintf(f, "approval%d=%s,%ld,%s\n", i, p->approvals[i].user,
+		        (long)p->approvals[i].at, p->approvals[i].note);
+	fclose(f);
+	if (rename(tmp, path) != 0) return -1;
+	return 0;
+}
+
+int rbs_plan_load(const char *id, rbs_plan *p) {
+	char path[640], line[256], key[64], val[192];
+	FILE *f;
+	int i;
+	if (!rbs_plan_id_ok(id)) return -1;
+	rbs_plan_path(path, sizeof(path), id);
+	f = fopen(path, "r");
+	if (!f) return -1;
+	memset(p, 0, sizeof(*p));
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, "id") == 0) snprintf(p->id, sizeof(p->id), "%s", val);
+		else if (strcmp(key, "status") == 0) snprintf(p->status, sizeof(p->status), "%s", val);
+		else if (strcmp(key, "strategy") == 0) snprintf(p->strategy, sizeof(p->strategy), "%s", val);
+		else if (strcmp(key, "symbol") == 0) snprintf(p->symbol, sizeof(p->symbol), "%s", val);
+		else if (strcmp(key, "side") == 0) snprintf(p->side, sizeof(p->side), "%s", val);
+		else if (strcmp(key, "qty") == 0) p->qty = atof(val);
+		else if (strcmp(key, "price") == 0) p->price = atof(val);
+		else if (strcmp(key, "created") == 0) p->created = (time_t)atol(val);
+		else if (strcmp(key, "ttl") == 0) p->ttl = atol(val);
+		else if (strcmp(key, "validated_at") == 0) p->validated_at = (time_t)atol(val);
+		else if (strcmp(key, "executed_at") == 0) p->executed_at = (time_t)atol(val);
+		else if (strcmp(key, "version") == 0) p->version = atoi(val);
+		else if (strcmp(key, "parent") == 0) snprintf(p->parent, sizeof(p->parent), "%s", val);
+		else if (strncmp(key, "approval", 8) == 0 && p->napprovals < 8) {
+			i = p->napprovals;
+			p->approvals[i].note[0] = '\0';
+			if (sscanf(val, "%31[^,],%ld,%63[^\n]",
+			           p->approvals[i].user, (long *)&p->approvals[i].at,
+			           p->approvals[i].note) >= 2)
+				p->napprovals++;
+		}
+		else if (strncmp(key, "leg", 3) == 0 && p->nlegs < 8) {
+			i = p->nlegs;
+			if (sscanf(val, "%11[^,],%7[^,],%lf,%lf,%11[^,\n]",
+			           p->legs[i].role, p->legs[i].side,
+			           &p->legs[i].qty, &p->legs[i].price,
+			           p->legs[i].status) == 5)
+				p->nlegs++;
+		}
+	}
+	fclose(f);
+	return 0;
+}
+
+void rbs_plan_print(rbs_plan *p) {
+	char when[40], qs[32], ps[32];
+	int i;
+	rbs_fmt_time(p->created, when, sizeof(when));
+	printf("id:       %s\n", p->id);
+	printf("status:   %s\n", p->status);
+	printf("strategy: %s\n", p->strategy);
+	printf("symbol:   %s\n", p->symbol);
+	printf("side:     %s\n", p->side);
+	rbs_fmt_qty(p->symbol, p->qty, qs, sizeof(qs));
+	rbs_fmt_px(p->symbol, p->price, ps, sizeof(ps));
+	printf("qty:      %s\n", qs);
+	printf("price:    %s\n", ps);
+	printf("created:  %s\n", when);
+	printf("ttl:      %lds%s\n", p->ttl, rbs_plan_expired(p) ? " (expired)" : "");
+	printf("version:  %d%s%s\n", p->version > 0 ? p->version : 1,
+	       p->parent[0] ? " amends " : "", p->parent);
+	if (p->validated_at) {
+		rbs_fmt_time(p->validated_at, when, sizeof(when));
+		printf("validated: %s (%ldm in stage%s)\n", when,
+		       ((p->executed_at ? p->executed_at : time(NULL))
+		        - p->validated_at) / 60,
+		       !p->executed_at &&
+		       time(NULL) - p->validated_at > 900 ? ", getting stale" : "");
+	}
+	if (p->executed_at) {
+		rbs_fmt_time(p->executed_at, when, sizeof(when));
+		printf("executed:  %s\n", when);
+	}
+	{
+		double notional = p->qty * p->price;
+		double fee = notional * rbs_fee_pct() / 100.0;
+		double slip = notional * rbs_spread_pct() / 100.0 / 2.0;
+		printf("costs:    notional %.2f, est fee %.4f (%.2f%%), "
+		       "est slippage %.4f (half of %.2f%% spread)\n",
+		       notional, fee, rbs_fee_pct(), slip, rbs_spread_pct());
+	}
+	for (i = 0; i < p->nlegs; i++)
+		printf("leg %d:    %-12s %-4s %f @ %f  [%s]\n", i,
+		       p->legs[i].role, p->legs[i].side, p->legs[i].qty,
+		       p->legs[i].price, p->legs[i].status);
+	for (i = 0; i < p->napprovals; i++) {
+		rbs_fmt_time(p->approvals[i].at, when, sizeof(when));
+		printf("approved: %s at %s%s%s\n", p->approvals[i].user, when,
+		       p->approvals[i].note[0] ? " - " : "",
+		       p->approvals[i].note);
+	}
+}
+
+void rbs_plan_print_json(rbs_plan *p) {
+	int i;
+	printf("{\"id\":\"%s\",\"status\":\"%s\",\"strategy\":\"%s\","
+	       "\"symbol\":\"%s\",\"side\":\"%s\",\"qty\":%f,\"price\":%f,"
+	       "\"created\":%ld,\"ttl\":%ld,\"expired\":%s",
+	       p->id, p->status, p->strategy, p->symbol, p->side,
+	       p->qty, p->price, (long)p->created, p->ttl,
+	       rbs_plan_expired(p) ? "true" : "false");
+	if (p->nlegs > 0) {
+		printf(",\"legs\":[");
+		for (i = 0; i < p->nlegs; i++)
+			printf("%s{\"role\":\"%s\",\"side\":\"%s\",\"qty\":%f,"
+			       "\"price\":%f,\"status\":\"%s\"}",
+			       i ? "," : "", p->legs[i].role, p->legs[i].side,
+			       p->legs[i].qty, p->legs[i].price, p->legs[i].status);
+		printf("]");
+	}
+	printf("}");
+}
+
+/* Defined in simulate.h, which layers on top of this module. */
+void rbs_simulate_print(rbs_plan *p, double leverage);
+
+int rbs_plan_new(int argc, char *argv[]) {
+	rbs_plan p;
+	double leverage = 1;
+	int i, simulate = 0;
+	if (argc < 4) {
+		printf("usage: robson plan new <symbol> <buy|sell> <qty> <price> [--strategy NAME] [--ttl 24h] [--simulate] [--leverage N]\n");
+		return 1;
+	}
+	memset(&p, 0, sizeof(p));
+	rbs_plan_gen_id(p.id, sizeof(p.id));
+	snprintf(p.status, sizeof(p.status), "pending");
+	snprintf(p.strategy, sizeof(p.strategy), "manual");
+	snprintf(p.symbol, sizeof(p.symbol), "%s", argv[0]);
+	snprintf(p.side, sizeof(p.side), "%s", argv[1]);
+	p.qty = atof(argv[2]);
+	p.price = atof(argv[3]);
+	p.created = time(NULL);
+	p.ttl = RBS_PLAN_TTL_DEFAULT;
+	for (i = 4; i < argc; i++) {
+		if (strcmp(argv[i], "--strategy") == 0) {
+			if (i + 1 >= argc) {
+				printf("--strategy wants a value\n");
+				return 1;
+			}
+			snprintf(p.strategy, sizeof(p.strategy), "%s", argv[++i]);
+		}
+		else if (strcmp(argv[i], "--ttl") == 0) {
+			if (i + 1 >= argc) {
+				printf("--ttl wants a value\n");
+				return 1;
+			}
+			p.ttl = rbs_parse_duration(argv[++i]);
+			if (p.ttl < 0) {
+				printf("--ttl wants 30m/24h/7d style, got %s\n", argv[i]);
+				return 1;
+			}
+		}
+		else if (strcmp(argv[i], "--simulate") == 0) simulate = 1;
+		else if (strcmp(argv[i], "--leverage") == 0 && i + 1 < argc) {
+			leverage = atof(argv[++i]);
+			if (leverage < 1) {
+				printf("--leverage wants a number >= 1, got %s\n", argv[i]);
+				return 1;
+			}
+		}
+	}
+	if (strcmp(p.side, "buy") != 0 && strcmp(p.side, "sell") != 0) {
+		printf("side must be buy or sell, got %s\n", p.side);
+		return 1;
+	}
+	if (p.qty <= 0 || p.price <= 0) {
+		printf("qty and price must be positive\n");
+		return 1;
+	}
+	if (simulate) {
+		rbs_simulate_print(&p, leverage);
+		printf("(simulation only; nothing registered)\n");
+		return 0;
+	}
+	if (rbs_plan_create(&p) != 0) {
+		printf("could not save plan\n");
+		return 1;
+	}
+	printf("plan %s registered (notional %.2f, est fee %.4f, est slippage %.4f)\n",
+	       p.id, p.qty * p.price,
+	       p.qty * p.price * rbs_fee_pct() / 100.0,
+	       p.qty * p.price * rbs_spread_pct() / 100.0 / 2.0);
+	return 0;
+}
+
+/* One unit of entry + protective stop + take-profit: legs execute in
+ * order and each tracks its own status so a partial failure is visible
+ * and recoverable. */
+int rbs_plan_multi(int argc, char *argv[]) {
+	rbs_plan p;
+	double entry_qty = 0, entry_price = 0, stop_price = 0, tp_price = 0;
+	int i;
+	if (argc < 1) {
+		printf("usage: robson plan multi <symbol> --entry <qty>@<price> --stop <price> --tp <price> [--strategy NAME] [--ttl 24h]\n");
+		return 1;
+	}
+	memset(&p, 0, sizeof(p));
+	rbs_plan_gen_id(p.id, sizeof(p.id));
+	snprintf(p.status, sizeof(p.status), "pending");
+	snprintf(p.strategy, sizeof(p.strategy), "bracket");
+	snprintf(p.symbol, sizeof(p.symbol), "%s", argv[0]);
+	snprintf(p.side, sizeof(p.side), "buy");
+	p.created = time(NULL);
+	p.ttl = RBS_PLAN_TTL_DEFAULT;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--entry") == 0 && i + 1 < argc) {
+			if (sscanf(argv[++i], "%lf@%lf", &entry_qty, &entry_price) != 2) {
+				printf("--entry wants <qty>@<price>, got %s\n", argv[i]);
+				return 1;
+			}
+		}
+		else if (strcmp(argv[i], "--stop") == 0 && i + 1 < argc) stop_price = atof(argv[++i]);
+		else if (strcmp(argv[i], "--tp") == 0 && i + 1 < argc) tp_price = atof(argv[++i]);
+		else if (strcmp(argv[i], "--strategy") == 0 && i + 1 < argc)
+			snprintf(p.strategy, sizeof(p.strategy), "%s", argv[++i]);
+		else if (strcmp(argv[i], "--ttl") == 0 && i + 1 < argc) {
+			p.ttl = rbs_parse_duration(argv[++i]);
+			if (p.ttl < 0) {
+				printf("--ttl wants 30m/24h/7d style, got %s\n", argv[i]);
+				return 1;
+			}
+		}
+	}
+	if (entry_qty <= 0 || entry_price <= 0 || stop_price <= 0 || tp_price <= 0) {
+		printf("--entry, --stop and --tp are all required and positive\n");
+		return 1;
+	}
+	if (!(stop_price < entry_price && entry_price < tp_price)) {
+		printf("want stop < entry < take-profit for a long bracket "
+		       "(%f / %f / %f)\n", stop_price, entry_price, tp_price);
+		return 1;
+	}
+	p.qty = entry_qty;
+	p.price = entry_price;
+	p.nlegs = 3;
+	snprintf(p.legs[0].role, sizeof(p.legs[0].role), "entry");
+	snprintf(p.legs[0].side, sizeof(p.legs[0].side), "buy");
+	p.legs[0].qty = entry_qty; p.legs[0].price = entry_price;
+	{
+		char stype[16];
+		double offset;
+		int overridden = rbs_stop_type_for(p.symbol, stype,
+		                                   sizeof(stype), &offset);
+		snprintf(p.legs[1].role, sizeof(p.legs[1].role), "%s", stype);
+		if (overridden)
+			printf("note: %s is marked thin; stop-limit preference "
+			       "overridden to stop-market\n", p.symbol);
+		if (strcmp(stype, "stop-limit") == 0)
+			printf("stop-limit: trigger %f, limit %f (offset %.2f%%)\n",
+			       stop_price, stop_price * (1.0 - offset / 100.0),
+			       offset);
+	}
+	snprintf(p.legs[1].side, sizeof(p.legs[1].side), "sell");
+	p.legs[1].qty = entry_qty; p.legs[1].price = stop_price;
+	snprintf(p.legs[2].role, sizeof(p.legs[2].role), "take-profit");
+	snprintf(p.legs[2].side, sizeof(p.legs[2].side), "sell");
+	p.legs[2].qty = entry_qty; p.legs[2].price = tp_price;
+	for (i = 0; i < p.nlegs; i++)
+		snprintf(p.legs[i].status, sizeof(p.legs[i].status), "pending");
+	if (rbs_plan_create(&p) != 0) {
+		printf("could not save plan\n");
+		return 1;
+	}
+	printf("plan %s registered (%d legs)\n", p.id, p.nlegs);
+	return 0;
+}
+
+/* qsort helper: newest plans first. */
+int rbs_plan_cmp(const void *a, const void *b) {
+	const rbs_plan *pa = (const rbs_plan *)a, *pb = (const rbs_plan *)b;
+	if (pa->created == pb->created) return strcmp(pa->id, pb->id);
+	return pa->created < pb->created ? 1 : -1;
+}
+
+/* Load every plan on disk into a caller-provided array, newest first.
+ * Returns how many were loaded. */
+int rbs_plan_load_all(rbs_plan *out, int max) {
+	char dir[512], id[64];
+	DIR *d;
+	struct dirent *e;
+	int n = 0;
+	char *dot;
+	rbs_store_dir(dir, sizeof(dir), "plans");
+	d = opendir(dir);
+	if (!d) return 0;
+	while ((e = readdir(d)) && n < max) {
+		dot = strstr(e->d_name, ".plan");
+		if (!dot || dot[5] != '\0') continue;
+		snprintf(id, sizeof(id), "%.*s", (int)(dot - e->d_name), e->d_name);
+		if (rbs_plan_load(id, &out[n]) == 0) n++;
+	}
+	closedir(d);
+	qsort(out, n, sizeof(rbs_plan), rbs_plan_cmp);
+	return n;
+}
+
+int rbs_plan_list(int argc, char *argv[]) {
+	static rbs_plan plans[1024];
+	char when[40];
+	const char *f_status = NULL, *f_strategy = NULL;
+	char qs[32], ps[32];
+	time_t f_since = 0;
+	int json = 0, n, i, shown = 0, y, mo, dy;
+	struct tm tm;
+	for (i = 0; i < argc; i++) {
+		if (strcmp(argv[i], "--json") == 0) json = 1;
+		else if (strcmp(argv[i], "--status") == 0 && i + 1 < argc) f_status = argv[++i];
+		else if (strcmp(argv[i], "--strategy") == 0 && i + 1 < argc) f_strategy = argv[++i];
+		else if (strcmp(argv[i], "--since") == 0 && i + 1 < argc) {
+			if (sscanf(argv[++i], "%d-%d-%d", &y, &mo, &dy) != 3) {
+				printf("--since wants YYYY-MM-DD, got %s\n", argv[i]);
+				return 1;
+			}
+			memset(&tm, 0, sizeof(tm));
+			tm.tm_year = y - 1900; tm.tm_mon = mo - 1; tm.tm_mday = dy;
+			f_since = mktime(&tm);
+		}
+	}
+	n = rbs_plan_load_all(plans, 1024);
+	if (json) printf("[");
+	for (i = 0; i < n; i++) {
+		if (f_status && strcmp(plans[i].status, f_status) != 0) continue;
+		if (f_strategy && strcmp(plans[i].strategy, f_strategy) != 0) continue;
+		if (f_since && plans[i].created < f_since) continue;
+		if (json) {
+			if (shown) printf(",");
+			rbs_plan_print_json(&plans[i]);
+		} else {
+			if (!shown)
+				printf("%-18s %-10s %-10s %-8s %-5s %12s %12s  %s\n",
+				       "ID", "STATUS", "STRATEGY", "SYMBOL", "SIDE",
+				       "QTY", "PRICE", "CREATED");
+			rbs_fmt_time_short(plans[i].created, when, sizeof(when));
+			rbs_fmt_qty(plans[i].symbol, plans[i].qty, qs, sizeof(qs));
+			rbs_fmt_px(plans[i].symbol, plans[i].price, ps, sizeof(ps));
+			printf("%-18s %-10s %-10s %-8s %-5s %12s %12s  %s\n",
+			       plans[i].id, plans[i].status, plans[i].strategy,
+			       plans[i].symbol, plans[i].side, qs, ps, when);
+		}
+		shown++;
+	}
+	if (json) printf("]\n");
+	else if (!shown) printf("no plans\n");
+	return 0;
+}
+
+int rbs_plan_show(int argc, char *argv[]) {
+	rbs_plan p, q;
+	const char *id = NULL;
+	int json = 0, i;
+	for (i = 0; i < argc; i++) {
+		if (strcmp(argv[i], "--json") == 0) json = 1;
+		else id = argv[i];
+	}
+	if (!id) {
+		printf("usage: robson plan show <id> [--json]\n");
+		return 1;
+	}
+	if (rbs_plan_load(id, &p) != 0) {
+		printf("no such plan %s\n", id);
+		return 1;
+	}
+	if (json) { rbs_plan_print_json(&p); printf("\n"); }
+	else {
+		rbs_plan_print(&p);
+		/* Walk the amendment chain back to v1 so the audit trail of
+		 * intent changes reads in one place. The hop cap keeps a
+		 * hand-corrupted parent cycle from hanging us. */
+		for (i = 0; i < 64 && p.parent[0] &&
+		            rbs_plan_load(p.parent, &q) == 0; i++) {
+			printf("  v%d %s: %s %s %f @ %f [%s]\n",
+			       q.version > 0 ? q.version : 1, q.id, q.side,
+			       q.symbol, q.qty, q.price, q.status);
+			p = q;
+		}
+	}
+	return 0;
+}
+
+/* Plans authored as flat YAML (key: value per line, # comments) can be
+ * checked into git and reviewed before they ever reach validation. We
+ * parse just that subset; nothing nested. */
+/* Parse a flat YAML plan (key: value per line, # comments) from an
+ * already-open stream into *p, applying defaults. label names the
+ * source in error messages. Returns 0 when the plan is well formed. */
+int rbs_plan_parse_yaml(FILE *f, rbs_plan *p, const char *label) {
+	char line[256], key[64], val[192];
+	char *colon, *v, *end;
+	memset(p, 0, sizeof(*p));
+	snprintf(p->status, sizeof(p->status), "pending");
+	snprintf(p->strategy, sizeof(p->strategy), "manual");
+	p->ttl = RBS_PLAN_TTL_DEFAULT;
+	while (fgets(line, sizeof(line), f)) {
+		if (line[0] == '#' || line[0] == '\n') continue;
+		colon = strchr(line, ':');
+		if (!colon) continue;
+		*colon = '\0';
+		v = colon + 1;
+		while (*v == ' ' || *v == '\t') v++;
+		end = v + strlen(v);
+		while (end > v && (end[-1] == '\n' || end[-1] == ' ')) *--end = '\0';
+		snprintf(key, sizeof(key), "%s", line);
+		snprintf(val, sizeof(val), "%s", v);
+		if (strcmp(key, "symbol") == 0) snprintf(p->symbol, sizeof(p->symbol), "%s", val);
+		else if (strcmp(key, "side") == 0) snprintf(p->side, sizeof(p->side), "%s", val);
+		else if (strcmp(key, "strategy") == 0) snprintf(p->strategy, sizeof(p->strategy), "%s", val);
+		else if (strcmp(key, "qty") == 0) p->qty = atof(val);
+		else if (strcmp(key, "price") == 0) p->price = atof(val);
+		else if (strcmp(key, "ttl") == 0) {
+			p->ttl = rbs_parse_duration(val);
+			if (p->ttl < 0) {
+				printf("%s: ttl wants 30m/24h/7d style, got %s\n", label, val);
+				return -1;
+			}
+		}
+	}
+	if (strcmp(p->side, "buy") != 0 && strcmp(p->side, "sell") != 0) {
+		printf("%s: side must be buy or sell\n", label);
+		return -1;
+	}
+	if (!p->symbol[0] || p->qty <= 0 || p->price <= 0) {
+		printf("%s: symbol, qty and price are required and positive\n", label);
+		return -1;
+	}
+	return 0;
+}
+
+/* Plans authored as flat YAML can be checked into git and reviewed
+ * before they ever reach validation. */
+int rbs_plan_import(const char *file) {
+	FILE *f;
+	rbs_plan p;
+	f = fopen(file, "r");
+	if (!f) {
+		printf("cannot open %s\n", file);
+		return 1;
+	}
+	if (rbs_plan_parse_yaml(f, &p, file) != 0) {
+		fclose(f);
+		return 1;
+	}
+	fclose(f);
+	rbs_plan_gen_id(p.id, sizeof(p.id));
+	p.created = time(NULL);
+	if (rbs_plan_create(&p) != 0) {
+		printf("could not save plan\n");
+		return 1;
+	}
+	printf("plan %s registered from %s\n", p.id, file);
+	return 0;
+}
+
+int rbs_plan_export(int argc, char *argv[]) {
+	rbs_plan p;
+	const char *id = NULL, *format = "yaml";
+	int i;
+	for (i = 0; i < argc; i++) {
+		if (strcmp(argv[i], "--format") == 0 && i + 1 < argc) format = argv[++i];
+		else id = argv[i];
+	}
+	if (!id) {
+		printf("usage: robson plan export <id> [--format yaml]\n");
+		return 1;
+	}
+	if (strcmp(format, "yaml") != 0) {
+		printf("unknown format %s (only yaml)\n", format);
+		return 1;
+	}
+	if (rbs_plan_load(id, &p) != 0) {
+		printf("no such plan %s\n", id);
+		return 1;
+	}
+	printf("# robson plan %s\n", p.id);
+	printf("symbol: %s\n", p.symbol);
+	printf("side: %s\n", p.side);
+	printf("qty: %f\n", p.qty);
+	printf("price: %f\n", p.price);
+	printf("strategy: %s\n", p.strategy);
+	printf("ttl: %lds\n", p.ttl);
+	return 0;
+}
+
+/* Prune old finished plans. Dry run by default; --force deletes.
+ * "expired" in --status matches any plan past its ttl regardless of
+ * recorded status. */
+int rbs_plan_gc(int argc, char *argv[]) {
+	static rbs_plan plans[1024];
+	char path[640];
+	long older = 30 * 86400;
+	const char *statuses = "executed,expired";
+	int force = 0, i, n, hits = 0;
+	time_t now = time(NULL);
+	for (i = 0; i < argc; i++) {
+		if (strcmp(argv[i], "--older-than") == 0 && i + 1 < argc) {
+			older = rbs_parse_duration(argv[++i]);
+			if (older < 0) {
+				printf("--older-than wants 30m/24h/7d style, got %s\n", argv[i]);
+				return 1;
+			}
+		}
+		else if (strcmp(argv[i], "--status") == 0 && i + 1 < argc) statuses = argv[++i];
+		else if (strcmp(argv[i], "--force") == 0) force = 1;
+	}
+	n = rbs_plan_load_all(plans, 1024);
+	for (i = 0; i < n; i++) {
+		if (now - plans[i].created < older) continue;
+		if (!rbs_flag_in_csv(statuses, plans[i].status) &&
+		    !(rbs_flag_in_csv(statuses, "expired") && rbs_plan_expired(&plans[i])))
+			continue;
+		hits++;
+		if (force) {
+			rbs_plan_path(path, sizeof(path), plans[i].id);
+			unlink(path);
+			printf("deleted %s (%s, %s)\n", plans[i].id,
+			       plans[i].status, plans[i].symbol);
+		} else {
+			printf("would delete %s (%s, %s)\n", plans[i].id,
+			       plans[i].status, plans[i].symbol);
+		}
+	}
+	if (!hits) printf("nothing to prune\n");
+	else if (!force) printf("%d plan%s; re-run with --force to delete\n",
+	                        hits, hits == 1 ? "" : "s");
+	return 0;
+}
+
+int rbs_plan_delete(int argc, char *argv[]) {
+	char path[640];
+	if (argc < 1) {
+		printf("usage: robson plan delete <id>\n");
+		return 1;
+	}
+	if (!rbs_plan_id_ok(argv[0])) {
+		printf("no such plan %s\n", argv[0]);
+		return 1;
+	}
+	rbs_plan_path(path, sizeof(path), argv[0]);
+	if (unlink(path) != 0) {
+		printf("no such plan %s\n", argv[0]);
+		return 1;
+	}
+	printf("plan %s deleted\n", argv[0]);
+	return 0;
+}
+
+/* Amending creates a new version linked to its parent and retires the
+ * parent, so earlier validations and approvals never carry over. */
+int rbs_plan_amend(int argc, char *argv[]) {
+	rbs_plan old, p;
+	const char *id = NULL;
+	double qty = 0, price = 0;
+	int i;
+	for (i = 0; i < argc; i++) {
+		if (strcmp(argv[i], "--quantity") == 0 && i + 1 < argc) qty = atof(argv[++i]);
+		else if (strcmp(argv[i], "--price") == 0 && i + 1 < argc) price = atof(argv[++i]);
+		else id = argv[i];
+	}
+	if (!id || (qty <= 0 && price <= 0)) {
+		printf("usage: robson plan amend <id> [--quantity N] [--price N]\n");
+		return 1;
+	}
+	if (rbs_plan_load(id, &old) != 0) {
+		printf("no such plan %s\n", id);
+		return 1;
+	}
+	if (strcmp(old.status, "executed") == 0 || strcmp(old.status, "amended") == 0) {
+		printf("plan %s is %s and cannot be amended\n", old.id, old.status);
+		return 1;
+	}
+	if (old.nlegs > 0) {
+		printf("plan %s has legs; re-plan the bracket instead of amending\n", old.id);
+		return 1;
+	}
+	p = old;
+	rbs_plan_gen_id(p.id, sizeof(p.id));
+	snprintf(p.parent, sizeof(p.parent), "%s", old.id);
+	p.version = (old.version > 0 ? old.version : 1) + 1;
+	snprintf(p.status, sizeof(p.status), "pending");
+	p.napprovals = 0;
+	p.validated_at = 0;
+	p.executed_at = 0;
+	p.created = time(NULL);
+	if (qty > 0) p.qty = qty;
+	if (price > 0) p.price = price;
+	if (rbs_plan_create(&p) != 0) {
+		printf("could not save plan\n");
+		return 1;
+	}
+	snprintf(old.status, sizeof(old.status), "amended");
+	rbs_plan_save(&old);
+	printf("plan %s v%d registered, amends %s (re-validate before executing)\n",
+	       p.id, p.version, old.id);
+	return 0;
+}
+
+/* Defined in template.h, rebalance.h and audit.h, which layer on top
+ * of this module. */
+int rbs_template_cmd(int argc, char *argv[]);
+int rbs_rebalance_cmd(int argc, char *argv[]);
+int rbs_plan_sync_cmd(void);
+int rbs_plan_stats_cmd(int argc, char *argv[]);
+
+int rbs_plan_cmd(int argc, char *argv[]) {
+	if (argc < 2) {
+		printf("usage: robson plan <new|multi|amend|list|show|delete|export|template|sync|gc|--file> ...\n");
+		return 1;
+	}
+	if (strcmp(argv[1], "template") == 0) return rbs_template_cmd(argc - 2, argv + 2);
+	if (strcmp(argv[1], "rebalance") == 0) return rbs_rebalance_cmd(argc - 2, argv + 2);
+	if (strcmp(argv[1], "sync") == 0) return rbs_plan_sync_cmd();
+	if (strcmp(argv[1], "gc") == 0) return rbs_plan_gc(argc - 2, argv + 2);
+	if (strcmp(argv[1], "stats") == 0) return rbs_plan_stats_cmd(argc - 2, argv + 2);
+	if (strcmp(argv[1], "--file") == 0) {
+		if (argc < 3) {
+			printf("usage: robson plan --file <plan.yaml>\n");
+			return 1;
+		}
+		return rbs_plan_import(argv[2]);
+	}
+	if (strcmp(argv[1], "new") == 0) return rbs_plan_new(argc - 2, argv + 2);
+	if (strcmp(argv[1], "multi") == 0) return rbs_plan_multi(argc - 2, argv + 2);
+	if (strcmp(argv[1], "amend") == 0) return rbs_plan_amend(argc - 2, argv + 2);
+	if (strcmp(argv[1], "list") == 0) return rbs_plan_list(argc - 2, argv + 2);
+	if (strcmp(argv[1], "show") == 0) return rbs_plan_show(argc - 2, argv + 2);
+	if (strcmp(argv[1], "delete") == 0) return rbs_plan_delete(argc - 2, argv + 2);
+	if (strcmp(argv[1], "export") == 0) return rbs_plan_export(argc - 2, argv + 2);
+	printf("unknown plan subcommand %s\n", argv[1]);
+	return 1;
+}
+
+#endif
@@ -0,0 +1,190 @@
+#ifndef RBS_PNL_H
+#define RBS_PNL_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include "store.h"
+#include "operation.h"
+#include "plan.h"
+#include "price.h"
+#include "fees.h"
+
+/* P&L report over executed operations. Realized P&L uses the same
+ * running-average-cost model as the trades view; unrealized marks the
+ * open remainder against the current feed price; fees follow the
+ * active schedule and open margin exposure accrues the estimated
+ * hourly borrow interest. Broken down by symbol, by strategy and by
+ * day, with --json for dashboards. robson report is the same view
+ * (the old stub finally points somewhere). */
+
+#define RBS_PNL_MAX 32
+
+typedef struct {
+	char key[24];
+	double realized, fees, unreal, interest;
+	double qty, cost;	/* running inventory, symbol rows only */
+	time_t opened;		/* oldest open fill, for interest accrual */
+} rbs_pnl_row;
+
+int rbs_pnl_row_find(rbs_pnl_row *rows, int *n, const char *key) {
+	int i;
+	for (i = 0; i < *n; i++)
+		if (strcmp(rows[i].key, key) == 0) return i;
+	if (*n >= RBS_PNL_MAX) return -1;
+	memset(&rows[*n], 0, sizeof(rows[*n]));
+	snprintf(rows[*n].key, sizeof(rows[*n].key), "%s", key);
+	return (*n)++;
+}
+
+long rbs_pnl_period(const char *period) {
+	if (strcmp(period, "day") == 0) return 86400;
+	if (strcmp(period, "week") == 0) return 7 * 86400;
+	if (strcmp(period, "month") == 0) return 30 * 86400;
+	if (strcmp(period, "all") == 0) return 0;
+	return -1;
+}
+
+int rbs_pnl_cmd(int argc, char *argv[]) {
+	static rbs_operation ops[1024];
+	static rbs_pnl_row sym[RBS_PNL_MAX], strat[RBS_PNL_MAX];
+	struct { char day[12]; double realized, fees; } days[62];
+	rbs_plan plan;
+	char daybuf[12];
+	const char *period = "month", *f_symbol = NULL;
+	struct tm tmv;
+	time_t cutoff = 0;
+	long span;
+	double t_real = 0, t_fees = 0, t_unreal = 0, t_int = 0;
+	int json = 0, n, i, j, s, nsym = 0, nstrat = 0, ndays = 0;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--period") == 0 && i + 1 < argc) period = argv[++i];
+		else if (strcmp(argv[i], "--symbol") == 0 && i + 1 < argc) f_symbol = argv[++i];
+		else if (strcmp(argv[i], "--json") == 0) json = 1;
+	}
+	span = rbs_pnl_period(period);
+	if (span < 0) {
+		printf("--period wants day, week, month or all\n");
+		return 1;
+	}
+	if (span) cutoff = time(NULL) - span;
+	n = rbs_operation_load_all(ops, 1024);
+	/* oldest first so average cost evolves correctly */
+	for (i = n - 1; i >= 0; i--) {
+		double notional = ops[i].qty * ops[i].price;
+		double fee = notional * rbs_fee_pct() / 100.0;
+		double realized = 0;
+		int in_period = ops[i].executed >= cutoff;
+		if (f_symbol && strcmp(ops[i].symbol, f_symbol) != 0) continue;
+		j = rbs_pnl_row_find(sym, &nsym, ops[i].symbol);
+		if (j < 0) {
+			printf("more than %d symbols; totals exclude %s\n",
+			       RBS_PNL_MAX, ops[i].symbol);
+			continue;
+		}
+		if (strcmp(ops[i].side, "buy") == 0) {
+			sym[j].cost += notional;
+			sym[j].qty += ops[i].qty;
+			if (sym[j].opened == 0) sym[j].opened = ops[i].executed;
+		} else if (sym[j].qty > 0) {
+			double avg = sym[j].cost / sym[j].qty;
+			realized = (ops[i].price - avg) * ops[i].qty - fee;
+			sym[j].cost -= avg * ops[i].qty;
+			sym[j].qty -= ops[i].qty;
+			if (sym[j].qty <= 0) sym[j].opened = 0;
+		}
+		if (!in_period) continue;
+		sym[j].realized += realized;
+		sym[j].fees += fee;
+		t_real += realized;
+		t_fees += fee;
+		/* strategy comes from the plan the operation executed;
+		 * multi-leg operation ids carry a -L suffix */
+		{
+			char pid[32], *dash;
+			snprintf(pid, sizeof(pid), "%s", ops[i].id);
+			dash = strstr(pid, "-L");
+			if (dash) *dash = '\0';
+			if (rbs_plan_load(pid, &plan) != 0)
+				snprintf(plan.strategy, sizeof(plan.strategy), "unknown");
+		}
+		s = rbs_pnl_row_find(strat, &nstrat, plan.strategy);
+		if (s >= 0) {
+			strat[s].realized += realized;
+			strat[s].fees += fee;
+		}
+		localtime_r(&ops[i].executed, &tmv);
+		strftime(daybuf, sizeof(daybuf), "%Y-%m-%d", &tmv);
+		for (j = 0; j < ndays; j++)
+			if (strcmp(days[j].day, daybuf) == 0) break;
+		if (j == ndays && ndays < 62) {
+			snprintf(days[ndays].day, sizeof(days[ndays].day), "%s", daybuf);
+			days[ndays].realized = days[ndays].fees = 0;
+			ndays++;
+		}
+		if (j < ndays) {
+			days[j].realized += realized;
+			days[j].fees += fee;
+		}
+	}
+	/* open remainder: mark against the feed, accrue borrow estimate */
+	for (j = 0; j < nsym; j++) {
+		double px;
+		if (sym[j].qty <= 0) continue;
+		if (rbs_price_get(sym[j].key, &px) != 0) px = 0;
+		if (px > 0)
+			sym[j].unreal = (px - sym[j].cost / sym[j].qty) * sym[j].qty;
+		if (sym[j].opened) {
+			double hours = (time(NULL) - sym[j].opened) / 3600.0;
+			sym[j].interest = sym[j].cost * rbs_margin_hourly_pct()
+			                  / 100.0 * hours;
+		}
+		t_unreal += sym[j].unreal;
+		t_int += sym[j].interest;
+	}
+	if (json) {
+		printf("{\"period\":\"%s\",\"realized\":%.4f,\"fees\":%.4f,"
+		       "\"unrealized\":%.4f,\"est_interest\":%.4f,\"symbols\":[",
+		       period, t_real, t_fees, t_unreal, t_int);
+		for (j = 0; j < nsym; j++)
+			printf("%s{\"symbol\":\"%s\",\"realized\":%.4f,\"fees\":%.4f,"
+			       "\"unrealized\":%.4f,\"est_interest\":%.4f}",
+			       j ? "," : "", sym[j].key, sym[j].realized, sym[j].fees,
+			       sym[j].unreal, sym[j].interest);
+		printf("],\"strategies\":[");
+		for (s = 0; s < nstrat; s++)
+			printf("%s{\"strategy\":\"%s\",\"realized\":%.4f,\"fees\":%.4f}",
+			       s ? "," : "", strat[s].key, strat[s].realized,
+			       strat[s].fees);
+		printf("]}\n");
+		return 0;
+	}
+	printf(span ? "P&L, last %s\n" : "P&L, %s\n", span ? period : "all time");
+	printf("%-10s %12s %10s %12s %12s\n", "SYMBOL", "REALIZED", "FEES",
+	       "UNREALIZED", "EST INT");
+	for (j = 0; j < nsym; j++) {
+		if (sym[j].realized == 0 && sym[j].fees == 0 && sym[j].unreal == 0)
+			continue;
+		printf("%-10s %+12.4f %10.4f %+12.4f %12.4f\n", sym[j].key,
+		       sym[j].realized, sym[j].fees, sym[j].unreal,
+		       sym[j].interest);
+	}
+	printf("%-10s %+12.4f %10.4f %+12.4f %12.4f\n", "TOTAL", t_real,
+	       t_fees, t_unreal, t_int);
+	if (nstrat) {
+		printf("\n%-14s %12s %10s\n", "STRATEGY", "REALIZED", "FEES");
+		for (s = 0; s < nstrat; s++)
+			printf("%-14s %+12.4f %10.4f\n", strat[s].key,
+			       strat[s].realized, strat[s].fees);
+	}
+	if (ndays) {
+		printf("\n%-12s %12s %10s\n", "DAY", "REALIZED", "FEES");
+		for (j = ndays - 1; j >= 0; j--)
+			printf("%-12s %+12.4f %10.4f\n", days[j].day,
+			       days[j].realized, days[j].fees);
+	}
+	if (!nsym) printf("no operations in the period\n");
+	return 0;
+}
+
+#endif
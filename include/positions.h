@@ -0,0 +1,129 @@
+#ifndef RBS_POSITIONS_H
+#define RBS_POSITIONS_H
+#include <stdio.h>
+#include <string.h>
+#include <unistd.h>
+#include "store.h"
+#include "operation.h"
+#include "client.h"
+#include "plan.h"
+#include "config.h"
+#include "gap.h"
+
+/* Positions built from the operation history, with the true
+ * break-even per symbol: paid fees ride on the cost basis, so a
+ * position that looks green on raw price but is underwater after
+ * costs shows as such. */
+
+typedef struct {
+	char symbol[16];
+	double qty;		/* net, buys - sells */
+	double cost;		/* notional spent on buys minus recovered on sells */
+	double fees;		/* estimated fees across all fills */
+} rbs_position;
+
+int rbs_positions_build(rbs_position *out, int max) {
+	static rbs_operation ops[1024];
+	int n, i, j, npos = 0;
+	n = rbs_operation_load_all(ops, 1024);
+	for (i = n - 1; i >= 0; i--) {	/* oldest first */
+		double notional = ops[i].qty * ops[i].price;
+		for (j = 0; j < npos; j++)
+			if (strcmp(out[j].symbol, ops[i].symbol) == 0) break;
+		if (j == npos) {
+			if (npos >= max) continue;
+			memset(&out[npos], 0, sizeof(out[npos]));
+			snprintf(out[npos].symbol, sizeof(out[npos].symbol),
+			         "%s", ops[i].symbol);
+			npos++;
+		}
+		if (strcmp(ops[i].side, "buy") == 0) {
+			out[j].qty += ops[i].qty;
+			out[j].cost += notional;
+		} else {
+			out[j].qty -= ops[i].qty;
+			out[j].cost -= notional;
+		}
+		out[j].fees += notional * rbs_fee_pct() / 100.0;
+	}
+	return npos;
+}
+
+void rbs_positions_draw(int json) {
+	rbs_position pos[64];
+	char be[24];
+	double px, breakeven;
+	int n, i, shown = 0;
+	int tty = isatty(1);
+	if (!json) rbs_client_header();
+	n = rbs_positions_build(pos, 64);
+	if (!json)
+		printf("%-10s %12s %12s %10s %12s %12s  %s\n", "SYMBOL", "QTY",
+		       "COST", "FEES", "BREAK-EVEN", "PRICE", "STATUS");
+	for (i = 0; i < n; i++) {
+		int have_px;
+		if (pos[i].qty <= 0.0000001) continue;	/* flat or short-closed */
+		breakeven = (pos[i].cost + pos[i].fees) / pos[i].qty;
+		snprintf(be, sizeof(be), "%.4f", breakeven);
+		have_px = rbs_client_price(pos[i].symbol, &px) == 0;
+		if (json) {
+			/* one object per line: NDJSON streams cleanly in watch */
+			printf("{\"symbol\":\"%s\",\"qty\":%.8f,\"cost\":%.2f,"
+			       "\"fees\":%.4f,\"breakeven\":%.4f",
+			       pos[i].symbol, pos[i].qty, pos[i].cost,
+			       pos[i].fees, breakeven);
+			if (have_px) printf(",\"price\":%.8f", px);
+			printf("}\n");
+		} else if (have_px) {
+			const char *color = !tty ? "" : px >= breakeven
+			                  ? "\033[32m" : "\033[31m";
+			printf("%-10s %12f %12.2f %10.4f %12s %12f  %s%s%s\n",
+			       pos[i].symbol, pos[i].qty, pos[i].cost,
+			       pos[i].fees, be, px, color,
+			       px >= breakeven ? "above water" : "UNDERWATER",
+			       tty ? "\033[0m" : "");
+		} else {
+			printf("%-10s %12f %12.2f %10.4f %12s %12s  %s\n",
+			       pos[i].symbol, pos[i].qty, pos[i].cost,
+			       pos[i].fees, be, "-", "no quote");
+		}
+		shown++;
+	}
+	if (!shown && !json) printf("no open positions\n");
+	if (shown && !json) {
+		char gapline[200];
+		for (i = 0; i < n; i++) {
+			if (pos[i].qty <= 0.0000001) continue;
+			if (rbs_gap_warn(pos[i].symbol, 0, 12 * 3600, gapline,
+			                 sizeof(gapline)))
+				printf("%s\n", gapline);
+		}
+	}
+}
+
+int rbs_positions_cmd(int argc, char *argv[]) {
+	int watch = 0, json = 0, interval = 2, i;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--watch") == 0) {
+			watch = 1;
+			if (i + 1 < argc && argv[i + 1][0] >= '0' &&
+			    argv[i + 1][0] <= '9')
+				interval = atoi(argv[++i]);
+		}
+		else if (strcmp(argv[i], "--json") == 0) json = 1;
+	}
+	if (interval < 1) interval = 1;
+	if (!watch) {
+		rbs_positions_draw(json);
+		return 0;
+	}
+	for (;;) {
+		if (!json) printf("\033[2J\033[H");
+		rbs_positions_draw(json);
+		fflush(stdout);
+		sleep(interval);
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,170 @@
+#ifndef RBS_PRICE_H
+#define RBS_PRICE_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <sys/time.h>
+#include <unistd.h>
+#include "store.h"
+#include "fmt.h"
+#include "hooks.h"
+
+/* Shared price cache. Concurrent robson commands that need the same
+ * symbol price within the TTL read one cached quote from
+ * ~/.robson/cache instead of each hitting the source. Writes go
+ * through a temp file + rename so readers never see a torn quote.
+ *
+ * The upstream source is ~/.robson/feed/<SYMBOL>: a plain number kept
+ * fresh by whatever feeds this box (daemon, cron, operator). */
+
+#define RBS_PRICE_TTL_MS_DEFAULT 500
+
+long rbs_now_ms() {
+	struct timeval tv;
+	gettimeofday(&tv, NULL);
+	return (long)tv.tv_sec * 1000 + tv.tv_usec / 1000;
+}
+
+long rbs_price_ttl_ms() {
+	const char *s = getenv("ROBSON_PRICE_TTL_MS");
+	long v;
+	if (!s) return RBS_PRICE_TTL_MS_DEFAULT;
+	v = atol(s);
+	return v > 0 ? v : RBS_PRICE_TTL_MS_DEFAULT;
+}
+
+/* Feed-quality knobs: reject ticks that jumped more than the limit
+ * from the last seen price (default 20%) or whose feed file is older
+ * than ROBSON_FEED_MAX_AGE (a duration; unset = no staleness check).
+ * Bad ticks are quarantined to ~/.robson/quarantine.log and never
+ * reach alerts, stops or execution. */
+double rbs_feed_max_jump_pct() {
+	const char *s = getenv("ROBSON_FEED_MAX_JUMP_PCT");
+	double v = s ? atof(s) : 20.0;
+	return v > 0 ? v : 20.0;
+}
+
+void rbs_feed_quarantine(const char *symbol, const char *why, double px) {
+	char path[640];
+	FILE *f;
+	rbs_state_path(path, sizeof(path), "quarantine.log");
+	f = fopen(path, "a");
+	if (!f) return;
+	fprintf(f, "%ld %s %s %f\n", (long)time(NULL), symbol, why, px);
+	fclose(f);
+	fprintf(stderr, "feed quality: %s tick for %s quarantined (%f)\n",
+	        why, symbol, px);
+	{
+		char detail[64];
+		snprintf(detail, sizeof(detail), "%s %s", symbol, why);
+		rbs_hook_fire(strcmp(why, "stale") == 0 ? "feed-stale"
+		                                        : "feed-quarantine", detail);
+	}
+	if (strcmp(why, "jump") == 0)
+		fprintf(stderr, "  (if the move is real, raise "
+		        "ROBSON_FEED_MAX_JUMP_PCT or clear ~/.robson/cache/%s.price)\n",
+		        symbol);
+}
+
+/* Read a quote straight from the source, bypassing the cache.
+ * Returns 0 and fills *out on success. */
+int rbs_price_fetch(const char *symbol, double *out) {
+	char dir[512], path[640], cpath[700], line[64];
+	const char *max_age = getenv("ROBSON_FEED_MAX_AGE");
+	struct stat st;
+	FILE *f;
+	double last = 0;
+	long lts;
+	if (!rbs_symbol_ok(symbol)) return -1;
+	rbs_store_dir(dir, sizeof(dir), "feed");
+	snprintf(path, sizeof(path), "%s/%s", dir, symbol);
+	f = fopen(path, "r");
+	if (!f) return -1;
+	if (fscanf(f, "%lf", out) != 1) {
+		fclose(f);
+		return -1;
+	}
+	fclose(f);
+	if (*out <= 0) {
+		rbs_feed_quarantine(symbol, "non-positive", *out);
+		return -1;
+	}
+	if (max_age && *max_age) {
+		long age = rbs_parse_duration(max_age);
+		if (age > 0 && stat(path, &st) == 0 &&
+		    time(NULL) - st.st_mtime > age) {
+			rbs_feed_quarantine(symbol, "stale", *out);
+			return -1;
+		}
+	}
+	/* last seen price (even an expired cache entry) anchors the jump
+	 * check */
+	rbs_store_dir(dir, sizeof(dir), "cache");
+	snprintf(cpath, sizeof(cpath), "%s/%s.price", dir, symbol);
+	f = fopen(cpath, "r");
+	if (f) {
+		if (fgets(line, sizeof(line), f) &&
+		    sscanf(line, "%ld %lf", &lts, &last) == 2 && last > 0) {
+			double jump = (*out > last ? *out - last : last - *out)
+			            / last * 100.0;
+			if (jump > rbs_feed_max_jump_pct()) {
+				fclose(f);
+				rbs_feed_quarantine(symbol, "jump", *out);
+				return -1;
+			}
+		}
+		fclose(f);
+	}
+	return 0;
+}
+
+int rbs_price_get(const char *symbol, double *out) {
+	char dir[512], path[640], tmp[700];
+	long ts;
+	double px;
+	FILE *f;
+	if (!rbs_symbol_ok(symbol)) return -1;
+	rbs_store_dir(dir, sizeof(dir), "cache");
+	snprintf(path, sizeof(path), "%s/%s.price", dir, symbol);
+	f = fopen(path, "r");
+	if (f) {
+		if (fscanf(f, "%ld %lf", &ts, &px) == 2 &&
+		    rbs_now_ms() - ts < rbs_price_ttl_ms()) {
+			fclose(f);
+			*out = px;
+			return 0;
+		}
+		fclose(f);
+	}
+	if (rbs_price_fetch(symbol, &px) != 0) return -1;
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(tmp, "w");
+	if (f) {
+		fprintf(f, "%ld %f\n", rbs_now_ms(), px);
+		fclose(f);
+		rename(tmp, path);
+	}
+	*out = px;
+	return 0;
+}
+
+int rbs_price_cmd(int argc, char *argv[]) {
+	double px;
+	if (argc < 2) {
+		printf("usage: robson price <symbol>\n");
+		return 1;
+	}
+	if (rbs_price_get(argv[1], &px) != 0) {
+		printf("no price for %s (is the feed running?)\n", argv[1]);
+		return 1;
+	}
+	{
+		char ps[32];
+		rbs_fmt_px(argv[1], px, ps, sizeof(ps));
+		printf("%s %s\n", argv[1], ps);
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,150 @@
+#ifndef RBS_PRICETABLE_H
+#define RBS_PRICETABLE_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+#include <sys/wait.h>
+#include <time.h>
+#include "store.h"
+#include "price.h"
+#include "book.h"
+#include "candles.h"
+#include "fmt.h"
+
+/* Multi-symbol price table: robson price SYM SYM... (or
+ * --symbols-file LIST, one symbol per line). Quotes are fetched in
+ * parallel - one child per symbol, same pattern as the account view -
+ * and rendered with best bid/ask from the book feeder and the 24h
+ * change from the 1d kline when those feeds exist. --watch redraws
+ * the whole table in place. */
+
+#define RBS_PTABLE_MAX 16
+
+/* One symbol's row, written by the child as a single line:
+ * "last bid ask chg have_book have_chg" */
+void rbs_ptable_fetch(const char *symbol, int wfd) {
+	rbs_level bids[4], asks[4];
+	rbs_candle cs[512];
+	char line[160];
+	double px = 0, bid = 0, ask = 0, chg = 0;
+	int nb = 0, na = 0, have_book = 0, have_chg = 0, n;
+	if (rbs_price_get(symbol, &px) != 0) {
+		if (write(wfd, "-\n", 2) < 0) {}
+		return;
+	}
+	if (rbs_book_load(symbol, bids, asks, 4, &nb, &na) == 0 &&
+	    nb > 0 && na > 0) {
+		bid = bids[0].price;
+		ask = asks[0].price;
+		have_book = 1;
+	}
+	n = rbs_candles_load(symbol, "1d", cs, 512);
+	if (n > 0 && cs[n - 1].o > 0) {
+		chg = (px - cs[n - 1].o) / cs[n - 1].o * 100.0;
+		have_chg = 1;
+	}
+	snprintf(line, sizeof(line), "%f %f %f %f %d %d\n", px, bid, ask,
+	         chg, have_book, have_chg);
+	if (write(wfd, line, strlen(line)) < 0) {}
+}
+
+void rbs_ptable_draw(char syms[][16], int nsym) {
+	int pfd[RBS_PTABLE_MAX][2];
+	pid_t pids[RBS_PTABLE_MAX];
+	char buf[160], ps[32], bs[32], as[32];
+	int i;
+	for (i = 0; i < nsym; i++) {
+		if (pipe(pfd[i]) != 0) return;
+		pids[i] = fork();
+		if (pids[i] == 0) {
+			close(pfd[i][0]);
+			rbs_ptable_fetch(syms[i], pfd[i][1]);
+			_exit(0);
+		}
+		close(pfd[i][1]);
+	}
+	printf("%-10s %14s %14s %14s %10s\n", "SYMBOL", "LAST", "BID",
+	       "ASK", "24H");
+	for (i = 0; i < nsym; i++) {
+		double px, bid, ask, chg;
+		int have_book, have_chg;
+		ssize_t got = read(pfd[i][0], buf, sizeof(buf) - 1);
+		close(pfd[i][0]);
+		waitpid(pids[i], NULL, 0);
+		if (got <= 0) buf[0] = '\0';
+		else buf[got] = '\0';
+		if (sscanf(buf, "%lf %lf %lf %lf %d %d", &px, &bid, &ask, &chg,
+		           &have_book, &have_chg) != 6) {
+			printf("%-10s %14s %14s %14s %10s\n", syms[i], "-", "-",
+			       "-", "-");
+			continue;
+		}
+		rbs_fmt_px(syms[i], px, ps, sizeof(ps));
+		if (have_book) {
+			rbs_fmt_px(syms[i], bid, bs, sizeof(bs));
+			rbs_fmt_px(syms[i], ask, as, sizeof(as));
+		} else {
+			snprintf(bs, sizeof(bs), "-");
+			snprintf(as, sizeof(as), "-");
+		}
+		printf("%-10s %14s %14s %14s ", syms[i], ps, bs, as);
+		if (have_chg) printf("%+9.2f%%\n", chg);
+		else printf("%10s\n", "-");
+	}
+}
+
+int rbs_price_table_cmd(int argc, char *argv[]) {
+	char syms[RBS_PTABLE_MAX][16];
+	const char *symfile = NULL;
+	long interval = 2;
+	int nsym = 0, watch = 0, i;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--symbols-file") == 0 && i + 1 < argc)
+			symfile = argv[++i];
+		else if (strcmp(argv[i], "--watch") == 0) watch = 1;
+		else if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc) {
+			interval = rbs_parse_duration(argv[++i]);
+			if (interval <= 0) interval = 2;
+		}
+		else if (rbs_symbol_ok(argv[i]) && nsym < RBS_PTABLE_MAX)
+			snprintf(syms[nsym++], 16, "%s", argv[i]);
+		else if (argv[i][0] != '-') {
+			printf("bad symbol %s\n", argv[i]);
+			return 1;
+		}
+	}
+	if (symfile) {
+		char line[64];
+		FILE *f = fopen(symfile, "r");
+		if (!f) {
+			printf("cannot read %s\n", symfile);
+			return 1;
+		}
+		while (fgets(line, sizeof(line), f) && nsym < RBS_PTABLE_MAX) {
+			char *nl = strchr(line, '\n');
+			if (nl) *nl = '\0';
+			if (line[0] && rbs_symbol_ok(line))
+				snprintf(syms[nsym++], 16, "%s", line);
+		}
+		fclose(f);
+	}
+	if (!nsym) {
+		printf("usage: robson price SYMBOL... [--symbols-file LIST] "
+		       "[--watch]\n");
+		return 1;
+	}
+	if (!watch) {
+		rbs_ptable_draw(syms, nsym);
+		return 0;
+	}
+	for (;;) {
+		printf("\033[H\033[2J");
+		rbs_ptable_draw(syms, nsym);
+		fflush(stdout);
+		sleep(interval);
+	}
+	return 0;
+}
+
+#endif
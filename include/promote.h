@@ -0,0 +1,113 @@
+#ifndef RBS_PROMOTE_H
+#define RBS_PROMOTE_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+#include "paper.h"
+#include "exec_prefs.h"
+
+/* Strategy promotion: a strategy earns its live-eligible flag only
+ * with enough paper history (ROBSON_PROMOTE_MIN_TRADES, default 20)
+ * and a bounded max drawdown over that history
+ * (ROBSON_PROMOTE_MAX_DD_PCT of peak equity, default 20%). Execution
+ * enforces the flag for every non-manual strategy. */
+
+int rbs_promote_min_trades() {
+	const char *s = getenv("ROBSON_PROMOTE_MIN_TRADES");
+	int v = s ? atoi(s) : 20;
+	return v > 0 ? v : 20;
+}
+
+double rbs_promote_max_dd_pct() {
+	const char *s = getenv("ROBSON_PROMOTE_MAX_DD_PCT");
+	double v = s ? atof(s) : 20.0;
+	return v > 0 ? v : 20.0;
+}
+
+int rbs_strategy_live_eligible(const char *strategy) {
+	char dir[512], path[640], line[128], k[64], v[64];
+	FILE *f;
+	int ok = 0;
+	if (strchr(strategy, '/') || strchr(strategy, '.')) return 0;
+	rbs_store_dir(dir, sizeof(dir), "strategies");
+	snprintf(path, sizeof(path), "%s/%s.conf", dir, strategy);
+	f = fopen(path, "r");
+	if (!f) return 0;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0)
+			continue;
+		if (strcmp(k, "live_eligible") == 0) ok = atoi(v);
+	}
+	fclose(f);
+	return ok;
+}
+
+/* Paper history for one strategy: trade count and max drawdown of
+ * the running cash P&L, as a percent of the equity peak. */
+int rbs_promote_history(const char *strategy, int *trades, double *dd_pct) {
+	static rbs_operation ops[1024];
+	rbs_plan p;
+	char base[40], *dash;
+	double equity = 0, peak = 0, dd, worst = 0;
+	int n, i;
+	*trades = 0;
+	n = rbs_operation_load_all(ops, 1024);
+	for (i = n - 1; i >= 0; i--) {	/* oldest first */
+		snprintf(base, sizeof(base), "%s", ops[i].id);
+		dash = strstr(base, "-L");
+		if (dash) *dash = '\0';
+		if (rbs_plan_load(base, &p) != 0) continue;
+		if (strcmp(p.strategy, strategy) != 0) continue;
+		(*trades)++;
+		equity += (strcmp(ops[i].side, "sell") == 0 ? 1 : -1)
+		        * ops[i].qty * ops[i].price;
+		if (equity > peak) peak = equity;
+		dd = peak - equity;
+		if (peak > 0 && dd / peak * 100.0 > worst)
+			worst = dd / peak * 100.0;
+	}
+	*dd_pct = worst;
+	return 0;
+}
+
+int rbs_strategy_cmd(int argc, char *argv[]) {
+	char dir[512], path[640];
+	FILE *f;
+	int trades;
+	double dd;
+	if (argc < 3 || strcmp(argv[1], "promote") != 0) {
+		printf("usage: robson strategy promote <name>\n");
+		return 1;
+	}
+	if (strchr(argv[2], '/') || strchr(argv[2], '.')) {
+		printf("malformed strategy name %s\n", argv[2]);
+		return 1;
+	}
+	rbs_promote_history(argv[2], &trades, &dd);
+	printf("strategy %s: %d paper trade%s, max drawdown %.1f%%\n",
+	       argv[2], trades, trades == 1 ? "" : "s", dd);
+	if (trades < rbs_promote_min_trades()) {
+		printf("not promoted: need at least %d paper trades\n",
+		       rbs_promote_min_trades());
+		return 1;
+	}
+	if (dd > rbs_promote_max_dd_pct()) {
+		printf("not promoted: max drawdown %.1f%% exceeds the %.1f%% bound\n",
+		       dd, rbs_promote_max_dd_pct());
+		return 1;
+	}
+	rbs_store_dir(dir, sizeof(dir), "strategies");
+	snprintf(path, sizeof(path), "%s/%s.conf", dir, argv[2]);
+	f = fopen(path, "a");
+	if (!f) {
+		printf("cannot write strategy config\n");
+		return 1;
+	}
+	fprintf(f, "live_eligible=1\n");
+	fclose(f);
+	printf("strategy %s promoted: live-eligible\n", argv[2]);
+	return 0;
+}
+
+#endif
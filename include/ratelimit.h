@@ -0,0 +1,140 @@
+#ifndef RBS_RATELIMIT_H
+#define RBS_RATELIMIT_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/time.h>
+#include <unistd.h>
+#include "store.h"
+
+/* Client-side token bucket per endpoint class, shared across
+ * concurrent robson processes through a state file, so watch loops
+ * and daemons cannot trip backend throttling or exchange weight
+ * limits. Configure per class:
+ *
+ *   ROBSON_RATE_BACKEND=10/1s     10 requests per second
+ *   ROBSON_RATE_EXCHANGE=1200/1m  Binance-style weight budget
+ *
+ * An unconfigured class is unlimited. rbs_rate_take blocks until a
+ * token is available. */
+
+long rbs_rl_now_ms() {
+	struct timeval tv;
+	gettimeofday(&tv, NULL);
+	return (long)tv.tv_sec * 1000 + tv.tv_usec / 1000;
+}
+
+/* Parse "N/dur" into tokens per window; 0 window = unlimited. */
+int rbs_rate_conf(const char *class_, long *burst, long *window_ms) {
+	char var[64], spec[64], *slash;
+	const char *s;
+	size_t i;
+	long d;
+	snprintf(var, sizeof(var), "ROBSON_RATE_%s", class_);
+	for (i = 12; var[i]; i++)
+		if (var[i] >= 'a' && var[i] <= 'z') var[i] -= 32;
+	s = getenv(var);
+	if (!s || !*s) return -1;
+	snprintf(spec, sizeof(spec), "%s", s);
+	slash = strchr(spec, '/');
+	if (!slash) return -1;
+	*slash = '\0';
+	*burst = atol(spec);
+	d = rbs_parse_duration(slash + 1);
+	if (*burst <= 0 || d <= 0) return -1;
+	*window_ms = d * 1000;
+	return 0;
+}
+
+/* Nonblocking take for request handlers: 0 when a token was taken,
+ * -1 when the bucket is empty right now (reply 429 instead of
+ * stalling the accept loop). Unlimited classes always succeed. */
+int rbs_rate_try(const char *class_) {
+	char dir[512], path[640], tmp[700], line[64];
+	long burst, window_ms, tokens, last, now, refill;
+	FILE *f;
+	if (rbs_rate_conf(class_, &burst, &window_ms) != 0)
+		return 0;
+	rbs_store_dir(dir, sizeof(dir), "ratelimit");
+	snprintf(path, sizeof(path), "%s/%s", dir, class_);
+	tokens = burst;
+	last = rbs_rl_now_ms();
+	f = fopen(path, "r");
+	if (f) {
+		if (fgets(line, sizeof(line), f) &&
+		    sscanf(line, "%ld %ld", &tokens, &last) != 2) {
+			tokens = burst;
+			last = rbs_rl_now_ms();
+		}
+		fclose(f);
+	}
+	now = rbs_rl_now_ms();
+	refill = (now - last) * burst / window_ms;
+	if (refill > 0) {
+		tokens += refill;
+		last = now;
+		if (tokens > burst) tokens = burst;
+	}
+	if (tokens <= 0) return -1;
+	tokens--;
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(tmp, "w");
+	if (f) {
+		fprintf(f, "%ld %ld\n", tokens, last);
+		fclose(f);
+		rename(tmp, path);
+	}
+	return 0;
+}
+
+/* Take one token for the class, sleeping until one is available.
+ * Returns the milliseconds waited. The state update is optimistic
+ * (read, modify, rename) rather than locked: two processes racing on
+ * the last token may both proceed, an overspend of at most the number
+ * of concurrent callers, which beats serializing every quote behind a
+ * lock file. */
+long rbs_rate_take(const char *class_) {
+	char dir[512], path[640], tmp[700], line[64];
+	long burst, window_ms, tokens, last, now, waited = 0, refill;
+	FILE *f;
+	if (rbs_rate_conf(class_, &burst, &window_ms) != 0)
+		return 0;	/* unlimited */
+	rbs_store_dir(dir, sizeof(dir), "ratelimit");
+	snprintf(path, sizeof(path), "%s/%s", dir, class_);
+	for (;;) {
+		tokens = burst;
+		last = rbs_rl_now_ms();
+		f = fopen(path, "r");
+		if (f) {
+			if (fgets(line, sizeof(line), f) &&
+			    sscanf(line, "%ld %ld", &tokens, &last) != 2) {
+				tokens = burst;
+				last = rbs_rl_now_ms();
+			}
+			fclose(f);
+		}
+		now = rbs_rl_now_ms();
+		/* refill proportionally to elapsed time */
+		refill = (now - last) * burst / window_ms;
+		if (refill > 0) {
+			tokens += refill;
+			last = now;
+			if (tokens > burst) tokens = burst;
+		}
+		if (tokens > 0) {
+			tokens--;
+			snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+			f = fopen(tmp, "w");
+			if (f) {
+				fprintf(f, "%ld %ld\n", tokens, last);
+				fclose(f);
+				rename(tmp, path);
+			}
+			return waited;
+		}
+		usleep(window_ms * 1000 / burst);
+		waited += window_ms / burst;
+	}
+}
+
+#endif
@@ -0,0 +1,129 @@
+#ifndef RBS_REBALANCE_H
+#define RBS_REBALANCE_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <math.h>
+#include <time.h>
+#include "store.h"
+#include "plan.h"
+#include "client.h"
+
+/* Rebalance planner: read spot balances through the client layer,
+ * price everything against USDT, compute the delta trades needed to
+ * hit the target allocation and emit them as one multi-leg plan.
+ * Nothing executes here - the output is a plan that still has to
+ * pass validate and execute like any other. */
+
+int rbs_rebalance_cmd(int argc, char *argv[]) {
+	rbs_reb_line lines[RBS_REB_MAX];
+	rbs_plan p;
+	char targets[256], *tok, *colon, *endp;
+	double total = 0, pct_sum = 0, want, delta_notional;
+	int n, i, j, nlegs = 0;
+	const char *spec = NULL;
+	for (i = 0; i < argc; i++) {
+		if (strcmp(argv[i], "--target-allocation") == 0 && i + 1 < argc)
+			spec = argv[++i];
+	}
+	if (!spec) {
+		printf("usage: robson plan rebalance --target-allocation btc:50,eth:30,usdt:20\n");
+		return 1;
+	}
+	n = rbs_balances_load(lines, RBS_REB_MAX);
+	if (n < 0) {
+		printf("no balances found; write asset=qty lines to ~/.robson/balances\n");
+		return 1;
+	}
+	/* Parse the target spec against the loaded balances; an asset in
+	 * the spec with no balance line starts from zero. */
+	snprintf(targets, sizeof(targets), "%s", spec);
+	for (tok = strtok(targets, ","); tok; tok = strtok(NULL, ",")) {
+		colon = strchr(tok, ':');
+		if (!colon) {
+			printf("bad target %s (want asset:percent)\n", tok);
+			return 1;
+		}
+		*colon = '\0';
+		for (j = 0; j < n; j++)
+			if (strcasecmp(lines[j].asset, tok) == 0) break;
+		if (j == n) {
+			if (n >= RBS_REB_MAX) {
+				printf("too many assets (max %d)\n", RBS_REB_MAX);
+				return 1;
+			}
+			snprintf(lines[n].asset, sizeof(lines[n].asset), "%s", tok);
+			lines[n].qty = 0;
+			n++;
+		}
+		lines[j].pct = strtod(colon + 1, &endp);
+		if (endp == colon + 1 || *endp != '\0' || lines[j].pct < 0) {
+			printf("bad percent for %s: %s\n", tok, colon + 1);
+			return 1;
+		}
+		pct_sum += lines[j].pct;
+	}
+	if (fabs(pct_sum - 100.0) > 0.01) {
+		printf("target allocation sums to %.2f%%, want 100%%\n", pct_sum);
+		return 1;
+	}
+	for (i = 0; i < n; i++) {
+		if (rbs_reb_price(&lines[i]) != 0) {
+			printf("no price for %s (feed its USDT pair first)\n", lines[i].asset);
+			return 1;
+		}
+		lines[i].notional = lines[i].qty * lines[i].price;
+		total += lines[i].notional;
+	}
+	if (total <= 0) {
+		printf("total portfolio value is zero; nothing to rebalance\n");
+		return 1;
+	}
+	memset(&p, 0, sizeof(p));
+	rbs_plan_gen_id(p.id, sizeof(p.id));
+	snprintf(p.status, sizeof(p.status), "pending");
+	snprintf(p.strategy, sizeof(p.strategy), "rebalance");
+	snprintf(p.symbol, sizeof(p.symbol), "PORTFOLIO");
+	snprintf(p.side, sizeof(p.side), "buy");
+	p.created = time(NULL);
+	p.ttl = RBS_PLAN_TTL_DEFAULT;
+	printf("portfolio value: %.2f USDT\n", total);
+	printf("%-8s %12s %8s %8s %14s\n", "ASSET", "NOTIONAL", "NOW%", "TARGET%", "DELTA(USDT)");
+	for (i = 0; i < n; i++) {
+		want = total * lines[i].pct / 100.0;
+		delta_notional = want - lines[i].notional;
+		printf("%-8s %12.2f %7.1f%% %7.1f%% %+14.2f\n",
+		       lines[i].asset, lines[i].notional,
+		       lines[i].notional / total * 100.0, lines[i].pct,
+		       delta_notional);
+		if (strcasecmp(lines[i].asset, "usdt") == 0) continue;
+		if (fabs(delta_notional) < total * 0.001) continue;	/* noise */
+		if (nlegs >= 8) {
+			printf("too many rebalance legs\n");
+			return 1;
+		}
+		snprintf(p.legs[nlegs].role, sizeof(p.legs[nlegs].role), "rebalance");
+		snprintf(p.legs[nlegs].side, sizeof(p.legs[nlegs].side),
+		         delta_notional > 0 ? "buy" : "sell");
+		p.legs[nlegs].qty = fabs(delta_notional) / lines[i].price;
+		p.legs[nlegs].price = lines[i].price;
+		snprintf(p.legs[nlegs].status, sizeof(p.legs[nlegs].status), "pending");
+		nlegs++;
+	}
+	if (nlegs == 0) {
+		printf("already balanced; no plan registered\n");
+		return 0;
+	}
+	p.nlegs = nlegs;
+	p.qty = 1;
+	p.price = total;
+	if (rbs_plan_create(&p) != 0) {
+		printf("could not save plan\n");
+		return 1;
+	}
+	printf("plan %s registered (%d rebalance leg%s)\n", p.id, nlegs,
+	       nlegs == 1 ? "" : "s");
+	return 0;
+}
+
+#endif
@@ -0,0 +1,143 @@
+#ifndef RBS_REMOTE_H
+#define RBS_REMOTE_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "http.h"
+#include "plan.h"
+#include "config.h"
+
+/* Remote plan operations. With ROBSON_API_URL set, validate (and
+ * execute) go to the gateway's endpoints so the CLI works against a
+ * remote deployment; the local engine stays as the fallback when the
+ * gateway is unreachable, mirroring the old local-subprocess mode. */
+
+/* Parse {"ok":bool,"output":"..."} loosely: returns 0/1 for ok and
+ * un-escapes the output into out. -1 when the shape is missing. */
+int rbs_remote_result(const char *body, char *out, size_t n) {
+	const char *okp = strstr(body, "\"ok\":");
+	const char *op = strstr(body, "\"output\":\"");
+	size_t o = 0;
+	int ok;
+	if (!okp) return -1;
+	ok = strncmp(okp + 5, "true", 4) == 0;
+	out[0] = '\0';
+	if (op) {
+		op += 10;
+		while (*op && *op != '"' && o + 2 < n) {
+			if (*op == '\\' && op[1] == 'n') {
+				out[o++] = '\n';
+				op += 2;
+			} else if (*op == '\\' && op[1]) {
+				out[o++] = op[1];
+				op += 2;
+			} else {
+				out[o++] = *op++;
+			}
+		}
+		out[o] = '\0';
+	}
+	return ok;
+}
+
+/* Which transport backend operations use: "auto" falls back from
+ * http to local, "http" and "local" are strict, "grpc" is reserved
+ * until that client exists. */
+const char *rbs_transport() {
+	const char *t = getenv("ROBSON_TRANSPORT");
+	return t && *t ? t : "auto";
+}
+
+/* Run one remote plan operation; returns the command exit code, -1
+ * when the caller should fall back to local, or -2 when the chosen
+ * transport is unusable and the command must stop. */
+int rbs_remote_plan_op(const char *op, const char *plan_id,
+                       const char *extra_query) {
+	static char body[65536], output[32768];
+	char url[900];
+	const char *base = rbs_api_base();
+	const char *transport = rbs_transport();
+	int status, ok;
+	if (strcmp(transport, "local") == 0) return -1;
+	if (strcmp(transport, "grpc") == 0) {
+		printf("grpc transport is not built into this binary yet; the\n"
+		       "contract lives in proto/robson.proto - use --transport "
+		       "http or local\n");
+		return -2;
+	}
+	if (strcmp(transport, "http") != 0 && strcmp(transport, "auto") != 0) {
+		printf("unknown transport %s (local, http or grpc)\n", transport);
+		return -2;
+	}
+	if (!base || !*base) {
+		if (strcmp(transport, "http") == 0) {
+			printf("transport http selected but ROBSON_API_URL is not set\n");
+			return -2;
+		}
+		return -1;
+	}
+	snprintf(url, sizeof(url), "%s/%s?plan=%s%s%s", base, op, plan_id,
+	         extra_query && *extra_query ? "&" : "",
+	         extra_query ? extra_query : "");
+	setenv("ROBSON_HTTP_NO_CACHE", "1", 1);	/* state changes must not cache */
+	status = rbs_http_get(url, body, sizeof(body));
+	if (status < 0) {
+		if (strcmp(transport, "http") == 0) {
+			printf("gateway unreachable and transport http is strict; "
+			       "not falling back\n");
+			return -2;
+		}
+		printf("gateway unreachable; falling back to local %s\n", op);
+		return -1;
+	}
+	ok = rbs_remote_result(body, output, sizeof(output));
+	if (ok < 0) {
+		if (strcmp(transport, "http") == 0) {
+			printf("gateway returned a malformed result; transport http "
+			       "is strict, stopping\n");
+			return -2;
+		}
+		printf("gateway returned a malformed result; falling back to local %s\n", op);
+		return -1;
+	}
+	fputs(output, stdout);
+	return ok ? 0 : 1;
+}
+
+/* When the backend only exists inside a container, the management
+ * command runs through the configured exec prefix, e.g.
+ *   ROBSON_BACKEND_EXEC="docker exec robson-backend"
+ *   ROBSON_BACKEND_EXEC="kubectl exec -n robson deploy/backend --"
+ * Returns the command's exit code, or -1 when no prefix is
+ * configured (caller proceeds with the built-in engine). */
+int rbs_container_plan_op(const char *op, const char *plan_id,
+                          const char *flags) {
+	char cmd[768], line[512];
+	const char *prefix = getenv("ROBSON_BACKEND_EXEC");
+	FILE *p;
+	int status;
+	if (!prefix || !*prefix) return -1;
+	if (!rbs_plan_id_ok(plan_id)) {
+		printf("malformed plan id %s\n", plan_id);
+		return 1;
+	}
+	snprintf(cmd, sizeof(cmd), "%s python manage.py %s_plan %s%s%s 2>&1",
+	         prefix, op, plan_id, flags && *flags ? " " : "",
+	         flags ? flags : "");
+	p = popen(cmd, "r");
+	if (!p) {
+		printf("could not run the container exec prefix\n");
+		return 1;
+	}
+	while (fgets(line, sizeof(line), p))
+		fputs(line, stdout);
+	status = pclose(p);
+	if (status != 0) {
+		printf("container %s failed (is the target in "
+		       "ROBSON_BACKEND_EXEC running?)\n", op);
+		return 1;
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,11 @@
+#ifndef RBS_REPORT_H
+#define RBS_REPORT_H
+#include <stdio.h>
+#include "i18n.h"
+
+void rbs_openscreen_report() {
+	printf("%s", rbs_tr("report.title"));
+	printf("%s", rbs_tr("report.empty"));
+}
+
+#endif
@@ -0,0 +1,149 @@
+#ifndef RBS_RISK_H
+#define RBS_RISK_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+
+/* Versioned risk configuration. ~/.robson/risk.conf carries the
+ * limits plus a version that bumps on every change; every change is
+ * appended to risk.audit with who, when, old and new. Validation and
+ * execution print the active version so a report always names the
+ * rules it ran under. Environment variables still win for one-off
+ * overrides. */
+
+static const char *rbs_risk_keys[] = {
+	"max_risk_pct", "max_exposure_pct", "max_leverage", "max_drift_pct",
+};
+#define RBS_RISK_NKEYS 4
+
+int rbs_risk_version(void) {
+	char path[640], line[128], k[64], v[64];
+	FILE *f;
+	int ver = 0;
+	rbs_state_path(path, sizeof(path), "risk.conf");
+	f = fopen(path, "r");
+	if (!f) return 0;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0)
+			continue;
+		if (strcmp(k, "version") == 0) ver = atoi(v);
+	}
+	fclose(f);
+	return ver;
+}
+
+double rbs_risk_value(const char *key, double fallback) {
+	char path[640], line[128], k[64], v[64];
+	FILE *f;
+	double out = fallback;
+	rbs_state_path(path, sizeof(path), "risk.conf");
+	f = fopen(path, "r");
+	if (!f) return fallback;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, k, sizeof(k), v, sizeof(v)) != 0)
+			continue;
+		if (strcmp(k, key) == 0) out = atof(v);
+	}
+	fclose(f);
+	return out;
+}
+
+/* "risk config v3" (or "defaults") for reports. */
+void rbs_risk_banner(char *out, size_t n) {
+	int ver = rbs_risk_version();
+	if (ver > 0) snprintf(out, n, "risk config v%d", ver);
+	else snprintf(out, n, "risk defaults");
+}
+
+int rbs_risk_cmd(int argc, char *argv[]) {
+	char path[640], tmp[700], audit[640], line[128], k[64], v[64];
+	const char *user;
+	FILE *in, *out, *af;
+	double old;
+	int i, ver, wrote = 0;
+	rbs_state_path(path, sizeof(path), "risk.conf");
+	if (argc >= 2 && strcmp(argv[1], "show") == 0) {
+		char banner[40];
+		rbs_risk_banner(banner, sizeof(banner));
+		printf("%s\n", banner);
+		for (i = 0; i < RBS_RISK_NKEYS; i++) {
+			double val = rbs_risk_value(rbs_risk_keys[i], -1);
+			if (val > 0) printf("  %-18s %.2f\n", rbs_risk_keys[i], val);
+			else printf("  %-18s (default)\n", rbs_risk_keys[i]);
+		}
+		return 0;
+	}
+	if (argc >= 2 && strcmp(argv[1], "audit") == 0) {
+		rbs_state_path(audit, sizeof(audit), "risk.audit");
+		in = fopen(audit, "r");
+		if (!in) {
+			printf("no risk changes recorded\n");
+			return 0;
+		}
+		while (fgets(line, sizeof(line), in))
+			fputs(line, stdout);
+		fclose(in);
+		return 0;
+	}
+	if (argc >= 4 && strcmp(argv[1], "set") == 0) {
+		for (i = 0; i < RBS_RISK_NKEYS; i++)
+			if (strcmp(rbs_risk_keys[i], argv[2]) == 0) break;
+		if (i == RBS_RISK_NKEYS) {
+			printf("unknown risk key %s; known:", argv[2]);
+			for (i = 0; i < RBS_RISK_NKEYS; i++)
+				printf(" %s", rbs_risk_keys[i]);
+			printf("\n");
+			return 1;
+		}
+		if (atof(argv[3]) <= 0) {
+			printf("risk limits must be positive\n");
+			return 1;
+		}
+		old = rbs_risk_value(argv[2], -1);
+		ver = rbs_risk_version() + 1;
+		snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+		out = fopen(tmp, "w");
+		if (!out) return 1;
+		fprintf(out, "version=%d\n", ver);
+		in = fopen(path, "r");
+		if (in) {
+			while (fgets(line, sizeof(line), in)) {
+				char parse[128];
+				snprintf(parse, sizeof(parse), "%s", line);
+				if (rbs_kv_line(parse, k, sizeof(k), v, sizeof(v)) != 0)
+					continue;
+				if (strcmp(k, "version") == 0) continue;
+				if (strcmp(k, argv[2]) == 0) {
+					fprintf(out, "%s=%s\n", argv[2], argv[3]);
+					wrote = 1;
+					continue;
+				}
+				fputs(line, out);
+			}
+			fclose(in);
+		}
+		if (!wrote) fprintf(out, "%s=%s\n", argv[2], argv[3]);
+		fclose(out);
+		rename(tmp, path);
+		/* the audit line is the whole point: who moved which limit */
+		user = getenv("ROBSON_USER");
+		if (!user || !*user) user = getenv("USER");
+		if (!user) user = "unknown";
+		rbs_state_path(audit, sizeof(audit), "risk.audit");
+		af = fopen(audit, "a");
+		if (af) {
+			fprintf(af, "%ld %s %s %.4f -> %s (v%d)\n", (long)time(NULL),
+			        user, argv[2], old, argv[3], ver);
+			fclose(af);
+		}
+		printf("risk config v%d: %s = %s\n", ver, argv[2], argv[3]);
+		return 0;
+	}
+	printf("usage: robson risk <show|set KEY VALUE|audit>\n");
+	return 1;
+}
+
+#endif
@@ -0,0 +1,10 @@
+#ifndef RBS_SAY_H
+#define RBS_SAY_H
+#include <stdio.h>
+#include "i18n.h"
+
+void rbs_openscreen_say() {
+	printf("%s", rbs_tr("say.hello"));
+}
+
+#endif
@@ -0,0 +1,171 @@
+#ifndef RBS_SCRIPT_H
+#define RBS_SCRIPT_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+#include "flags.h"
+
+/* Sandboxed strategy scripting (experimental, behind the scripting
+ * flag). Scripts live in ~/.robson/scripts/<name>.rbs as rule lines:
+ *
+ *   # long the dip
+ *   entry: price < 60000 and rsi < 35
+ *   exit: price > 65000
+ *   size: capital * 0.02 / price
+ *
+ * Conditions are comparisons over named variables joined by "and";
+ * size is a left-to-right arithmetic chain. The interpreter can only
+ * read the variables the caller passes - no I/O, no exec, no state -
+ * which is the whole point of sandboxing the logic. */
+
+typedef struct {
+	char name[24];
+	double value;
+} rbs_var;
+
+int rbs_script_lookup(rbs_var *vars, int nvars, const char *tok, double *out) {
+	char *end;
+	int i;
+	double v = strtod(tok, &end);
+	if (end != tok && *end == '\0') {
+		*out = v;
+		return 0;
+	}
+	for (i = 0; i < nvars; i++) {
+		if (strcmp(vars[i].name, tok) == 0) {
+			*out = vars[i].value;
+			return 0;
+		}
+	}
+	printf("script: unknown variable %s\n", tok);
+	return -1;
+}
+
+/* "a < b and c >= d ..." -> 1/0, -1 on error. */
+int rbs_script_cond(rbs_var *vars, int nvars, char *expr) {
+	char *save = NULL, *tok;
+	double lhs, rhs;
+	char op[3];
+	int result = 1;
+	tok = strtok_r(expr, " \t", &save);
+	while (tok) {
+		if (rbs_script_lookup(vars, nvars, tok, &lhs) != 0) return -1;
+		tok = strtok_r(NULL, " \t", &save);
+		if (!tok || strlen(tok) > 2) {
+			printf("script: expected operator after value\n");
+			return -1;
+		}
+		snprintf(op, sizeof(op), "%s", tok);
+		tok = strtok_r(NULL, " \t", &save);
+		if (!tok || rbs_script_lookup(vars, nvars, tok, &rhs) != 0)
+			return -1;
+		if (strcmp(op, "<") == 0) result = result && lhs < rhs;
+		else if (strcmp(op, ">") == 0) result = result && lhs > rhs;
+		else if (strcmp(op, "<=") == 0) result = result && lhs <= rhs;
+		else if (strcmp(op, ">=") == 0) result = result && lhs >= rhs;
+		else if (strcmp(op, "==") == 0) result = result && lhs == rhs;
+		else {
+			printf("script: unknown operator %s\n", op);
+			return -1;
+		}
+		tok = strtok_r(NULL, " \t", &save);
+		if (!tok) break;
+		if (strcmp(tok, "and") != 0) {
+			printf("script: expected 'and', got %s\n", tok);
+			return -1;
+		}
+		tok = strtok_r(NULL, " \t", &save);
+	}
+	return result;
+}
+
+/* "capital * 0.02 / price" evaluated left to right. */
+int rbs_script_expr(rbs_var *vars, int nvars, char *expr, double *out) {
+	char *save = NULL, *tok;
+	double acc, rhs;
+	tok = strtok_r(expr, " \t", &save);
+	if (!tok || rbs_script_lookup(vars, nvars, tok, &acc) != 0) return -1;
+	while ((tok = strtok_r(NULL, " \t", &save))) {
+		char op = tok[0];
+		tok = strtok_r(NULL, " \t", &save);
+		if (!tok || rbs_script_lookup(vars, nvars, tok, &rhs) != 0)
+			return -1;
+		if (op == '+') acc += rhs;
+		else if (op == '-') acc -= rhs;
+		else if (op == '*') acc *= rhs;
+		else if (op == '/') {
+			if (rhs == 0) {
+				printf("script: division by zero\n");
+				return -1;
+			}
+			acc /= rhs;
+		}
+		else {
+			printf("script: unknown operator %c\n", op);
+			return -1;
+		}
+	}
+	*out = acc;
+	return 0;
+}
+
+int rbs_script_cmd(int argc, char *argv[]) {
+	char dir[512], path[640], line[256];
+	rbs_var vars[16];
+	FILE *f;
+	char *colon, *eq, *nl;
+	double size;
+	int i, nvars = 0, r;
+	if (rbs_flag_require("scripting") != 0)
+		return 1;
+	if (argc < 3 || strcmp(argv[1], "eval") != 0) {
+		printf("usage: robson script eval <name> [var=value ...]\n");
+		return 1;
+	}
+	if (strchr(argv[2], '/') || strchr(argv[2], '.')) {
+		printf("malformed script name %s\n", argv[2]);
+		return 1;
+	}
+	for (i = 3; i < argc && nvars < 16; i++) {
+		eq = strchr(argv[i], '=');
+		if (!eq) continue;
+		snprintf(vars[nvars].name, sizeof(vars[nvars].name), "%.*s",
+		         (int)(eq - argv[i]), argv[i]);
+		vars[nvars].value = atof(eq + 1);
+		nvars++;
+	}
+	rbs_store_dir(dir, sizeof(dir), "scripts");
+	snprintf(path, sizeof(path), "%s/%s.rbs", dir, argv[2]);
+	f = fopen(path, "r");
+	if (!f) {
+		printf("no such script %s (looked in ~/.robson/scripts)\n", argv[2]);
+		return 1;
+	}
+	while (fgets(line, sizeof(line), f)) {
+		if (line[0] == '#' || line[0] == '\n') continue;
+		nl = strchr(line, '\n');
+		if (nl) *nl = '\0';
+		colon = strchr(line, ':');
+		if (!colon) continue;
+		*colon = '\0';
+		if (strcmp(line, "entry") == 0 || strcmp(line, "exit") == 0) {
+			r = rbs_script_cond(vars, nvars, colon + 1);
+			if (r < 0) {
+				fclose(f);
+				return 1;
+			}
+			printf("%s: %s\n", line, r ? "yes" : "no");
+		} else if (strcmp(line, "size") == 0) {
+			if (rbs_script_expr(vars, nvars, colon + 1, &size) != 0) {
+				fclose(f);
+				return 1;
+			}
+			printf("size: %f\n", size);
+		}
+	}
+	fclose(f);
+	return 0;
+}
+
+#endif
@@ -0,0 +1,11 @@
+#ifndef RBS_SELL_H
+#define RBS_SELL_H
+#include <stdio.h>
+#include "i18n.h"
+
+void rbs_openscreen_sell() {
+	printf("%s", rbs_tr("sell.title"));
+	printf("%s", rbs_tr("sell.hint"));
+}
+
+#endif
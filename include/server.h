@@ -0,0 +1,506 @@
+#ifndef RBS_SERVER_H
+#define RBS_SERVER_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <arpa/inet.h>
+#include <netinet/in.h>
+#include <signal.h>
+#include <sys/socket.h>
+#include <sys/wait.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "plan.h"
+#include "simulate.h"
+#include "operation.h"
+#include "sync.h"
+#include "execute.h"
+#include "margin.h"
+#include "fees.h"
+#include "apikey.h"
+#include "ratelimit.h"
+#include "daemon.h"
+
+/* serve-api: a small local REST gateway so other surfaces (the React
+ * frontend's order preview, scripts) consume the same engines as the
+ * CLI instead of reimplementing them. Binds localhost only.
+ *
+ *   GET /healthz                         -> {"ok":true}
+ *   GET /simulate?symbol=&side=&qty=&price=&leverage=
+ *        -> the rbs_simulate_calc result as JSON
+ *   GET /validate?plan=ID
+ *   GET /execute?plan=ID[&live=1][&accept_drift=1]
+ *        -> {"ok":bool,"output":"..."} from the shared engines (the
+ *           frontend and remote CLIs hit these instead of shelling
+ *           into the backend)
+ *   GET /operations?offset=N&limit=M
+ *        -> {"items":[...],"next":"/operations?..."} with next absent
+ *           on the last page
+ */
+
+#define RBS_SERVE_PORT_DEFAULT 8741
+
+/* Value of one query parameter into out; 0 when present. */
+int rbs_query_param(const char *query, const char *name, char *out, size_t n) {
+	const char *p = query;
+	size_t len = strlen(name);
+	while (p && *p) {
+		if (strncmp(p, name, len) == 0 && p[len] == '=') {
+			const char *v = p + len + 1;
+			const char *end = strchr(v, '&');
+			size_t vl = end ? (size_t)(end - v) : strlen(v);
+			if (vl >= n) vl = n - 1;
+			memcpy(out, v, vl);
+			out[vl] = '\0';
+			return 0;
+		}
+		p = strchr(p, '&');
+		if (p) p++;
+	}
+	return -1;
+}
+
+void rbs_http_reply(int fd, int code, const char *status, const char *body) {
+	char head[256];
+	int n = snprintf(head, sizeof(head),
+	                 "HTTP/1.0 %d %s\r\nContent-Type: application/json\r\n"
+	                 "Content-Length: %zu\r\n\r\n",
+	                 code, status, strlen(body));
+	if (write(fd, head, n) < 0 || write(fd, body, strlen(body)) < 0) {
+		/* client went away; nothing to do */
+	}
+}
+
+/* FNV-1a content hash doubles as the ETag. */
+unsigned long rbs_etag_hash(const char *s) {
+	unsigned long h = 1469598103934665603UL;
+	for (; *s; s++) h = (h ^ (unsigned char)*s) * 1099511628211UL;
+	return h;
+}
+
+/* Reply with an ETag; when the request carries a matching
+ * If-None-Match the body stays home and 304 goes out instead. */
+void rbs_http_reply_cached(int fd, const char *req, const char *body) {
+	char head[320], etag[24];
+	const char *inm = strcasestr(req, "If-None-Match:");
+	int n;
+	snprintf(etag, sizeof(etag), "\"%lx\"", rbs_etag_hash(body));
+	if (inm && strstr(inm, etag)) {
+		n = snprintf(head, sizeof(head),
+		             "HTTP/1.0 304 Not Modified\r\nETag: %s\r\n\r\n", etag);
+		if (write(fd, head, n) < 0) { /* gone */ }
+		return;
+	}
+	n = snprintf(head, sizeof(head),
+	             "HTTP/1.0 200 OK\r\nContent-Type: application/json\r\n"
+	             "ETag: %s\r\nContent-Length: %zu\r\n\r\n",
+	             etag, strlen(body));
+	if (write(fd, head, n) < 0 || write(fd, body, strlen(body)) < 0) {
+		/* gone */
+	}
+}
+
+void rbs_serve_handle(int fd) {
+	static char postbody[16384];	/* matches the sync client's record cap */
+	char req[2048], body[512], path[512], query[512];
+	char symbol[32], side[16], qty[32], price[32], lev[32];
+	char *sp, *q;
+	rbs_plan p;
+	rbs_sim sim;
+	long postlen = 0;
+	ssize_t got = read(fd, req, sizeof(req) - 1);
+	if (got <= 0) return;
+	req[got] = '\0';
+	postbody[0] = '\0';
+	/* POST bodies can be larger than the header buffer and can land in
+	 * later segments: finish reading the headers, then stream the body
+	 * into its own buffer (or refuse it) so a pushed record is stored
+	 * whole, never a truncated prefix */
+	if (strncmp(req, "POST", 4) == 0) {
+		const char *cl;
+		char *hdr_end;
+		long want, have;
+		while (!(hdr_end = strstr(req, "\r\n\r\n")) &&
+		       got < (ssize_t)sizeof(req) - 1) {
+			ssize_t more = read(fd, req + got, sizeof(req) - 1 - got);
+			if (more <= 0) break;
+			got += more;
+			req[got] = '\0';
+		}
+		if (!hdr_end) {
+			rbs_http_reply(fd, 400, "Bad Request",
+			               "{\"error\":\"headers too large\"}");
+			return;
+		}
+		cl = strcasestr(req, "Content-Length:");
+		want = cl ? atol(cl + 15) : 0;
+		if (want > (long)sizeof(postbody) - 1) {
+			rbs_http_reply(fd, 413, "Payload Too Large",
+			               "{\"error\":\"body over 16KB\"}");
+			return;
+		}
+		have = got - (hdr_end + 4 - req);
+		if (have > 0) memcpy(postbody, hdr_end + 4, have);
+		postlen = have > 0 ? have : 0;
+		while (postlen < want) {
+			ssize_t more = read(fd, postbody + postlen,
+			                    sizeof(postbody) - 1 - postlen);
+			if (more <= 0) break;
+			postlen += more;
+		}
+		postbody[postlen] = '\0';
+		req[hdr_end + 4 - req] = '\0';	/* routing sees headers only */
+	}
+	{
+		int off = strncmp(req, "GET ", 4) == 0 ? 4
+		        : strncmp(req, "POST /state/", 12) == 0 ? 5 : 0;
+		if (!off) {
+			rbs_http_reply(fd, 405, "Method Not Allowed",
+			               "{\"error\":\"GET only (POST for /state)\"}");
+			return;
+		}
+		sp = strchr(req + off, ' ');
+		if (!sp) return;
+		snprintf(path, sizeof(path), "%.*s", (int)(sp - req - off),
+		         req + off);
+	}
+	query[0] = '\0';
+	q = strchr(path, '?');
+	if (q) {
+		snprintf(query, sizeof(query), "%s", q + 1);
+		*q = '\0';
+	}
+	/* auth middleware: once keys exist, every request presents one;
+	 * write scope gates the state-changing routes. Per-key buckets
+	 * (ROBSON_RATE_APIKEY) answer 429 instead of stalling. */
+	if (rbs_apikeys_exist()) {
+		char keyhdr[96], scope[32], class_[64], logkey[16];
+		const char *kh = strcasestr(req, "X-Api-Key:");
+		keyhdr[0] = '\0';
+		if (kh) {
+			int ko = 0;
+			kh += 10;
+			while (*kh == ' ') kh++;
+			while (*kh && *kh != '\r' && *kh != '\n' &&
+			       ko < (int)sizeof(keyhdr) - 1)
+				keyhdr[ko++] = *kh++;
+			keyhdr[ko] = '\0';
+		}
+		if (!keyhdr[0] ||
+		    !rbs_apikey_scope(keyhdr, scope, sizeof(scope))) {
+			printf("api: DENY %s (bad or missing key)\n", path);
+			fflush(stdout);
+			rbs_http_reply(fd, 401, "Unauthorized",
+			               "{\"error\":\"missing or unknown api key\"}");
+			return;
+		}
+		if ((strcmp(path, "/validate") == 0 ||
+		     strcmp(path, "/execute") == 0 ||
+		     (strncmp(req, "POST", 4) == 0 &&
+		      strncmp(path, "/state/", 7) == 0)) &&
+		    strcmp(scope, "write") != 0) {
+			printf("api: DENY %s (key %.8s... lacks write scope)\n",
+			       path, keyhdr);
+			fflush(stdout);
+			rbs_http_reply(fd, 403, "Forbidden",
+			               "{\"error\":\"write scope required\"}");
+			return;
+		}
+		snprintf(logkey, sizeof(logkey), "%.8s", keyhdr);
+		/* per-key bucket: the shared ROBSON_RATE_APIKEY policy is
+		 * mirrored into a per-key class so keys cannot starve each
+		 * other */
+		{
+			const char *perkey = getenv("ROBSON_RATE_APIKEY");
+			if (perkey && *perkey) {
+				char var[96];
+				size_t ci;
+				snprintf(class_, sizeof(class_), "key%.8s", keyhdr);
+				snprintf(var, sizeof(var), "ROBSON_RATE_KEY%.8s", keyhdr);
+				for (ci = 12; var[ci]; ci++)
+					if (var[ci] >= 'a' && var[ci] <= 'z')
+						var[ci] -= 32;
+				setenv(var, perkey, 1);
+				if (rbs_rate_try(class_) != 0) {
+					printf("api: 429 %s (key %s rate limited)\n",
+					       path, logkey);
+					fflush(stdout);
+					rbs_http_reply(fd, 429, "Too Many Requests",
+					               "{\"error\":\"rate limited\"}");
+					return;
+				}
+			}
+		}
+		printf("api: %s key=%s...\n", path, logkey);
+		fflush(stdout);
+	}
+	if (strcmp(path, "/healthz") == 0) {
+		rbs_http_reply_cached(fd, req, "{\"ok\":true}");
+		return;
+	}
+	if (strcmp(path, "/validate") == 0 || strcmp(path, "/execute") == 0) {
+		/* run the real command in a child with stdout captured, so the
+		 * gateway returns exactly what the CLI would have printed */
+		static char big[16384], out[8192], esc[12288];
+		char planid[64], flag1[8], flag2[8], flag3[8];
+		char *vargv[5];
+		int pfd[2], status = 1, nargs = 2, o = 0;
+		size_t i;
+		ssize_t got;
+		pid_t pid;
+		if (rbs_query_param(query, "plan", planid, sizeof(planid)) != 0) {
+			rbs_http_reply(fd, 400, "Bad Request",
+			               "{\"error\":\"plan is required\"}");
+			return;
+		}
+		if (pipe(pfd) != 0) return;
+		pid = fork();
+		if (pid == 0) {
+			close(pfd[0]);
+			dup2(pfd[1], 1);
+			vargv[0] = path + 1;
+			vargv[1] = planid;
+			if (strcmp(path, "/execute") == 0) {
+				if (rbs_query_param(query, "live", flag1, sizeof(flag1)) == 0 &&
+				    strcmp(flag1, "1") == 0)
+					vargv[nargs++] = (char *)"--live";
+				if (rbs_query_param(query, "accept_drift", flag2, sizeof(flag2)) == 0 &&
+				    strcmp(flag2, "1") == 0)
+					vargv[nargs++] = (char *)"--accept-drift";
+				if (rbs_query_param(query, "ack", flag3, sizeof(flag3)) == 0 &&
+				    strcmp(flag3, "1") == 0)
+					vargv[nargs++] = (char *)"--ack";
+			}
+			status = strcmp(path, "/validate") == 0
+			       ? rbs_validate_cmd(nargs, vargv)
+			       : rbs_execute_cmd(nargs, vargv);
+			fflush(stdout);
+			_exit(status);
+		}
+		close(pfd[1]);
+		got = read(pfd[0], out, sizeof(out) - 1);
+		if (got < 0) got = 0;
+		out[got] = '\0';
+		close(pfd[0]);
+		waitpid(pid, &status, 0);
+		for (i = 0; out[i] && o < (int)sizeof(esc) - 3; i++) {
+			if (out[i] == '\n') { esc[o++] = '\\'; esc[o++] = 'n'; }
+			else if (out[i] == '"' || out[i] == '\\') {
+				esc[o++] = '\\';
+				esc[o++] = out[i];
+			}
+			else esc[o++] = out[i];
+		}
+		esc[o] = '\0';
+		snprintf(big, sizeof(big), "{\"ok\":%s,\"output\":\"%s\"}",
+		         WIFEXITED(status) && WEXITSTATUS(status) == 0
+		         ? "true" : "false", esc);
+		rbs_http_reply(fd, 200, "OK", big);
+		return;
+	}
+	if (strcmp(path, "/fees") == 0) {
+		static char big[256];
+		snprintf(big, sizeof(big),
+		         "fee_pct=%f\nmargin_hourly_pct=%f\nfunding_8h_pct=%f\n",
+		         rbs_fee_pct(), rbs_margin_hourly_pct(),
+		         rbs_funding_8h_pct());
+		rbs_http_reply_cached(fd, req, big);
+		return;
+	}
+	if (strcmp(path, "/margin") == 0) {
+		static char big[4096];
+		double collateral, borrowed;
+		if (rbs_margin_read(&collateral, &borrowed, NULL) != 0) {
+			rbs_http_reply(fd, 404, "Not Found",
+			               "{\"error\":\"no margin account\"}");
+			return;
+		}
+		snprintf(big, sizeof(big),
+		         "{\"collateral\":%.2f,\"borrowed\":%.2f,\"level\":%.2f}",
+		         collateral, borrowed,
+		         borrowed > 0 ? collateral / borrowed : 0);
+		rbs_http_reply_cached(fd, req, big);
+		return;
+	}
+	if (strcmp(path, "/operations") == 0) {
+		static rbs_operation ops[1024];
+		static char big[16384];
+		char off[16], lim[16];
+		int offset = 0, limit = 10, n, i, o;
+		if (rbs_query_param(query, "offset", off, sizeof(off)) == 0)
+			offset = atoi(off);
+		if (rbs_query_param(query, "limit", lim, sizeof(lim)) == 0)
+			limit = atoi(lim);
+		if (offset < 0) offset = 0;
+		if (limit <= 0 || limit > 100) limit = 10;
+		n = rbs_operation_load_all(ops, 1024);
+		o = snprintf(big, sizeof(big), "{\"items\":[");
+		for (i = offset; i < n && i < offset + limit &&
+		     o < (int)sizeof(big) - 256; i++)
+			o += snprintf(big + o, sizeof(big) - o,
+			              "%s{\"id\":\"%s\",\"symbol\":\"%s\","
+			              "\"side\":\"%s\",\"qty\":%f,\"price\":%f,"
+			              "\"executed\":%ld}",
+			              i > offset ? "," : "", ops[i].id, ops[i].symbol,
+			              ops[i].side, ops[i].qty, ops[i].price,
+			              (long)ops[i].executed);
+		o += snprintf(big + o, sizeof(big) - o, "]");
+		if (offset + limit < n)
+			o += snprintf(big + o, sizeof(big) - o,
+			              ",\"next\":\"/operations?offset=%d&limit=%d\"",
+			              offset + limit, limit);
+		snprintf(big + o, sizeof(big) - o, "}");
+		rbs_http_reply_cached(fd, req, big);
+		return;
+	}
+	if (strcmp(path, "/simulate") == 0) {
+		if (rbs_query_param(query, "symbol", symbol, sizeof(symbol)) != 0 ||
+		    rbs_query_param(query, "qty", qty, sizeof(qty)) != 0 ||
+		    rbs_query_param(query, "price", price, sizeof(price)) != 0) {
+			rbs_http_reply(fd, 400, "Bad Request",
+			               "{\"error\":\"symbol, qty and price are required\"}");
+			return;
+		}
+		if (rbs_query_param(query, "side", side, sizeof(side)) != 0)
+			snprintf(side, sizeof(side), "buy");
+		if (rbs_query_param(query, "leverage", lev, sizeof(lev)) != 0)
+			snprintf(lev, sizeof(lev), "1");
+		if (!rbs_symbol_ok(symbol) || atof(qty) <= 0 || atof(price) <= 0) {
+			rbs_http_reply(fd, 400, "Bad Request",
+			               "{\"error\":\"malformed symbol, qty or price\"}");
+			return;
+		}
+		memset(&p, 0, sizeof(p));
+		snprintf(p.symbol, sizeof(p.symbol), "%s", symbol);
+		snprintf(p.side, sizeof(p.side), "%s", side);
+		p.qty = atof(qty);
+		p.price = atof(price);
+		rbs_simulate_calc(&p, atof(lev), &sim);
+		snprintf(body, sizeof(body),
+		         "{\"symbol\":\"%s\",\"side\":\"%s\",\"qty\":%f,"
+		         "\"price\":%f,\"notional\":%.2f,\"margin\":%.2f,"
+		         "\"liquidation\":%.2f,\"portfolio\":%.2f,"
+		         "\"exposure_pct\":%.2f}",
+		         p.symbol, p.side, p.qty, sim.price, sim.notional,
+		         sim.margin, sim.liquidation, sim.total,
+		         sim.exposure_pct);
+		rbs_http_reply_cached(fd, req, body);
+		return;
+	}
+	if (strcmp(path, "/state/manifest") == 0) {
+		static char big[32768];
+		big[0] = '\0';
+		rbs_sync_manifest_local(big, sizeof(big));
+		rbs_http_reply(fd, 200, "OK", big);
+		return;
+	}
+	if (strncmp(path, "/state/", 7) == 0) {
+		/* /state/<kind>/<file>: GET serves the record, POST replaces
+		 * it atomically. Kind and name are validated so a request
+		 * cannot reach outside the synced stores. */
+		char dir[512], spath[760], kind[32];
+		const char *rest = path + 7;
+		char *slash2 = strchr(rest, '/');
+		int k;
+		if (!slash2 || !rbs_sync_name_ok(slash2 + 1)) {
+			rbs_http_reply(fd, 400, "Bad Request",
+			               "{\"error\":\"want /state/<kind>/<file>\"}");
+			return;
+		}
+		snprintf(kind, sizeof(kind), "%.*s", (int)(slash2 - rest), rest);
+		for (k = 0; k < RBS_SYNC_NKINDS; k++)
+			if (strcmp(kind, rbs_sync_kinds[k]) == 0) break;
+		if (k == RBS_SYNC_NKINDS) {
+			rbs_http_reply(fd, 400, "Bad Request",
+			               "{\"error\":\"unknown state kind\"}");
+			return;
+		}
+		rbs_store_dir(dir, sizeof(dir), kind);
+		snprintf(spath, sizeof(spath), "%s/%s", dir, slash2 + 1);
+		if (strncmp(req, "POST", 4) == 0) {
+			char tmp[820];
+			FILE *sf;
+			if (postlen <= 0) {
+				rbs_http_reply(fd, 400, "Bad Request",
+				               "{\"error\":\"empty body\"}");
+				return;
+			}
+			snprintf(tmp, sizeof(tmp), "%s.%d", spath, (int)getpid());
+			sf = fopen(tmp, "w");
+			if (!sf) {
+				rbs_http_reply(fd, 500, "Internal Server Error",
+				               "{\"error\":\"cannot write\"}");
+				return;
+			}
+			fwrite(postbody, 1, (size_t)postlen, sf);
+			fclose(sf);
+			rename(tmp, spath);
+			rbs_http_reply(fd, 200, "OK", "{\"ok\":true}");
+			return;
+		}
+		{
+			static char big[16384];
+			FILE *sf = fopen(spath, "r");
+			size_t got2;
+			if (!sf) {
+				rbs_http_reply(fd, 404, "Not Found",
+				               "{\"error\":\"no such record\"}");
+				return;
+			}
+			got2 = fread(big, 1, sizeof(big) - 1, sf);
+			big[got2] = '\0';
+			fclose(sf);
+			rbs_http_reply(fd, 200, "OK", big);
+			return;
+		}
+	}
+	rbs_http_reply(fd, 404, "Not Found", "{\"error\":\"no such route\"}");
+}
+
+int rbs_serve_api_cmd(int argc, char *argv[]) {
+	struct sockaddr_in addr;
+	int port = RBS_SERVE_PORT_DEFAULT, srv, fd, one = 1, i;
+	time_t started = time(NULL);
+	long served = 0;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--port") == 0 && i + 1 < argc)
+			port = atoi(argv[++i]);
+	}
+	if (port <= 0 || port > 65535) {
+		printf("--port wants 1..65535\n");
+		return 1;
+	}
+	signal(SIGPIPE, SIG_IGN);	/* a vanished client must not kill us */
+	srv = socket(AF_INET, SOCK_STREAM, 0);
+	if (srv < 0) {
+		printf("cannot create socket\n");
+		return 1;
+	}
+	setsockopt(srv, SOL_SOCKET, SO_REUSEADDR, &one, sizeof(one));
+	memset(&addr, 0, sizeof(addr));
+	addr.sin_family = AF_INET;
+	addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+	addr.sin_port = htons(port);
+	if (bind(srv, (struct sockaddr *)&addr, sizeof(addr)) != 0 ||
+	    listen(srv, 16) != 0) {
+		printf("cannot listen on 127.0.0.1:%d (already running?)\n", port);
+		close(srv);
+		return 1;
+	}
+	printf("serve-api listening on http://127.0.0.1:%d\n", port);
+	fflush(stdout);
+	for (;;) {
+		fd = accept(srv, NULL, NULL);
+		if (fd < 0) continue;
+		rbs_serve_handle(fd);
+		close(fd);
+		served++;
+		rbs_daemon_heartbeat("server", started,
+		                     served / ((time(NULL) - started) / 60.0 + 1),
+		                     0, "");
+	}
+	return 0;
+}
+
+#endif
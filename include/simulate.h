@@ -0,0 +1,66 @@
+#ifndef RBS_SIMULATE_H
+#define RBS_SIMULATE_H
+#include <stdio.h>
+#include <string.h>
+#include "plan.h"
+#include "client.h"
+#include "price.h"
+
+/* Pre-trade simulation: what the book looks like if this plan fills.
+ * Uses the live quote when the feed has one, otherwise the planned
+ * price, and prices the rest of the portfolio the same way the
+ * rebalance planner does. One engine feeds both the CLI output and
+ * the serve-api /simulate endpoint so every surface shows the same
+ * numbers. */
+
+typedef struct {
+	double price;		/* quote actually used */
+	double notional;
+	double margin;
+	double liquidation;	/* 0 for spot */
+	double total;		/* portfolio value, -1 when unknown */
+	double exposure_pct;	/* of portfolio, when total known */
+} rbs_sim;
+
+void rbs_simulate_calc(rbs_plan *p, double leverage, rbs_sim *out) {
+	double px, total;
+	if (leverage < 1) leverage = 1;
+	if (rbs_price_get(p->symbol, &px) != 0) px = p->price;
+	out->price = px;
+	out->notional = p->qty * px;
+	out->margin = out->notional / leverage;
+	/* First-order liquidation estimate: full margin consumed,
+	 * maintenance margin ignored. */
+	out->liquidation = leverage > 1
+	    ? (strcmp(p->side, "buy") == 0
+	       ? px * (1.0 - 1.0 / leverage)
+	       : px * (1.0 + 1.0 / leverage))
+	    : 0;
+	if (rbs_client_patrimony(&total) == 0 && total > 0) {
+		out->total = total;
+		out->exposure_pct = out->notional / total * 100.0;
+	} else {
+		out->total = -1;
+		out->exposure_pct = 0;
+	}
+}
+
+void rbs_simulate_print(rbs_plan *p, double leverage) {
+	rbs_sim sim;
+	rbs_simulate_calc(p, leverage, &sim);
+	printf("simulation (%s %s %f @ %f, leverage %.1fx):\n",
+	       p->side, p->symbol, p->qty, sim.price, leverage < 1 ? 1 : leverage);
+	printf("  notional:        %.2f\n", sim.notional);
+	printf("  required margin: %.2f\n", sim.margin);
+	if (sim.liquidation > 0)
+		printf("  est liquidation: %.2f\n", sim.liquidation);
+	else
+		printf("  est liquidation: n/a (spot)\n");
+	if (sim.total > 0)
+		printf("  portfolio after fill: %.2f total, this position %.1f%%\n",
+		       sim.total, sim.exposure_pct);
+	else
+		printf("  portfolio exposure: unavailable (no balances or missing feed)\n");
+}
+
+#endif
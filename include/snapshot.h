@@ -0,0 +1,154 @@
+#ifndef RBS_SNAPSHOT_H
+#define RBS_SNAPSHOT_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <time.h>
+#include "store.h"
+#include "operation.h"
+#include "client.h"
+
+/* Point-in-time capture of balances and recorded operations under
+ * ~/.robson/snapshots/<epoch>.snap. robson diff compares now against
+ * the newest snapshot at least --since old - the morning check-in of
+ * what changed overnight. */
+
+int rbs_snapshot_write(void) {
+	char dir[512], path[640], tmp[700];
+	rbs_reb_line bals[RBS_REB_MAX];
+	static rbs_operation ops[1024];
+	FILE *f;
+	int nb, no, i;
+	time_t now = time(NULL);
+	rbs_store_dir(dir, sizeof(dir), "snapshots");
+	snprintf(path, sizeof(path), "%s/%ld.snap", dir, (long)now);
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(tmp, "w");
+	if (!f) return -1;
+	fprintf(f, "taken=%ld\n", (long)now);
+	nb = rbs_balances_load(bals, RBS_REB_MAX);
+	for (i = 0; i < nb; i++)
+		fprintf(f, "balance=%s,%f\n", bals[i].asset, bals[i].qty);
+	no = rbs_operation_load_all(ops, 1024);
+	for (i = 0; i < no; i++)
+		fprintf(f, "op=%s,%s,%s,%f,%f\n", ops[i].id, ops[i].symbol,
+		        ops[i].side, ops[i].qty, ops[i].price);
+	fclose(f);
+	rename(tmp, path);
+	printf("snapshot %ld written (%d balance%s, %d operation%s)\n",
+	       (long)now, nb < 0 ? 0 : nb, nb == 1 ? "" : "s",
+	       no, no == 1 ? "" : "s");
+	return 0;
+}
+
+int rbs_snapshot_cmd(int argc, char *argv[]) {
+	(void)argc; (void)argv;
+	return rbs_snapshot_write() == 0 ? 0 : 1;
+}
+
+/* Newest snapshot whose age is at least min_age; falls back to the
+ * oldest one available. Returns its epoch or 0 when none exist. */
+long rbs_snapshot_pick(long min_age) {
+	char dir[512];
+	DIR *d;
+	struct dirent *e;
+	long ts, best = 0, oldest = 0;
+	time_t now = time(NULL);
+	rbs_store_dir(dir, sizeof(dir), "snapshots");
+	d = opendir(dir);
+	if (!d) return 0;
+	while ((e = readdir(d))) {
+		ts = atol(e->d_name);
+		if (ts <= 0 || !strstr(e->d_name, ".snap")) continue;
+		if (!oldest || ts < oldest) oldest = ts;
+		if (now - ts >= min_age && ts > best) best = ts;
+	}
+	closedir(d);
+	return best ? best : oldest;
+}
+
+int rbs_diff_cmd(int argc, char *argv[]) {
+	char dir[512], path[640], line[256], key[64], val[192], when[40];
+	char asset[12], sym[16], side[8], opid[32];
+	rbs_reb_line bals[RBS_REB_MAX];
+	static rbs_operation ops[1024];
+	static char snap_ops[1024][32];
+	double oldq[RBS_REB_MAX], q, px;
+	int seen[RBS_REB_MAX];
+	FILE *f;
+	long since = 86400, ts;
+	int i, j, nb, no, nso = 0, changes = 0;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--since") == 0 && i + 1 < argc) {
+			since = rbs_parse_duration(argv[++i]);
+			if (since < 0) {
+				printf("--since wants 30m/24h/7d style, got %s\n", argv[i]);
+				return 1;
+			}
+		}
+	}
+	ts = rbs_snapshot_pick(since);
+	if (!ts) {
+		printf("no snapshots yet; run robson snapshot first\n");
+		return 1;
+	}
+	rbs_store_dir(dir, sizeof(dir), "snapshots");
+	snprintf(path, sizeof(path), "%s/%ld.snap", dir, ts);
+	f = fopen(path, "r");
+	if (!f) {
+		printf("cannot open snapshot %ld\n", ts);
+		return 1;
+	}
+	nb = rbs_balances_load(bals, RBS_REB_MAX);
+	if (nb < 0) nb = 0;
+	for (i = 0; i < nb; i++) { oldq[i] = 0; seen[i] = 0; }
+	rbs_fmt_time(ts, when, sizeof(when));
+	printf("diff against snapshot from %s\n", when);
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, "balance") == 0 &&
+		    sscanf(val, "%11[^,],%lf", asset, &q) == 2) {
+			for (i = 0; i < nb; i++) {
+				if (strcmp(bals[i].asset, asset) == 0) {
+					oldq[i] = q;
+					seen[i] = 1;
+					break;
+				}
+			}
+			if (i == nb && q != 0)
+				printf("balance gone: %s (was %f)\n", asset, q), changes++;
+		} else if (strcmp(key, "op") == 0 && nso < 1024 &&
+		           sscanf(val, "%31[^,],%15[^,],%7[^,],%lf,%lf",
+		                  opid, sym, side, &q, &px) == 5) {
+			snprintf(snap_ops[nso], sizeof(snap_ops[nso]), "%s", opid);
+			nso++;
+		}
+	}
+	fclose(f);
+	for (i = 0; i < nb; i++) {
+		if (!seen[i] && bals[i].qty != 0) {
+			printf("balance new:  %s %f\n", bals[i].asset, bals[i].qty);
+			changes++;
+		} else if (seen[i] && bals[i].qty != oldq[i]) {
+			printf("balance:      %s %f -> %f (%+f)\n", bals[i].asset,
+			       oldq[i], bals[i].qty, bals[i].qty - oldq[i]);
+			changes++;
+		}
+	}
+	no = rbs_operation_load_all(ops, 1024);
+	for (i = 0; i < no; i++) {
+		for (j = 0; j < nso; j++)
+			if (strcmp(snap_ops[j], ops[i].id) == 0) break;
+		if (j == nso) {
+			printf("new operation: %s %s %s %f @ %f\n", ops[i].id,
+			       ops[i].side, ops[i].symbol, ops[i].qty, ops[i].price);
+			changes++;
+		}
+	}
+	if (!changes) printf("no changes\n");
+	return 0;
+}
+
+#endif
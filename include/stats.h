@@ -0,0 +1,101 @@
+#ifndef RBS_STATS_H
+#define RBS_STATS_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include "store.h"
+#include "plan.h"
+#include "operation.h"
+
+/* Analytics over the plan store: how the agentic flow is actually
+ * behaving. Counts per status and per strategy, plus the median time
+ * from plan creation to execution (taken from the operation record). */
+
+int rbs_stats_cmp_long(const void *a, const void *b) {
+	long la = *(const long *)a, lb = *(const long *)b;
+	return la < lb ? -1 : la > lb;
+}
+
+int rbs_plan_stats_cmd(int argc, char *argv[]) {
+	static rbs_plan plans[1024];
+	static long lat[1024];
+	rbs_operation op;
+	char opid[40];
+	struct {
+		char name[32];
+		int count;
+	} strat[32];
+	time_t f_since = 0;
+	struct tm tm;
+	int n, i, j, y, mo, dy, nstrat = 0, nlat = 0;
+	int pending = 0, validated = 0, executed = 0, partial = 0,
+	    amended = 0, expired = 0, other = 0, total = 0;
+	for (i = 0; i < argc; i++) {
+		if (strcmp(argv[i], "--since") == 0 && i + 1 < argc) {
+			if (sscanf(argv[++i], "%d-%d-%d", &y, &mo, &dy) != 3) {
+				printf("--since wants YYYY-MM-DD, got %s\n", argv[i]);
+				return 1;
+			}
+			memset(&tm, 0, sizeof(tm));
+			tm.tm_year = y - 1900; tm.tm_mon = mo - 1; tm.tm_mday = dy;
+			f_since = mktime(&tm);
+		}
+	}
+	n = rbs_plan_load_all(plans, 1024);
+	for (i = 0; i < n; i++) {
+		if (f_since && plans[i].created < f_since) continue;
+		total++;
+		if (strcmp(plans[i].status, "pending") == 0) {
+			if (rbs_plan_expired(&plans[i])) expired++;
+			else pending++;
+		}
+		else if (strcmp(plans[i].status, "validated") == 0) validated++;
+		else if (strcmp(plans[i].status, "executed") == 0) executed++;
+		else if (strcmp(plans[i].status, "partial") == 0) partial++;
+		else if (strcmp(plans[i].status, "amended") == 0) amended++;
+		else other++;
+		for (j = 0; j < nstrat; j++)
+			if (strcmp(strat[j].name, plans[i].strategy) == 0) break;
+		if (j == nstrat && nstrat < 32) {
+			snprintf(strat[j].name, sizeof(strat[j].name), "%s",
+			         plans[i].strategy);
+			strat[j].count = 0;
+			nstrat++;
+		}
+		if (j < nstrat) strat[j].count++;
+		if (strcmp(plans[i].status, "executed") == 0 && nlat < 1024) {
+			/* single-leg ops share the plan id; leg 0 stands in for
+			 * multi-leg plans */
+			if (rbs_operation_load(plans[i].id, &op) != 0) {
+				snprintf(opid, sizeof(opid), "%s-L0", plans[i].id);
+				if (rbs_operation_load(opid, &op) != 0) continue;
+			}
+			if (op.executed >= plans[i].created)
+				lat[nlat++] = op.executed - plans[i].created;
+		}
+	}
+	if (!total) {
+		printf("no plans%s\n", f_since ? " in range" : "");
+		return 0;
+	}
+	printf("plans: %d total\n", total);
+	printf("  pending    %d\n", pending);
+	printf("  validated  %d\n", validated);
+	printf("  executed   %d\n", executed);
+	printf("  partial    %d\n", partial);
+	printf("  amended    %d\n", amended);
+	printf("  expired    %d (pending past ttl)\n", expired);
+	if (other) printf("  other      %d\n", other);
+	printf("by strategy:\n");
+	for (j = 0; j < nstrat; j++)
+		printf("  %-12s %d\n", strat[j].name, strat[j].count);
+	if (nlat > 0) {
+		qsort(lat, nlat, sizeof(long), rbs_stats_cmp_long);
+		printf("median plan-to-execution: %lds (over %d execution%s)\n",
+		       lat[nlat / 2], nlat, nlat == 1 ? "" : "s");
+	}
+	return 0;
+}
+
+#endif
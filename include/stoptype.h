@@ -0,0 +1,50 @@
+#ifndef RBS_STOPTYPE_H
+#define RBS_STOPTYPE_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+
+/* Protective stop flavor per symbol, from
+ * ~/.robson/symbols/<SYMBOL>.conf:
+ *
+ *   stop_type=stop-limit        or stop-market (the default)
+ *   stop_limit_offset_pct=0.2   limit distance below the trigger
+ *   liquidity=thin              thin pairs force stop-market
+ *
+ * A stop-limit on a thin book can simply never fill in a gap; the
+ * liquidity marker overrides the preference until real depth metrics
+ * exist. */
+
+int rbs_stop_type_for(const char *symbol, char *type, size_t tn,
+                      double *offset_pct) {
+	char dir[512], path[640], line[128], key[64], val[64];
+	char liquidity[16] = "normal";
+	FILE *f;
+	snprintf(type, tn, "stop-market");
+	*offset_pct = 0.2;
+	if (!rbs_symbol_ok(symbol)) return 0;
+	rbs_store_dir(dir, sizeof(dir), "symbols");
+	snprintf(path, sizeof(path), "%s/%s.conf", dir, symbol);
+	f = fopen(path, "r");
+	if (!f) return 0;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, "stop_type") == 0 &&
+		    (strcmp(val, "stop-limit") == 0 || strcmp(val, "stop-market") == 0))
+			snprintf(type, tn, "%s", val);
+		else if (strcmp(key, "stop_limit_offset_pct") == 0)
+			*offset_pct = atof(val);
+		else if (strcmp(key, "liquidity") == 0)
+			snprintf(liquidity, sizeof(liquidity), "%s", val);
+	}
+	fclose(f);
+	if (strcmp(type, "stop-limit") == 0 && strcmp(liquidity, "thin") == 0) {
+		snprintf(type, tn, "stop-market");
+		return 1;	/* preference overridden */
+	}
+	return 0;
+}
+
+#endif
@@ -0,0 +1,116 @@
+#ifndef RBS_STORE_H
+#define RBS_STORE_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <sys/types.h>
+#include <time.h>
+#include <unistd.h>
+
+/* Local state lives under $XDG_STATE_HOME/robson when XDG_STATE_HOME
+ * is set, otherwise the legacy ~/.robson. On first use with XDG
+ * configured, an existing legacy directory is migrated by rename so
+ * nothing is left behind. Every module that persists something gets
+ * its own subdirectory so files never collide. */
+
+const char *rbs_home() {
+	const char *h = getenv("HOME");
+	return h ? h : ".";
+}
+
+/* Root of the state tree into out; creates it (and migrates the
+ * legacy directory) as needed. */
+void rbs_state_root(char *out, size_t n) {
+	const char *xdg = getenv("XDG_STATE_HOME");
+	char legacy[512];
+	if (xdg && *xdg) {
+		snprintf(out, n, "%s/robson", xdg);
+		mkdir(xdg, 0700);
+		snprintf(legacy, sizeof(legacy), "%s/.robson", rbs_home());
+		if (access(out, F_OK) != 0 && access(legacy, F_OK) == 0) {
+			if (rename(legacy, out) == 0)
+				fprintf(stderr, "migrated %s -> %s\n", legacy, out);
+		}
+	} else {
+		snprintf(out, n, "%s/.robson", rbs_home());
+	}
+	mkdir(out, 0700);
+}
+
+/* Path of one file directly under the state root. */
+void rbs_state_path(char *out, size_t n, const char *rel) {
+	char root[512];
+	rbs_state_root(root, sizeof(root));
+	snprintf(out, n, "%s/%s", root, rel);
+}
+
+void rbs_store_dir(char *out, size_t n, const char *sub) {
+	char root[512];
+	rbs_state_root(root, sizeof(root));
+	snprintf(out, n, "%s", root);
+	if (sub && *sub) {
+		snprintf(out, n, "%s/%s", root, sub);
+		mkdir(out, 0700);
+	}
+}
+
+/* Symbols become file names under ~/.robson; only accept exchange-style
+ * tickers (letters and digits) so nothing can point outside the store. */
+int rbs_symbol_ok(const char *s) {
+	if (!*s) return 0;
+	for (; *s; s++) {
+		if (!((*s >= 'A' && *s <= 'Z') || (*s >= 'a' && *s <= 'z') ||
+		      (*s >= '0' && *s <= '9')))
+			return 0;
+	}
+	return 1;
+}
+
+/* Everything on disk is epoch seconds (UTC). Rendering happens in the
+ * operator's timezone - ROBSON_TZ wins over the system TZ - always
+ * with the explicit offset so journal entries are unambiguous. */
+void rbs_tz_init() {
+	const char *tz = getenv("ROBSON_TZ");
+	if (tz && *tz) setenv("TZ", tz, 1);
+	tzset();
+}
+
+void rbs_fmt_time(time_t t, char *out, size_t n) {
+	struct tm *tm = localtime(&t);
+	strftime(out, n, "%Y-%m-%d %H:%M:%S %z", tm);
+}
+
+/* Short form for tables. */
+void rbs_fmt_time_short(time_t t, char *out, size_t n) {
+	struct tm *tm = localtime(&t);
+	strftime(out, n, "%Y-%m-%d %H:%M %z", tm);
+}
+
+/* Parse "30s", "15m", "24h", "7d" or plain seconds into seconds.
+ * Returns -1 on anything it cannot read. */
+long rbs_parse_duration(const char *s) {
+	char *end;
+	long v = strtol(s, &end, 10);
+	if (end == s || v < 0) return -1;
+	if (*end == '\0' || strcmp(end, "s") == 0) return v;
+	if (strcmp(end, "m") == 0) return v * 60;
+	if (strcmp(end, "h") == 0) return v * 3600;
+	if (strcmp(end, "d") == 0) return v * 86400;
+	return -1;
+}
+
+/* Parse one key=value line into key/val buffers. Returns 0 on success. */
+int rbs_kv_line(char *line, char *key, size_t kn, char *val, size_t vn) {
+	char *eq = strchr(line, '=');
+	char *nl;
+	if (!eq) return -1;
+	*eq = '\0';
+	nl = strchr(eq + 1, '\n');
+	if (nl) *nl = '\0';
+	snprintf(key, kn, "%s", line);
+	snprintf(val, vn, "%s", eq + 1);
+	return 0;
+}
+
+#endif
@@ -0,0 +1,119 @@
+#ifndef RBS_STRESS_H
+#define RBS_STRESS_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <math.h>
+#include "store.h"
+#include "client.h"
+
+/* Stress testing: apply a shock scenario to the current portfolio and
+ * show the hypothetical damage. Scenarios are comma-separated
+ * "<pct>% <asset|alts|all>" clauses, or one of the built-in
+ * historical weeks. Spot-only today: margin and liquidation lines
+ * appear as soon as leveraged positions exist locally. */
+
+typedef struct {
+	const char *name;
+	const char *spec;
+	const char *note;
+} rbs_scenario;
+
+static const rbs_scenario rbs_scenarios[] = {
+	{ "may-2021", "-35% btc, -50% alts", "May 2021 deleveraging week" },
+	{ "ftx-week", "-25% btc, -35% alts", "FTX collapse week, Nov 2022" },
+};
+
+int rbs_asset_is_stable(const char *a) {
+	return strcasecmp(a, "usdt") == 0 || strcasecmp(a, "usdc") == 0 ||
+	       strcasecmp(a, "busd") == 0 || strcasecmp(a, "dai") == 0;
+}
+
+/* Shock in percent for one asset under the given spec; 0 when the
+ * scenario does not touch it. Returns -1 on a malformed spec. */
+int rbs_stress_shock(const char *spec, const char *asset, double *out) {
+	char buf[256], *tok, *pct_end;
+	double pct;
+	const char *target;
+	*out = 0;
+	snprintf(buf, sizeof(buf), "%s", spec);
+	for (tok = strtok(buf, ","); tok; tok = strtok(NULL, ",")) {
+		while (*tok == ' ') tok++;
+		pct = strtod(tok, &pct_end);
+		if (pct_end == tok || *pct_end != '%')
+			return -1;
+		target = pct_end + 1;
+		while (*target == ' ') target++;
+		if (!*target) return -1;
+		if (strcasecmp(target, asset) == 0) {
+			*out = pct;
+			return 0;	/* exact clause wins */
+		}
+		if (strcasecmp(target, "all") == 0)
+			*out = pct;
+		else if (strcasecmp(target, "alts") == 0 &&
+		         strcasecmp(asset, "btc") != 0 &&
+		         !rbs_asset_is_stable(asset))
+			*out = pct;
+	}
+	return 0;
+}
+
+int rbs_stress_cmd(int argc, char *argv[]) {
+	rbs_reb_line bals[RBS_REB_MAX];
+	const char *spec = NULL;
+	double shock, before = 0, after = 0, hit;
+	size_t s;
+	int i, n;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--scenario") == 0 && i + 1 < argc)
+			spec = argv[++i];
+	}
+	if (!spec) {
+		printf("usage: robson stress --scenario \"-20%% btc, -30%% alts\"\n");
+		printf("built-in scenarios:\n");
+		for (s = 0; s < sizeof(rbs_scenarios) / sizeof(rbs_scenarios[0]); s++)
+			printf("  %-10s %-22s %s\n", rbs_scenarios[s].name,
+			       rbs_scenarios[s].spec, rbs_scenarios[s].note);
+		return 1;
+	}
+	for (s = 0; s < sizeof(rbs_scenarios) / sizeof(rbs_scenarios[0]); s++)
+		if (strcasecmp(rbs_scenarios[s].name, spec) == 0)
+			spec = rbs_scenarios[s].spec;
+	if (rbs_stress_shock(spec, "btc", &shock) != 0) {
+		printf("malformed scenario clause in: %s\n", spec);
+		return 1;
+	}
+	n = rbs_balances_load(bals, RBS_REB_MAX);
+	if (n <= 0) {
+		printf("no balances found; write asset=qty lines to ~/.robson/balances\n");
+		return 1;
+	}
+	printf("stress scenario: %s\n", spec);
+	printf("%-8s %12s %8s %12s %12s\n", "ASSET", "NOTIONAL", "SHOCK",
+	       "AFTER", "P&L");
+	for (i = 0; i < n; i++) {
+		if (rbs_reb_price(&bals[i]) != 0) {
+			printf("no price for %s (feed its USDT pair first)\n",
+			       bals[i].asset);
+			return 1;
+		}
+		if (rbs_stress_shock(spec, bals[i].asset, &shock) != 0) {
+			printf("malformed scenario clause in: %s\n", spec);
+			return 1;
+		}
+		bals[i].notional = bals[i].qty * bals[i].price;
+		hit = bals[i].notional * shock / 100.0;
+		before += bals[i].notional;
+		after += bals[i].notional + hit;
+		printf("%-8s %12.2f %7.1f%% %12.2f %+12.2f\n", bals[i].asset,
+		       bals[i].notional, shock, bals[i].notional + hit, hit);
+	}
+	printf("total: %.2f -> %.2f (%+.2f, %+.1f%%)\n", before, after,
+	       after - before, before > 0 ? (after - before) / before * 100.0 : 0);
+	printf("no leveraged positions on file; margin and liquidation "
+	       "checks not applicable\n");
+	return 0;
+}
+
+#endif
@@ -0,0 +1,214 @@
+#ifndef RBS_SYNC_H
+#define RBS_SYNC_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <sys/stat.h>
+#include <time.h>
+#include <utime.h>
+#include "store.h"
+#include "http.h"
+#include "config.h"
+
+/* Shared state across machines: robson sync state pushes and pulls
+ * plans, operations (the journal) and alert rules through the
+ * gateway's /state routes, so a team pointed at one serve-api sees
+ * one set of records. Resolution is per-file last-writer-wins on
+ * mtime; --ask prompts before the losing side is overwritten, and a
+ * plain run prints every conflict it resolved. Clock skew between
+ * machines shifts who wins - keep the fleet on NTP. */
+
+static const char *rbs_sync_kinds[] = { "plans", "operations", "alerts" };
+#define RBS_SYNC_NKINDS 3
+
+int rbs_sync_name_ok(const char *name) {
+	const char *c;
+	if (!*name || strstr(name, "..")) return 0;
+	for (c = name; *c; c++)
+		if (!((*c >= 'a' && *c <= 'z') || (*c >= 'A' && *c <= 'Z') ||
+		      (*c >= '0' && *c <= '9') || *c == '.' || *c == '-' ||
+		      *c == '_'))
+			return 0;
+	return 1;
+}
+
+/* "kind/file mtime" lines for everything under the synced stores. */
+int rbs_sync_manifest_local(char *out, size_t n) {
+	char dir[512], path[700];
+	struct stat st;
+	DIR *d;
+	struct dirent *e;
+	size_t o = 0;
+	int k;
+	for (k = 0; k < RBS_SYNC_NKINDS; k++) {
+		rbs_store_dir(dir, sizeof(dir), rbs_sync_kinds[k]);
+		d = opendir(dir);
+		if (!d) continue;
+		while ((e = readdir(d))) {
+			if (e->d_name[0] == '.' || !rbs_sync_name_ok(e->d_name))
+				continue;
+			snprintf(path, sizeof(path), "%s/%s", dir, e->d_name);
+			if (stat(path, &st) != 0 || !S_ISREG(st.st_mode)) continue;
+			o += snprintf(out + o, n - o, "%s/%s %ld\n",
+			              rbs_sync_kinds[k], e->d_name,
+			              (long)st.st_mtime);
+			if (o > n - 1) {
+				/* snprintf reports desired, not written; clamp and
+				 * stop once the manifest no longer fits */
+				o = n - 1;
+				closedir(d);
+				return 0;
+			}
+		}
+		closedir(d);
+	}
+	return 0;
+}
+
+long rbs_sync_remote_mtime(const char *manifest, const char *kindfile) {
+	char want[300];
+	const char *hit;
+	snprintf(want, sizeof(want), "%s ", kindfile);
+	for (hit = manifest; hit; hit = strchr(hit, '\n')) {
+		if (*hit == '\n') hit++;
+		if (strncmp(hit, want, strlen(want)) == 0)
+			return atol(hit + strlen(want));
+		if (!strchr(hit, '\n')) break;
+	}
+	return -1;
+}
+
+int rbs_sync_ask(const char *kindfile, const char *winner) {
+	char answer[16];
+	printf("conflict on %s: %s side is newer; overwrite the other? [y/N] ",
+	       kindfile, winner);
+	fflush(stdout);
+	if (!fgets(answer, sizeof(answer), stdin)) return 0;
+	return answer[0] == 'y' || answer[0] == 'Y';
+}
+
+int rbs_sync_state_cmd(int argc, char *argv[]) {
+	static char manifest[32768], body[16384], url[1024];
+	char dir[512], path[700], line[360], kindfile[300];
+	const char *base = rbs_api_base();
+	struct stat st;
+	DIR *d;
+	struct dirent *e;
+	FILE *f;
+	int push = 1, pull = 1, ask = 0;
+	int k, status, pushed = 0, pulled = 0, conflicts = 0, i;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--push") == 0) pull = 0;
+		else if (strcmp(argv[i], "--pull") == 0) push = 0;
+		else if (strcmp(argv[i], "--ask") == 0) ask = 1;
+	}
+	if (!base) {
+		printf("no backend configured: set api_base_url in the profile "
+		       "or ROBSON_API_BASE_URL\n");
+		return 1;
+	}
+	snprintf(url, sizeof(url), "%s/state/manifest", base);
+	status = rbs_http_get(url, manifest, sizeof(manifest));
+	if (status != 200) {
+		printf("backend manifest failed: %s (HTTP %d)\n",
+		       rbs_api_error_code(status), status);
+		return 1;
+	}
+	/* push: local files strictly newer than the remote copy */
+	if (push) {
+		for (k = 0; k < RBS_SYNC_NKINDS; k++) {
+			rbs_store_dir(dir, sizeof(dir), rbs_sync_kinds[k]);
+			d = opendir(dir);
+			if (!d) continue;
+			while ((e = readdir(d))) {
+				long remote, got;
+				if (e->d_name[0] == '.' || !rbs_sync_name_ok(e->d_name))
+					continue;
+				snprintf(path, sizeof(path), "%s/%s", dir, e->d_name);
+				if (stat(path, &st) != 0 || !S_ISREG(st.st_mode))
+					continue;
+				snprintf(kindfile, sizeof(kindfile), "%s/%s",
+				         rbs_sync_kinds[k], e->d_name);
+				remote = rbs_sync_remote_mtime(manifest, kindfile);
+				if (remote >= (long)st.st_mtime) continue;
+				if (remote >= 0) {
+					conflicts++;
+					printf("conflict %s: local newer, pushing\n",
+					       kindfile);
+					if (ask && !rbs_sync_ask(kindfile, "local"))
+						continue;
+				}
+				f = fopen(path, "r");
+				if (!f) continue;
+				got = (long)fread(body, 1, sizeof(body) - 1, f);
+				fclose(f);
+				snprintf(url, sizeof(url), "%s/state/%s", base, kindfile);
+				status = rbs_http_post_raw(url, body, (size_t)got);
+				if (status == 200) pushed++;
+				else printf("push %s failed (HTTP %d)\n", kindfile, status);
+			}
+			closedir(d);
+		}
+	}
+	/* pull: remote files strictly newer than (or absent from) here */
+	if (pull) {
+		char *cursor = manifest;
+		while (cursor && *cursor) {
+			char *nl = strchr(cursor, '\n');
+			long remote, local = -1;
+			snprintf(line, sizeof(line), "%.*s",
+			         nl ? (int)(nl - cursor) : (int)strlen(cursor),
+			         cursor);
+			cursor = nl ? nl + 1 : NULL;
+			if (sscanf(line, "%299s %ld", kindfile, &remote) != 2)
+				continue;
+			{
+				char *slash = strchr(kindfile, '/');
+				if (!slash || !rbs_sync_name_ok(slash + 1)) continue;
+				*slash = '\0';
+				for (k = 0; k < RBS_SYNC_NKINDS; k++)
+					if (strcmp(kindfile, rbs_sync_kinds[k]) == 0) break;
+				if (k == RBS_SYNC_NKINDS) continue;
+				rbs_store_dir(dir, sizeof(dir), kindfile);
+				snprintf(path, sizeof(path), "%s/%s", dir, slash + 1);
+				*slash = '/';
+			}
+			if (stat(path, &st) == 0) local = (long)st.st_mtime;
+			if (local >= remote) continue;
+			if (local >= 0) {
+				conflicts++;
+				printf("conflict %s: remote newer, pulling\n", kindfile);
+				if (ask && !rbs_sync_ask(kindfile, "remote"))
+					continue;
+			}
+			snprintf(url, sizeof(url), "%s/state/%s", base, kindfile);
+			status = rbs_http_get(url, body, sizeof(body));
+			if (status != 200) {
+				printf("pull %s failed (HTTP %d)\n", kindfile, status);
+				continue;
+			}
+			{
+				/* stamp the pulled copy with the remote mtime so the
+				 * next sync does not see it as a local edit and push
+				 * identical bytes back */
+				char tmp[760];
+				struct utimbuf ut;
+				snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+				f = fopen(tmp, "w");
+				if (!f) continue;
+				fputs(body, f);
+				fclose(f);
+				ut.actime = ut.modtime = (time_t)remote;
+				utime(tmp, &ut);
+				rename(tmp, path);
+			}
+			pulled++;
+		}
+	}
+	printf("sync: %d pushed, %d pulled, %d conflict%s resolved\n",
+	       pushed, pulled, conflicts, conflicts == 1 ? "" : "s");
+	return 0;
+}
+
+#endif
@@ -0,0 +1,127 @@
+#ifndef RBS_TA_H
+#define RBS_TA_H
+/* RBS_TA_MATH_ONLY strips everything below the pure math so the WASM
+ * build (wasm/analytics.c) gets a freestanding unit. */
+#ifndef RBS_TA_MATH_ONLY
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "store.h"
+#endif
+
+/* Technical analysis. Built-ins (sma, ema, rsi) plus user indicator
+ * plugins: a file ~/.robson/indicators/<name>.ind with
+ *
+ *   base=ema
+ *   period=21
+ *
+ * registers <name> wherever built-ins are accepted, so a strategy can
+ * say "slowtrend" instead of repeating parameters everywhere. */
+
+double rbs_ta_sma(const double *v, int n, int period) {
+	double sum = 0;
+	int i, from = n > period ? n - period : 0;
+	if (n == 0) return 0;
+	for (i = from; i < n; i++) sum += v[i];
+	return sum / (n - from);
+}
+
+double rbs_ta_ema(const double *v, int n, int period) {
+	double k = 2.0 / (period + 1), ema;
+	int i;
+	if (n == 0) return 0;
+	ema = v[0];
+	for (i = 1; i < n; i++) ema = v[i] * k + ema * (1 - k);
+	return ema;
+}
+
+double rbs_ta_rsi(const double *v, int n, int period) {
+	double gain = 0, loss = 0, d;
+	int i, from = n > period + 1 ? n - period - 1 : 0;
+	if (n < 2) return 50;
+	for (i = from + 1; i < n; i++) {
+		d = v[i] - v[i - 1];
+		if (d > 0) gain += d;
+		else loss -= d;
+	}
+	if (gain + loss == 0) return 50;
+	return 100.0 * gain / (gain + loss);
+}
+
+#ifndef RBS_TA_MATH_ONLY
+/* Resolve a name to a base indicator and period: user plugin file
+ * first, then built-ins with their default periods. */
+int rbs_ta_resolve(const char *name, char *base, size_t bn, int *period) {
+	char dir[512], path[640], line[128], key[64], val[64];
+	FILE *f;
+	if (strchr(name, '/') || strchr(name, '.')) return -1;
+	rbs_store_dir(dir, sizeof(dir), "indicators");
+	snprintf(path, sizeof(path), "%s/%s.ind", dir, name);
+	f = fopen(path, "r");
+	if (f) {
+		snprintf(base, bn, "sma");
+		*period = 14;
+		while (fgets(line, sizeof(line), f)) {
+			if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+				continue;
+			if (strcmp(key, "base") == 0) snprintf(base, bn, "%s", val);
+			else if (strcmp(key, "period") == 0) *period = atoi(val);
+		}
+		fclose(f);
+		return 0;
+	}
+	if (strcmp(name, "sma") == 0 || strcmp(name, "ema") == 0 ||
+	    strcmp(name, "rsi") == 0) {
+		snprintf(base, bn, "%s", name);
+		*period = 14;
+		return 0;
+	}
+	return -1;
+}
+
+int rbs_ta_compute(const char *base, const double *v, int n, int period,
+                   double *out) {
+	if (period <= 0) return -1;
+	if (strcmp(base, "sma") == 0) *out = rbs_ta_sma(v, n, period);
+	else if (strcmp(base, "ema") == 0) *out = rbs_ta_ema(v, n, period);
+	else if (strcmp(base, "rsi") == 0) *out = rbs_ta_rsi(v, n, period);
+	else return -1;
+	return 0;
+}
+
+int rbs_indicator_cmd(int argc, char *argv[]) {
+	static double vals[1024];
+	char base[16];
+	double out;
+	int i, n = 0, period;
+	const char *name = NULL;
+	int period_override = 0;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--period") == 0 && i + 1 < argc)
+			period_override = atoi(argv[++i]);
+		else if (!name && (argv[i][0] < '0' || argv[i][0] > '9'))
+			name = argv[i];
+		else if (n < 1024)
+			vals[n++] = atof(argv[i]);
+	}
+	if (!name || n == 0) {
+		printf("usage: robson indicator <sma|ema|rsi|plugin> [--period N] <v1 v2 ...>\n");
+		return 1;
+	}
+	if (rbs_ta_resolve(name, base, sizeof(base), &period) != 0) {
+		printf("unknown indicator %s (no built-in or ~/.robson/indicators/%s.ind)\n",
+		       name, name);
+		return 1;
+	}
+	if (period_override > 0) period = period_override;
+	if (rbs_ta_compute(base, vals, n, period, &out) != 0) {
+		printf("indicator %s has a bad base (%s) or period (%d)\n",
+		       name, base, period);
+		return 1;
+	}
+	printf("%s(%d) over %d values: %f\n", name, period, n, out);
+	return 0;
+}
+#endif /* RBS_TA_MATH_ONLY */
+
+#endif
@@ -0,0 +1,203 @@
+#ifndef RBS_TEMPLATE_H
+#define RBS_TEMPLATE_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <time.h>
+#include "store.h"
+#include "plan.h"
+
+/* Plan templates are the same flat YAML as plan --file plus
+ * ${param} placeholders. Built-ins cover the common setups; a file in
+ * ~/.robson/templates/<name>.yaml with the same name wins over the
+ * built-in of that name. */
+
+typedef struct {
+	const char *name;
+	const char *description;
+	const char *body;
+} rbs_template;
+
+static const rbs_template rbs_templates_builtin[] = {
+	{ "long-breakout", "enter long on a level break",
+	  "# long entry on breakout\n"
+	  "symbol: ${symbol}\n"
+	  "side: buy\n"
+	  "qty: ${qty}\n"
+	  "price: ${price}\n"
+	  "strategy: breakout\n"
+	  "ttl: 12h\n" },
+	{ "dca-weekly", "weekly dollar-cost-average buy",
+	  "# recurring spot accumulation\n"
+	  "symbol: ${symbol}\n"
+	  "side: buy\n"
+	  "qty: ${qty}\n"
+	  "price: ${price}\n"
+	  "strategy: dca\n"
+	  "ttl: 7d\n" },
+	{ "stop-tighten", "sell to tighten a protective stop",
+	  "# replace a wide stop with a closer one\n"
+	  "symbol: ${symbol}\n"
+	  "side: sell\n"
+	  "qty: ${qty}\n"
+	  "price: ${price}\n"
+	  "strategy: protect\n"
+	  "ttl: 6h\n" },
+};
+
+#define RBS_TEMPLATES_N (int)(sizeof(rbs_templates_builtin) / sizeof(rbs_templates_builtin[0]))
+
+/* Fetch a template body into buf: user file first, then built-in.
+ * Returns 0 when found. */
+int rbs_template_body(const char *name, char *buf, size_t n) {
+	char dir[512], path[640];
+	FILE *f;
+	size_t got;
+	int i;
+	if (!rbs_plan_id_ok(name)) return -1;	/* same charset rules as ids */
+	rbs_store_dir(dir, sizeof(dir), "templates");
+	snprintf(path, sizeof(path), "%s/%s.yaml", dir, name);
+	f = fopen(path, "r");
+	if (f) {
+		got = fread(buf, 1, n - 1, f);
+		buf[got] = '\0';
+		fclose(f);
+		return 0;
+	}
+	for (i = 0; i < RBS_TEMPLATES_N; i++) {
+		if (strcmp(rbs_templates_builtin[i].name, name) == 0) {
+			snprintf(buf, n, "%s", rbs_templates_builtin[i].body);
+			return 0;
+		}
+	}
+	return -1;
+}
+
+int rbs_template_list(void) {
+	char dir[512];
+	DIR *d;
+	struct dirent *e;
+	char *dot;
+	int i;
+	for (i = 0; i < RBS_TEMPLATES_N; i++)
+		printf("%-14s %s\n", rbs_templates_builtin[i].name,
+		       rbs_templates_builtin[i].description);
+	rbs_store_dir(dir, sizeof(dir), "templates");
+	d = opendir(dir);
+	if (d) {
+		while ((e = readdir(d))) {
+			dot = strstr(e->d_name, ".yaml");
+			if (!dot || dot[5] != '\0') continue;
+			printf("%-14.*s (user)\n", (int)(dot - e->d_name), e->d_name);
+		}
+		closedir(d);
+	}
+	return 0;
+}
+
+/* Substitute key=value arguments into ${key} placeholders. Any
+ * placeholder left unresolved is an error - a template must fully
+ * collapse into a concrete plan. */
+int rbs_template_fill(const char *body, int argc, char *argv[],
+                      char *out, size_t n) {
+	const char *p = body;
+	size_t o = 0;
+	int i, matched;
+	char *eq;
+	size_t klen;
+	while (*p && o + 1 < n) {
+		if (p[0] == '$' && p[1] == '{') {
+			const char *close = strchr(p + 2, '}');
+			if (!close) {
+				printf("template: unterminated ${ placeholder\n");
+				return -1;
+			}
+			matched = 0;
+			for (i = 0; i < argc; i++) {
+				eq = strchr(argv[i], '=');
+				if (!eq) continue;
+				klen = eq - argv[i];
+				if (klen == (size_t)(close - p - 2) &&
+				    strncmp(argv[i], p + 2, klen) == 0) {
+					o += snprintf(out + o, n - o, "%s", eq + 1);
+					if (o > n - 1) o = n - 1;	/* snprintf reports desired, not written */
+					matched = 1;
+					break;
+				}
+			}
+			if (!matched) {
+				printf("template: missing parameter %.*s (pass %.*s=VALUE)\n",
+				       (int)(close - p - 2), p + 2,
+				       (int)(close - p - 2), p + 2);
+				return -1;
+			}
+			p = close + 1;
+		} else {
+			out[o++] = *p++;
+		}
+	}
+	out[o] = '\0';
+	return 0;
+}
+
+int rbs_template_cmd(int argc, char *argv[]) {
+	static char body[8192], filled[8192];
+	rbs_plan p;
+	FILE *f;
+	if (argc < 1) {
+		printf("usage: robson plan template <list|show NAME|apply NAME k=v...>\n");
+		return 1;
+	}
+	if (strcmp(argv[0], "list") == 0)
+		return rbs_template_list();
+	if (strcmp(argv[0], "show") == 0) {
+		if (argc < 2) {
+			printf("usage: robson plan template show <name>\n");
+			return 1;
+		}
+		if (rbs_template_body(argv[1], body, sizeof(body)) != 0) {
+			printf("no such template %s\n", argv[1]);
+			return 1;
+		}
+		fputs(body, stdout);
+		return 0;
+	}
+	if (strcmp(argv[0], "apply") == 0) {
+		if (argc < 2) {
+			printf("usage: robson plan template apply <name> [param=value ...]\n");
+			return 1;
+		}
+		if (rbs_template_body(argv[1], body, sizeof(body)) != 0) {
+			printf("no such template %s\n", argv[1]);
+			return 1;
+		}
+		if (rbs_template_fill(body, argc - 2, argv + 2,
+		                      filled, sizeof(filled)) != 0)
+			return 1;
+		f = tmpfile();
+		if (!f) {
+			printf("could not stage template\n");
+			return 1;
+		}
+		fputs(filled, f);
+		rewind(f);
+		if (rbs_plan_parse_yaml(f, &p, argv[1]) != 0) {
+			fclose(f);
+			return 1;
+		}
+		fclose(f);
+		rbs_plan_gen_id(p.id, sizeof(p.id));
+		p.created = time(NULL);
+		if (rbs_plan_create(&p) != 0) {
+			printf("could not save plan\n");
+			return 1;
+		}
+		printf("plan %s registered from template %s\n", p.id, argv[1]);
+		return 0;
+	}
+	printf("unknown template subcommand %s\n", argv[0]);
+	return 1;
+}
+
+#endif
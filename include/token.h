@@ -0,0 +1,235 @@
+#ifndef RBS_TOKEN_H
+#define RBS_TOKEN_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <time.h>
+#include "store.h"
+
+/* Backend session tokens. Storage order: the OS keyring via
+ * secret-tool when available, otherwise ~/.robson/token (0600); the
+ * ROBSON_ACCESS_TOKEN environment variable overrides both so CI can
+ * inject a session without touching either store. rbs_token_access is
+ * the one entry point - it renews through the refresh hook when the
+ * access token is stale instead of letting every command start
+ * failing with 401 mid-session.
+ *
+ * The refresh hook is ROBSON_REFRESH_CMD: a command that finds the
+ * refresh token in ROBSON_REFRESH_TOKEN and prints access=... and
+ * expires=... lines (optionally a rotated refresh=...). When the
+ * HTTP client lands it becomes the token endpoint call. */
+
+typedef struct {
+	char access[512];
+	char refresh[512];
+	time_t expires;
+} rbs_token;
+
+void rbs_token_path(char *out, size_t n) {
+	{ char root_[512]; rbs_state_root(root_, sizeof(root_)); snprintf(out, n, "%s/token", root_); }
+}
+
+/* Is the libsecret CLI around? Cached per process. */
+int rbs_keyring_available(void) {
+	static int cached = -1;
+	FILE *p;
+	char line[16];
+	if (cached >= 0) return cached;
+	p = popen("command -v secret-tool 2>/dev/null", "r");
+	cached = p && fgets(line, sizeof(line), p) != NULL;
+	if (p) pclose(p);
+	return cached;
+}
+
+int rbs_keyring_get(const char *field, char *out, size_t n) {
+	char cmd[128], *nl;
+	FILE *p;
+	snprintf(cmd, sizeof(cmd),
+	         "secret-tool lookup service robson field %s 2>/dev/null", field);
+	p = popen(cmd, "r");
+	if (!p) return -1;
+	out[0] = '\0';
+	if (!fgets(out, n, p)) {
+		pclose(p);
+		return -1;
+	}
+	pclose(p);
+	nl = strchr(out, '\n');
+	if (nl) *nl = '\0';
+	return out[0] ? 0 : -1;
+}
+
+int rbs_keyring_set(const char *field, const char *value) {
+	char cmd[160];
+	FILE *p;
+	snprintf(cmd, sizeof(cmd),
+	         "secret-tool store --label robson service robson field %s "
+	         "2>/dev/null", field);
+	p = popen(cmd, "w");
+	if (!p) return -1;
+	fputs(value, p);
+	return pclose(p) == 0 ? 0 : -1;
+}
+
+int rbs_token_load(rbs_token *t) {
+	char path[640], line[600], key[64], val[520];
+	const char *env = getenv("ROBSON_ACCESS_TOKEN");
+	FILE *f;
+	memset(t, 0, sizeof(*t));
+	if (env && *env) {
+		/* CI fallback: an injected token is trusted as-is. */
+		snprintf(t->access, sizeof(t->access), "%s", env);
+		return 0;
+	}
+	if (rbs_keyring_available()) {
+		if (rbs_keyring_get("access", t->access, sizeof(t->access)) == 0) {
+			rbs_keyring_get("refresh", t->refresh, sizeof(t->refresh));
+			if (rbs_keyring_get("expires", line, sizeof(line)) == 0)
+				t->expires = (time_t)atol(line);
+			return 0;
+		}
+	}
+	rbs_token_path(path, sizeof(path));
+	f = fopen(path, "r");
+	if (!f) return -1;
+	while (fgets(line, sizeof(line), f)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, "access") == 0) snprintf(t->access, sizeof(t->access), "%s", val);
+		else if (strcmp(key, "refresh") == 0) snprintf(t->refresh, sizeof(t->refresh), "%s", val);
+		else if (strcmp(key, "expires") == 0) t->expires = (time_t)atol(val);
+	}
+	fclose(f);
+	return t->access[0] ? 0 : -1;
+}
+
+int rbs_token_save(rbs_token *t) {
+	char path[640], tmp[700], dir[512], buf[32];
+	FILE *f;
+	if (rbs_keyring_available()) {
+		snprintf(buf, sizeof(buf), "%ld", (long)t->expires);
+		if (rbs_keyring_set("access", t->access) == 0 &&
+		    (!t->refresh[0] || rbs_keyring_set("refresh", t->refresh) == 0) &&
+		    rbs_keyring_set("expires", buf) == 0)
+			return 0;
+		/* keyring refused; fall through to the file */
+	}
+	rbs_store_dir(dir, sizeof(dir), NULL);
+	rbs_token_path(path, sizeof(path));
+	snprintf(tmp, sizeof(tmp), "%s.%d", path, (int)getpid());
+	f = fopen(tmp, "w");
+	if (!f) return -1;
+	fchmod(fileno(f), 0600);
+	fprintf(f, "access=%s\n", t->access);
+	if (t->refresh[0]) fprintf(f, "refresh=%s\n", t->refresh);
+	fprintf(f, "expires=%ld\n", (long)t->expires);
+	fclose(f);
+	return rename(tmp, path);
+}
+
+/* Run the refresh hook with the refresh token in its environment;
+ * expects access= and expires= lines back. */
+int rbs_token_renew(rbs_token *t) {
+	const char *cmd = getenv("ROBSON_REFRESH_CMD");
+	char line[600], key[64], val[520];
+	FILE *p;
+	int got_access = 0;
+	if (!cmd || !*cmd || !t->refresh[0]) return -1;
+	setenv("ROBSON_REFRESH_TOKEN", t->refresh, 1);
+	p = popen(cmd, "r");
+	if (!p) return -1;
+	while (fgets(line, sizeof(line), p)) {
+		if (rbs_kv_line(line, key, sizeof(key), val, sizeof(val)) != 0)
+			continue;
+		if (strcmp(key, "access") == 0) {
+			snprintf(t->access, sizeof(t->access), "%s", val);
+			got_access = 1;
+		}
+		else if (strcmp(key, "refresh") == 0)
+			snprintf(t->refresh, sizeof(t->refresh), "%s", val);
+		else if (strcmp(key, "expires") == 0)
+			t->expires = (time_t)atol(val);
+	}
+	if (pclose(p) != 0 || !got_access) return -1;
+	return rbs_token_save(t);
+}
+
+/* The access token, renewed and re-persisted if it expired.
+ * Returns 0 with the token in out, -1 when no valid session exists. */
+int rbs_token_access(char *out, size_t n) {
+	rbs_token t;
+	if (rbs_token_load(&t) != 0) return -1;
+	if (t.expires > 0 && time(NULL) >= t.expires) {
+		if (rbs_token_renew(&t) != 0) return -1;
+	}
+	snprintf(out, n, "%s", t.access);
+	return 0;
+}
+
+int rbs_auth_cmd(int argc, char *argv[]) {
+	char access[512];
+	rbs_token t;
+	int i;
+	if (argc >= 2 && strcmp(argv[1], "set-token") == 0) {
+		const char *tok = NULL;
+		memset(&t, 0, sizeof(t));
+		for (i = 2; i < argc; i++) {
+			if (strcmp(argv[i], "--refresh") == 0 && i + 1 < argc)
+				snprintf(t.refresh, sizeof(t.refresh), "%s", argv[++i]);
+			else if (strcmp(argv[i], "--expires-in") == 0 && i + 1 < argc) {
+				long d = rbs_parse_duration(argv[++i]);
+				if (d < 0) {
+					printf("--expires-in wants 30m/24h/7d style, got %s\n",
+					       argv[i]);
+					return 1;
+				}
+				t.expires = time(NULL) + d;
+			}
+			else tok = argv[i];
+		}
+		if (!tok) {
+			printf("usage: robson auth set-token <token> [--refresh TOKEN] [--expires-in 1h]\n");
+			return 1;
+		}
+		snprintf(t.access, sizeof(t.access), "%s", tok);
+		if (rbs_token_save(&t) != 0) {
+			printf("could not store token\n");
+			return 1;
+		}
+		printf("token stored in %s\n",
+		       rbs_keyring_available() ? "OS keyring" : "~/.robson/token");
+		return 0;
+	}
+	if (argc >= 2 && strcmp(argv[1], "show") == 0) {
+		if (rbs_token_load(&t) != 0) {
+			printf("no token stored\n");
+			return 1;
+		}
+		printf("source:  %s\n",
+		       getenv("ROBSON_ACCESS_TOKEN") ? "environment" :
+		       rbs_keyring_available() ? "OS keyring" : "~/.robson/token");
+		printf("access:  %.8s... (%zu chars)\n", t.access, strlen(t.access));
+		printf("refresh: %s\n", t.refresh[0] ? "stored" : "none");
+		if (t.expires > 0)
+			printf("expires: %s (%ld)\n",
+			       time(NULL) >= t.expires ? "EXPIRED" : "valid",
+			       (long)t.expires);
+		else
+			printf("expires: never\n");
+		return 0;
+	}
+	if (argc >= 2 && strcmp(argv[1], "token") == 0) {
+		if (rbs_token_access(access, sizeof(access)) != 0) {
+			printf("no valid session (token missing or expired with no "
+			       "refresh path)\n");
+			return 1;
+		}
+		printf("%s\n", access);
+		return 0;
+	}
+	printf("usage: robson auth <set-token|show|token>\n");
+	return 1;
+}
+
+#endif
@@ -0,0 +1,113 @@
+#ifndef RBS_TRADES_H
+#define RBS_TRADES_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include "store.h"
+#include "operation.h"
+#include "plan.h"
+#include "fmt.h"
+
+/* Trade history: executed fills with fee and, for sells, the realized
+ * P&L against the running average cost at that point. Table by
+ * default, --csv and --json for scripts. */
+
+int rbs_trades_cmd(int argc, char *argv[]) {
+	static rbs_operation ops[1024];
+	struct { char symbol[16]; double qty, cost; } inv[32];
+	char when[40], qs[32], ps[32];
+	const char *f_symbol = NULL;
+	long since = 0;
+	time_t cutoff = 0;
+	int limit = 50, json = 0, csv = 0;
+	int n, i, j, ninv = 0, shown = 0, skip = 0;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--symbol") == 0 && i + 1 < argc) f_symbol = argv[++i];
+		else if (strcmp(argv[i], "--limit") == 0 && i + 1 < argc) limit = atoi(argv[++i]);
+		else if (strcmp(argv[i], "--json") == 0) json = 1;
+		else if (strcmp(argv[i], "--csv") == 0) csv = 1;
+		else if (strcmp(argv[i], "--since") == 0 && i + 1 < argc) {
+			since = rbs_parse_duration(argv[++i]);
+			if (since < 0) {
+				printf("--since wants 7d style, got %s\n", argv[i]);
+				return 1;
+			}
+			cutoff = time(NULL) - since;
+		}
+	}
+	if (limit <= 0) limit = 50;
+	n = rbs_operation_load_all(ops, 1024);
+	/* the walk below runs oldest-first for the average-cost model, so
+	 * honoring --limit means skipping the oldest rows, not the newest:
+	 * count what would print and drop the excess from the front */
+	for (i = n - 1; i >= 0; i--) {
+		if (f_symbol && strcmp(ops[i].symbol, f_symbol) != 0) continue;
+		if (cutoff && ops[i].executed < cutoff) continue;
+		skip++;
+	}
+	skip = skip > limit ? skip - limit : 0;
+	if (csv) printf("time,symbol,side,qty,price,fee,realized_pnl\n");
+	else if (!json)
+		printf("%-22s %-8s %-5s %12s %12s %10s %12s\n", "TIME",
+		       "SYMBOL", "SIDE", "QTY", "PRICE", "FEE", "REALIZED");
+	/* oldest first so average cost evolves correctly */
+	for (i = n - 1; i >= 0; i--) {
+		double notional = ops[i].qty * ops[i].price;
+		double fee = notional * rbs_fee_pct() / 100.0;
+		double realized = 0;
+		int have_realized = 0;
+		for (j = 0; j < ninv; j++)
+			if (strcmp(inv[j].symbol, ops[i].symbol) == 0) break;
+		if (j == ninv) {
+			if (ninv >= 32) continue;
+			memset(&inv[j], 0, sizeof(inv[j]));
+			snprintf(inv[j].symbol, sizeof(inv[j].symbol), "%s",
+			         ops[i].symbol);
+			ninv++;
+		}
+		if (strcmp(ops[i].side, "buy") == 0) {
+			inv[j].cost += notional;
+			inv[j].qty += ops[i].qty;
+		} else if (inv[j].qty > 0) {
+			double avg = inv[j].cost / inv[j].qty;
+			realized = (ops[i].price - avg) * ops[i].qty - fee;
+			have_realized = 1;
+			inv[j].cost -= avg * ops[i].qty;
+			inv[j].qty -= ops[i].qty;
+		}
+		if (f_symbol && strcmp(ops[i].symbol, f_symbol) != 0) continue;
+		if (cutoff && ops[i].executed < cutoff) continue;
+		if (skip > 0) {
+			skip--;
+			continue;
+		}
+		rbs_fmt_time_short(ops[i].executed, when, sizeof(when));
+		rbs_fmt_qty(ops[i].symbol, ops[i].qty, qs, sizeof(qs));
+		rbs_fmt_px(ops[i].symbol, ops[i].price, ps, sizeof(ps));
+		if (json) {
+			printf("{\"time\":%ld,\"symbol\":\"%s\",\"side\":\"%s\","
+			       "\"qty\":%.8f,\"price\":%.8f,\"fee\":%.4f",
+			       (long)ops[i].executed, ops[i].symbol, ops[i].side,
+			       ops[i].qty, ops[i].price, fee);
+			if (have_realized) printf(",\"realized_pnl\":%.4f", realized);
+			printf("}\n");
+		} else if (csv) {
+			printf("%ld,%s,%s,%.8f,%.8f,%.4f,", (long)ops[i].executed,
+			       ops[i].symbol, ops[i].side, ops[i].qty, ops[i].price,
+			       fee);
+			if (have_realized) printf("%.4f\n", realized);
+			else printf("\n");
+		} else {
+			printf("%-22s %-8s %-5s %12s %12s %10.4f ", when,
+			       ops[i].symbol, ops[i].side, qs, ps, fee);
+			if (have_realized) printf("%+12.4f\n", realized);
+			else printf("%12s\n", "-");
+		}
+		shown++;
+	}
+	if (!shown && !json && !csv) printf("no trades\n");
+	return 0;
+}
+
+#endif
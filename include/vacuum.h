@@ -0,0 +1,79 @@
+#ifndef RBS_VACUUM_H
+#define RBS_VACUUM_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <dirent.h>
+#include <sys/stat.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+
+/* Retention for the local stores so long-running installations don't
+ * grow unbounded. Policies are store:age pairs; ROBSON_RETENTION
+ * overrides the defaults below. Dry run unless --apply. Plans have
+ * their own lifecycle-aware pruning in plan gc and are deliberately
+ * not covered here. */
+
+#define RBS_RETENTION_DEFAULT \
+	"cache:1d,httpcache:7d,snapshots:30d,crash:90d,backups:90d,ratelimit:1d"
+
+int rbs_vacuum_cmd(int argc, char *argv[]) {
+	char spec[512], dir[512], path[700];
+	char *tok, *colon;
+	const char *conf = getenv("ROBSON_RETENTION");
+	struct dirent *e;
+	struct stat st;
+	DIR *d;
+	long age;
+	time_t now = time(NULL);
+	int apply = 0, i, pruned = 0, would = 0;
+	for (i = 1; i < argc; i++)
+		if (strcmp(argv[i], "--apply") == 0) apply = 1;
+	snprintf(spec, sizeof(spec), "%s",
+	         conf && *conf ? conf : RBS_RETENTION_DEFAULT);
+	for (tok = strtok(spec, ","); tok; tok = strtok(NULL, ",")) {
+		colon = strchr(tok, ':');
+		if (!colon) {
+			printf("bad retention entry %s (want store:age)\n", tok);
+			return 1;
+		}
+		*colon = '\0';
+		age = rbs_parse_duration(colon + 1);
+		if (age <= 0 || strchr(tok, '/') || strchr(tok, '.')) {
+			printf("bad retention entry %s:%s\n", tok, colon + 1);
+			return 1;
+		}
+		rbs_state_path(dir, sizeof(dir), tok);
+		d = opendir(dir);
+		if (!d) continue;
+		while ((e = readdir(d))) {
+			if (e->d_name[0] == '.') continue;
+			snprintf(path, sizeof(path), "%s/%s", dir, e->d_name);
+			if (stat(path, &st) != 0 || !S_ISREG(st.st_mode)) continue;
+			if (now - st.st_mtime < age) continue;
+			if (apply) {
+				if (unlink(path) == 0) {
+					printf("pruned %s/%s\n", tok, e->d_name);
+					pruned++;
+				}
+			} else {
+				printf("would prune %s/%s (%.1f days old, policy %lds)\n",
+				       tok, e->d_name,
+				       (now - st.st_mtime) / 86400.0, age);
+				would++;
+			}
+		}
+		closedir(d);
+	}
+	if (apply)
+		printf("vacuum: %d file%s pruned\n", pruned, pruned == 1 ? "" : "s");
+	else if (would)
+		printf("%d file%s; re-run with --apply to prune\n", would,
+		       would == 1 ? "" : "s");
+	else
+		printf("nothing to prune\n");
+	return 0;
+}
+
+#endif
@@ -0,0 +1,97 @@
+#ifndef RBS_VERIFYBUILD_H
+#define RBS_VERIFYBUILD_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+#include "store.h"
+#include "http.h"
+#include "version.h"
+
+/* Build attestation: hash the running binary and compare it against
+ * the published checksums for this version, so the binary authorized
+ * to place live orders can be shown untampered. Checksums come from
+ * ROBSON_CHECKSUMS_URL or ~/.robson/checksums, one
+ * "<sha256>  robson-<version>" line per release. */
+
+int rbs_self_sha256(char *out, size_t n) {
+	char exe[512], cmd[600], line[160];
+	ssize_t len;
+	FILE *p;
+	/* resolve our own path first: /proc/self/exe inside the child
+	 * would point at sha256sum, not at us */
+	len = readlink("/proc/self/exe", exe, sizeof(exe) - 1);
+	if (len <= 0) return -1;
+	exe[len] = '\0';
+	if (strchr(exe, '\'')) return -1;
+	snprintf(cmd, sizeof(cmd), "sha256sum '%s' 2>/dev/null", exe);
+	p = popen(cmd, "r");
+	if (!p) return -1;
+	if (!fgets(line, sizeof(line), p)) {
+		pclose(p);
+		return -1;
+	}
+	pclose(p);
+	if (strlen(line) < 64) return -1;
+	snprintf(out, n, "%.64s", line);
+	return 0;
+}
+
+int rbs_verify_build_cmd(void) {
+	static char body[65536];
+	char self[72], path[640], line[200], want[72], name[64], expect[64];
+	const char *url = getenv("ROBSON_CHECKSUMS_URL");
+	FILE *f;
+	int found = 0;
+	if (rbs_self_sha256(self, sizeof(self)) != 0) {
+		printf("could not hash the running binary (sha256sum missing?)\n");
+		return 1;
+	}
+	snprintf(expect, sizeof(expect), "robson-%s", RBS_VERSION);
+	if (url && *url) {
+		if (rbs_http_get(url, body, sizeof(body)) != 200) {
+			printf("could not fetch checksums from %s\n", url);
+			return 1;
+		}
+	} else {
+		rbs_state_path(path, sizeof(path), "checksums");
+		f = fopen(path, "r");
+		if (!f) {
+			printf("no checksum source: set ROBSON_CHECKSUMS_URL or place "
+			       "the release checksums at ~/.robson/checksums\n");
+			return 1;
+		}
+		body[0] = '\0';
+		while (fgets(line, sizeof(line), f))
+			strncat(body, line, sizeof(body) - strlen(body) - 1);
+		fclose(f);
+	}
+	{
+		char *p = body;
+		while (p && *p) {
+			if (sscanf(p, "%71s %63s", want, name) == 2 &&
+			    strcmp(name, expect) == 0) {
+				found = 1;
+				break;
+			}
+			p = strchr(p, '\n');
+			if (p) p++;
+		}
+	}
+	if (!found) {
+		printf("no published checksum for %s; cannot attest this build\n",
+		       expect);
+		return 1;
+	}
+	if (strcmp(self, want) == 0) {
+		printf("build verified: %s matches the published checksum\n", expect);
+		printf("  sha256 %s\n", self);
+		return 0;
+	}
+	printf("BUILD MISMATCH for %s\n  running:   %s\n  published: %s\n"
+	       "do not authorize live orders from this binary\n",
+	       expect, self, want);
+	return 1;
+}
+
+#endif
@@ -0,0 +1,6 @@
+#ifndef RBS_VERSION_H
+#define RBS_VERSION_H
+
+#define RBS_VERSION "0.01"
+
+#endif
@@ -0,0 +1,89 @@
+#ifndef RBS_WATCH_H
+#define RBS_WATCH_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "client.h"
+#include "config.h"
+
+/* Live price watch. Entries often hinge on how a candle closes and on
+ * the funding reset, so the view counts down to the current 1h/4h/1d
+ * candle close and to the next perp funding slot (00/08/16 UTC). */
+
+/* Seconds until the current candle of the given period closes.
+ * Candles are aligned to the epoch, which matches exchange UTC
+ * alignment for 1h/4h/1d. */
+long rbs_candle_close_in(time_t now, long period) {
+	return period - (now % period);
+}
+
+long rbs_next_funding_in(time_t now) {
+	return rbs_candle_close_in(now, 8 * 3600);
+}
+
+void rbs_fmt_countdown(long secs, char *out, size_t n) {
+	if (secs >= 3600)
+		snprintf(out, n, "%ldh%02ldm%02lds", secs / 3600,
+		         (secs % 3600) / 60, secs % 60);
+	else if (secs >= 60)
+		snprintf(out, n, "%ldm%02lds", secs / 60, secs % 60);
+	else
+		snprintf(out, n, "%lds", secs);
+}
+
+void rbs_watch_draw(const char *symbol) {
+	char when[40], c1[24], c4[24], cd[24], fu[24];
+	double px;
+	time_t now = time(NULL);
+	rbs_fmt_time(now, when, sizeof(when));
+	rbs_fmt_countdown(rbs_candle_close_in(now, 3600), c1, sizeof(c1));
+	rbs_fmt_countdown(rbs_candle_close_in(now, 4 * 3600), c4, sizeof(c4));
+	rbs_fmt_countdown(rbs_candle_close_in(now, 24 * 3600), cd, sizeof(cd));
+	rbs_fmt_countdown(rbs_next_funding_in(now), fu, sizeof(fu));
+	printf("robson watch %s - %s%s\n", symbol, when,
+	       rbs_env_is_prod() ? "  [PROD]" : "");
+	if (rbs_client_price(symbol, &px) == 0)
+		printf("price:    %f\n", px);
+	else
+		printf("price:    unavailable (is the feed running?)\n");
+	printf("1h close: %s   4h close: %s   1d close: %s\n", c1, c4, cd);
+	printf("funding:  %s\n", fu);
+}
+
+int rbs_watch_cmd(int argc, char *argv[]) {
+	const char *symbol = NULL;
+	int once = 0, i, interval = 2;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--once") == 0) once = 1;
+		else if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc)
+			interval = atoi(argv[++i]);
+		else symbol = argv[i];
+	}
+	if (!symbol) symbol = rbs_config_default("symbol", NULL);
+	if (!symbol) {
+		printf("usage: robson watch <symbol> [--once] [--interval N]\n");
+		return 1;
+	}
+	if (!rbs_symbol_ok(symbol)) {
+		printf("malformed symbol %s\n", symbol);
+		return 1;
+	}
+	if (interval < 1) interval = 1;
+	if (once) {
+		rbs_watch_draw(symbol);
+		return 0;
+	}
+	for (;;) {
+		printf("\033[2J\033[H");
+		rbs_watch_draw(symbol);
+		printf("\n(refreshing every %ds, Ctrl-C to quit)\n", interval);
+		fflush(stdout);
+		sleep(interval);
+	}
+	return 0;
+}
+
+#endif
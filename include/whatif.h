@@ -0,0 +1,103 @@
+#ifndef RBS_WHATIF_H
+#define RBS_WHATIF_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <math.h>
+#include "store.h"
+#include "client.h"
+#include "risk.h"
+
+/* What-if simulator: size a hypothetical position from capital and
+ * leverage, lay it against the current portfolio and show whether the
+ * risk checks would pass - all read-only, no plan is created. */
+
+double rbs_max_risk_pct() {
+	const char *s = getenv("ROBSON_MAX_RISK_PCT");
+	if (s) return atof(s) > 0 ? atof(s) : 2.0;
+	{ double v = rbs_risk_value("max_risk_pct", 2.0); return v > 0 ? v : 2.0; }
+}
+
+double rbs_max_exposure_pct() {
+	const char *s = getenv("ROBSON_MAX_EXPOSURE_PCT");
+	if (s) return atof(s) > 0 ? atof(s) : 25.0;
+	{ double v = rbs_risk_value("max_exposure_pct", 25.0); return v > 0 ? v : 25.0; }
+}
+
+double rbs_max_leverage() {
+	const char *s = getenv("ROBSON_MAX_LEVERAGE");
+	if (s) return atof(s) > 0 ? atof(s) : 5.0;
+	{ double v = rbs_risk_value("max_leverage", 5.0); return v > 0 ? v : 5.0; }
+}
+
+/* One-day 95% VaR with a flat 4% daily-volatility assumption - a
+ * deliberately blunt instrument until real volatility data arrives. */
+#define RBS_VAR_DAILY_VOL 0.04
+#define RBS_VAR_Z95 1.65
+
+int rbs_whatif_cmd(int argc, char *argv[]) {
+	const char *symbol = NULL;
+	double entry = 0, stop = 0, capital = 0, leverage = 1;
+	double qty, notional, risk, total = 0, var_pos, var_port;
+	int i, have_total, ok = 1, check;
+	for (i = 1; i < argc; i++) {
+		if (strcmp(argv[i], "--symbol") == 0 && i + 1 < argc) symbol = argv[++i];
+		else if (strcmp(argv[i], "--entry") == 0 && i + 1 < argc) entry = atof(argv[++i]);
+		else if (strcmp(argv[i], "--stop") == 0 && i + 1 < argc) stop = atof(argv[++i]);
+		else if (strcmp(argv[i], "--capital") == 0 && i + 1 < argc) capital = atof(argv[++i]);
+		else if (strcmp(argv[i], "--leverage") == 0 && i + 1 < argc) leverage = atof(argv[++i]);
+	}
+	if (!symbol || entry <= 0 || stop <= 0 || capital <= 0) {
+		printf("usage: robson whatif --symbol BTCUSDT --entry 65000 --stop 63000 "
+		       "--capital 500 [--leverage 3]\n");
+		return 1;
+	}
+	if (!rbs_symbol_ok(symbol)) {
+		printf("malformed symbol %s\n", symbol);
+		return 1;
+	}
+	if (leverage < 1) leverage = 1;
+	if (stop >= entry) {
+		printf("whatif models a long: want stop below entry (%f >= %f)\n",
+		       stop, entry);
+		return 1;
+	}
+	qty = capital * leverage / entry;
+	notional = qty * entry;
+	risk = (entry - stop) * qty;
+	have_total = rbs_client_patrimony(&total) == 0;
+	var_pos = notional * RBS_VAR_DAILY_VOL * RBS_VAR_Z95;
+	printf("whatif %s: %f @ %f, stop %f, capital %.2f at %.1fx\n",
+	       symbol, qty, entry, stop, capital, leverage);
+	printf("  notional:       %.2f\n", notional);
+	printf("  margin usage:   %.2f\n", capital);
+	printf("  risk at stop:   %.2f\n", risk);
+	printf("  position VaR:   %.2f (1d 95%%, %.0f%% vol assumption)\n",
+	       var_pos, RBS_VAR_DAILY_VOL * 100);
+	if (have_total && total > 0) {
+		var_port = (total + notional) * RBS_VAR_DAILY_VOL * RBS_VAR_Z95;
+		printf("  portfolio:      %.2f now, exposure %.1f%% after fill\n",
+		       total, notional / total * 100.0);
+		printf("  combined VaR:   %.2f\n", var_port);
+		check = risk <= total * rbs_max_risk_pct() / 100.0;
+		printf("  [%s] risk at stop within %.1f%% of portfolio\n",
+		       check ? "pass" : "FAIL", rbs_max_risk_pct());
+		ok &= check;
+		check = notional <= total * rbs_max_exposure_pct() / 100.0;
+		printf("  [%s] exposure within %.1f%% of portfolio\n",
+		       check ? "pass" : "FAIL", rbs_max_exposure_pct());
+		ok &= check;
+	} else {
+		printf("  portfolio:      unavailable (no balances or missing feed); "
+		       "portfolio checks skipped\n");
+	}
+	check = leverage <= rbs_max_leverage();
+	printf("  [%s] leverage within %.1fx\n", check ? "pass" : "FAIL",
+	       rbs_max_leverage());
+	ok &= check;
+	printf("%s\n", ok ? "all risk checks would pass"
+	                  : "one or more risk checks would FAIL");
+	return ok ? 0 : 1;
+}
+
+#endif
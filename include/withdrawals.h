@@ -0,0 +1,125 @@
+#ifndef RBS_WITHDRAWALS_H
+#define RBS_WITHDRAWALS_H
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+#include <unistd.h>
+#include "store.h"
+#include "daemon.h"
+
+/* Withdrawal watch for keys that do carry transfer rights: the user
+ * data stream (or its feeder) appends events to
+ * ~/.robson/events/withdrawals.log as "<epoch> <address> <amount>
+ * <asset>"; any address missing from ~/.robson/allowlist raises an
+ * immediate alert (stderr + ~/.robson/alerts.log). A byte offset in
+ * the state file keeps repeated checks from re-alerting. */
+
+int rbs_allowlisted(const char *address) {
+	char path[640], line[160], *nl;
+	FILE *f;
+	rbs_state_path(path, sizeof(path), "allowlist");
+	f = fopen(path, "r");
+	if (!f) return 0;	/* no allowlist = nothing is allowed */
+	while (fgets(line, sizeof(line), f)) {
+		nl = strchr(line, '\n');
+		if (nl) *nl = '\0';
+		if (line[0] && strcmp(line, address) == 0) {
+			fclose(f);
+			return 1;
+		}
+	}
+	fclose(f);
+	return 0;
+}
+
+void rbs_withdrawal_alert(const char *address, const char *amount,
+                          const char *asset) {
+	char path[640];
+	FILE *f;
+	fprintf(stderr, "ALERT: withdrawal of %s %s to NON-ALLOWLISTED "
+	        "address %s\n", amount, asset, address);
+	rbs_state_path(path, sizeof(path), "alerts.log");
+	f = fopen(path, "a");
+	if (f) {
+		fprintf(f, "%ld withdrawal %s %s to %s (not allowlisted)\n",
+		        (long)time(NULL), amount, asset, address);
+		fclose(f);
+	}
+}
+
+/* One pass over new events; returns alerts raised, -1 on error. */
+int rbs_withdrawals_pass(void) {
+	char dir[512], events[640], state[640], line[256];
+	char address[128], amount[32], asset[16];
+	long epoch, offset = 0;
+	FILE *f, *s;
+	int alerts = 0;
+	rbs_store_dir(dir, sizeof(dir), "events");
+	snprintf(events, sizeof(events), "%s/withdrawals.log", dir);
+	snprintf(state, sizeof(state), "%s/withdrawals.offset", dir);
+	s = fopen(state, "r");
+	if (s) {
+		if (fscanf(s, "%ld", &offset) != 1) offset = 0;
+		fclose(s);
+	}
+	f = fopen(events, "r");
+	if (!f) return 0;	/* no events yet */
+	/* a rotated/truncated log is shorter than the saved offset; start
+	 * over rather than silently skipping everything after rotation */
+	fseek(f, 0, SEEK_END);
+	if (ftell(f) < offset) offset = 0;
+	if (fseek(f, offset, SEEK_SET) != 0) rewind(f);
+	while (fgets(line, sizeof(line), f)) {
+		if (sscanf(line, "%ld %127s %31s %15s",
+		           &epoch, address, amount, asset) != 4)
+			continue;
+		if (!rbs_allowlisted(address)) {
+			rbs_withdrawal_alert(address, amount, asset);
+			alerts++;
+		}
+	}
+	offset = ftell(f);
+	fclose(f);
+	s = fopen(state, "w");
+	if (s) {
+		fprintf(s, "%ld\n", offset);
+		fclose(s);
+	}
+	return alerts;
+}
+
+int rbs_withdrawals_cmd(int argc, char *argv[]) {
+	int alerts;
+	if (argc >= 2 && strcmp(argv[1], "check") == 0) {
+		alerts = rbs_withdrawals_pass();
+		if (alerts == 0) printf("no new withdrawals outside the allowlist\n");
+		return alerts ? 1 : 0;
+	}
+	if (argc >= 2 && strcmp(argv[1], "watch") == 0) {
+		long interval = 10;
+		time_t started = time(NULL);
+		int i;
+		for (i = 2; i < argc; i++) {
+			if (strcmp(argv[i], "--interval") == 0 && i + 1 < argc) {
+				interval = rbs_parse_duration(argv[++i]);
+				if (interval <= 0) {
+					printf("--interval wants 10s/1m style\n");
+					return 1;
+				}
+			}
+		}
+		printf("withdrawal watch: every %lds\n", interval);
+		fflush(stdout);
+		for (;;) {
+			rbs_withdrawals_pass();
+			rbs_daemon_heartbeat("withdrawals", started, 0, 0, "");
+			fflush(stderr);
+			sleep(interval);
+		}
+	}
+	printf("usage: robson withdrawals <check|watch [--interval 10s]>\n");
+	return 1;
+}
+
+#endif
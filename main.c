@@ -1,15 +1,102 @@
+#define _GNU_SOURCE 1
 #include <stdio.h>
 #include <stdlib.h>
 #include <unistd.h>
 #include <string.h>
+#include "include/i18n.h"
 #include "include/help.h"
 #include "include/report.h"
 #include "include/say.h"
 #include "include/buy.h"
 #include "include/sell.h"
+#include "include/plan.h"
+#include "include/price.h"
+#include "include/daemon.h"
+#include "include/crash.h"
+#include "include/execute.h"
+#include "include/flags.h"
+#include "include/template.h"
+#include "include/watch.h"
+#include "include/rebalance.h"
+#include "include/snapshot.h"
+#include "include/explain.h"
+#include "include/simulate.h"
+#include "include/whatif.h"
+#include "include/stress.h"
+#include "include/allocate.h"
+#include "include/auto.h"
+#include "include/server.h"
+#include "include/stats.h"
+#include "include/token.h"
+#include "include/http.h"
+#include "include/firedrill.h"
+#include "include/operations_cmd.h"
+#include "include/fees.h"
+#include "include/account.h"
+#include "include/order.h"
+#include "include/script.h"
+#include "include/ta.h"
+#include "include/remote.h"
+#include "include/margin.h"
+#include "include/apikey.h"
+#include "include/backup.h"
+#include "include/vacuum.h"
+#include "include/config.h"
+#include "include/positions.h"
+#include "include/completion.h"
+#include "include/verifybuild.h"
+#include "include/init.h"
+#include "include/keys.h"
+#include "include/withdrawals.h"
+#include "include/golive.h"
+#include "include/dashboard.h"
+#include "include/paper.h"
+#include "include/book.h"
+#include "include/promote.h"
+#include "include/risk.h"
+#include "include/trades.h"
+#include "include/candles.h"
+#include "include/deadman.h"
+#include "include/pnl.h"
+#include "include/export.h"
+#include "include/hooks.h"
+#include "include/alert.h"
+#include "include/sync.h"
+#include "include/allocation.h"
+#include "include/backends.h"
+#include "include/breaker.h"
+#include "include/perf.h"
+#include "include/pricetable.h"
 int main(int argc, char *argv[]) {
+	rbs_crash_install();
+	rbs_dotenv_load();
+	rbs_tz_init();
+	if (argc>=3 && strcmp(argv[1], "--lang")==0) {
+		setenv("ROBSON_LANG", argv[2], 1);
+		argv += 2; argc -= 2;
+	}
+	if (argc>=3 && strcmp(argv[1], "--env")==0) {
+		setenv("ROBSON_ENV", argv[2], 1);
+		argv += 2; argc -= 2;
+	}
+	if (argc>=3 && strcmp(argv[1], "--profile")==0) {
+		setenv("ROBSON_PROFILE", argv[2], 1);
+		argv += 2; argc -= 2;
+	}
+	if (argc>=3 && strcmp(argv[1], "--transport")==0) {
+		setenv("ROBSON_TRANSPORT", argv[2], 1);
+		argv += 2; argc -= 2;
+	}
+	if (argc>=2 && strcmp(argv[1], "--verbose")==0) {
+		setenv("ROBSON_VERBOSE", "1", 1);
+		argv += 1; argc -= 1;
+	}
+	if (argc>=2 && strcmp(argv[1], "--trace")==0) {
+		setenv("ROBSON_VERBOSE", "2", 1);
+		argv += 1; argc -= 1;
+	}
 	if (argc<2) {
-		printf("Welcome to Robson 0.01\n"); 
+		printf(rbs_tr("welcome"), RBS_VERSION);
 	} else {
 		if (strcmp(argv[1], "--help")==0) {
 			rbs_openscreen_help(); return 0;
@@ -25,9 +112,259 @@ int main(int argc, char *argv[]) {
 		} 
 		else if (strcmp(argv[1], "--sell")==0) {
 			rbs_openscreen_sell(); return 0;
-		} 
+		}
+		else if (strcmp(argv[1], "plan")==0) {
+			return rbs_plan_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "price")==0) {
+			/* one bare symbol keeps the single-quote output; more
+			 * symbols, a symbols file or --watch get the table */
+			if (argc == 3 && argv[2][0] != '-')
+				return rbs_price_cmd(argc-1, argv+1);
+			return rbs_price_table_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "indicator")==0) {
+			return rbs_indicator_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "script")==0) {
+			return rbs_script_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "order")==0) {
+			return rbs_order_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "withdrawals")==0) {
+			return rbs_withdrawals_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "keys")==0) {
+			return rbs_keys_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "go-live")==0) {
+			return rbs_golive_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "init")==0) {
+			return rbs_init_cmd();
+		}
+		else if (strcmp(argv[1], "verify-build")==0) {
+			return rbs_verify_build_cmd();
+		}
+		else if (strcmp(argv[1], "completion")==0) {
+			return rbs_completion_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "__complete")==0) {
+			return rbs_complete_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "paper")==0 && argc >= 3 &&
+		         strcmp(argv[2], "compare")==0) {
+			return rbs_paper_compare_cmd(argc-2, argv+2);
+		}
+		else if (strcmp(argv[1], "dashboard")==0) {
+			return rbs_dashboard_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "positions")==0) {
+			return rbs_positions_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "account")==0) {
+			return rbs_account_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "fees")==0) {
+			return rbs_fees_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "compare-fees")==0) {
+			return rbs_compare_fees_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "sync")==0 && argc >= 3 &&
+		         strcmp(argv[2], "fees")==0) {
+			return rbs_sync_fees_cmd();
+		}
+		else if (strcmp(argv[1], "sync")==0 && argc >= 3 &&
+		         strcmp(argv[2], "state")==0) {
+			return rbs_sync_state_cmd(argc-2, argv+2);
+		}
+		else if (strcmp(argv[1], "config")==0) {
+			return rbs_config_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "vacuum")==0) {
+			return rbs_vacuum_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "backup")==0) {
+			return rbs_backup_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "apikey")==0) {
+			return rbs_apikey_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "margin-status")==0) {
+			return rbs_margin_status_cmd();
+		}
+		else if (strcmp(argv[1], "margin-positions")==0) {
+			return rbs_margin_positions_cmd();
+		}
+		else if (strcmp(argv[1], "margin-buy")==0) {
+			return rbs_margin_buy_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "operations")==0) {
+			return rbs_operations_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "fire-drill")==0) {
+			return rbs_firedrill_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "http")==0) {
+			return rbs_http_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "auth")==0) {
+			return rbs_auth_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "serve-api")==0) {
+			return rbs_serve_api_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "auto")==0) {
+			return rbs_auto_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "allocate")==0) {
+			return rbs_allocate_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "stress")==0) {
+			return rbs_stress_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "whatif")==0) {
+			return rbs_whatif_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "snapshot")==0) {
+			return rbs_snapshot_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "diff")==0) {
+			return rbs_diff_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "export")==0) {
+			return rbs_export_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "pnl")==0 || strcmp(argv[1], "report")==0) {
+			return rbs_pnl_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "stats")==0) {
+			return rbs_perf_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "breaker")==0) {
+			return rbs_breaker_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "backends")==0) {
+			return rbs_backends_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "allocation")==0) {
+			return rbs_allocation_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "alert")==0) {
+			return rbs_alert_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "hook")==0) {
+			return rbs_hook_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "pause")==0) {
+			return rbs_pause_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "resume")==0) {
+			return rbs_resume_cmd();
+		}
+		else if (strcmp(argv[1], "heartbeat")==0) {
+			return rbs_heartbeat_cmd();
+		}
+		else if (strcmp(argv[1], "deadman")==0 && argc >= 3 &&
+		         strcmp(argv[2], "watch")==0) {
+			return rbs_deadman_cmd(argc-2, argv+2);
+		}
+		else if (strcmp(argv[1], "candles")==0) {
+			return rbs_candles_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "trades")==0) {
+			return rbs_trades_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "risk")==0) {
+			return rbs_risk_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "strategy")==0) {
+			return rbs_strategy_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "book")==0) {
+			return rbs_book_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "watch")==0) {
+			return rbs_watch_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "top")==0) {
+			return rbs_top_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "bugreport")==0) {
+			return rbs_bugreport_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "paths")==0) {
+			char root[512], cfg[640];
+			rbs_state_root(root, sizeof(root));
+			rbs_config_path(cfg, sizeof(cfg));
+			printf("state:  %s\n", root);
+			printf("config: %s\n", cfg);
+			return 0;
+		}
+		else if (strcmp(argv[1], "env")==0) {
+			printf("%s%s\n", rbs_env(), rbs_env_is_prod()
+			       ? "  (live orders need --ack)" : "");
+			return 0;
+		}
+		else if (strcmp(argv[1], "flags")==0) {
+			return rbs_flags_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "validate")==0) {
+			if (argc >= 3) {
+				int r = rbs_remote_plan_op("validate", argv[2], NULL);
+				if (r >= 0) return r;
+				if (r == -2) return 1;
+				r = rbs_container_plan_op("validate", argv[2], NULL);
+				if (r >= 0) return r;
+			}
+			return rbs_validate_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "explain")==0) {
+			return rbs_explain_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "approve")==0) {
+			return rbs_approve_cmd(argc-1, argv+1);
+		}
+		else if (strcmp(argv[1], "execute")==0) {
+			if (argc >= 3) {
+				char extra[56] = "";
+				const char *id = NULL;
+				int i, live = 0, accept = 0, ack = 0, r;
+				for (i = 2; i < argc; i++) {
+					if (strcmp(argv[i], "--live")==0) live = 1;
+					else if (strcmp(argv[i], "--accept-drift")==0) accept = 1;
+					else if (strcmp(argv[i], "--ack")==0) ack = 1;
+					else id = argv[i];
+				}
+				if (id) {
+					snprintf(extra, sizeof(extra),
+					         "live=%d&accept_drift=%d&ack=%d",
+					         live, accept, ack);
+					r = rbs_remote_plan_op("execute", id, extra);
+					if (r >= 0) return r;
+					if (r == -2) return 1;
+					{
+						char cflags[48] = "";
+						if (live) strcat(cflags, "--live");
+						if (accept) {
+							if (cflags[0]) strcat(cflags, " ");
+							strcat(cflags, "--accept-drift");
+						}
+						if (ack) {
+							if (cflags[0]) strcat(cflags, " ");
+							strcat(cflags, "--ack");
+						}
+						r = rbs_container_plan_op("execute", id, cflags);
+					}
+					if (r >= 0) return r;
+				}
+			}
+			return rbs_execute_cmd(argc-1, argv+1);
+		}
 		else {
-			printf("Type --help. Invalid argument %s\n", argv[1]);
+			printf(rbs_tr("bad.argument"), argv[1]);
 		}
 	}
 }
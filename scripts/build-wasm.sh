@@ -0,0 +1,21 @@
+#!/bin/sh
+# Build the analytics core as WebAssembly for the frontend.
+# Needs either emscripten (emcc) or clang with a wasm32 target.
+set -e
+cd "$(dirname "$0")/.."
+OUT=wasm/analytics.wasm
+if command -v emcc >/dev/null 2>&1; then
+	emcc wasm/analytics.c -O2 -o "$OUT" \
+		-s STANDALONE_WASM=1 --no-entry \
+		-s "EXPORTED_FUNCTIONS=['_sma','_ema','_rsi','_position_size','_r_multiple']"
+elif command -v clang >/dev/null 2>&1 && \
+     clang --print-targets 2>/dev/null | grep -q wasm32; then
+	clang --target=wasm32 -nostdlib -O2 \
+		-Wl,--no-entry -Wl,--export-dynamic \
+		-o "$OUT" wasm/analytics.c
+else
+	echo "no wasm toolchain found: install emscripten (emcc) or a clang" >&2
+	echo "with the wasm32 target, then re-run scripts/build-wasm.sh" >&2
+	exit 1
+fi
+echo "built $OUT ($(wc -c < "$OUT") bytes); pair it with wasm/analytics.js"
@@ -0,0 +1,31 @@
+#!/bin/sh
+# Generate gRPC stubs from proto/robson.proto for whichever languages
+# are installed. The CLI's --transport grpc stays a clear error until
+# a generated C client is linked in; the Go backend and daemons can
+# consume the same contract meanwhile.
+set -e
+cd "$(dirname "$0")/.."
+found=0
+if command -v protoc >/dev/null 2>&1; then
+	if protoc --version >/dev/null 2>&1; then
+		mkdir -p gen
+		if command -v protoc-gen-go >/dev/null 2>&1; then
+			protoc --go_out=gen --go-grpc_out=gen proto/robson.proto
+			echo "generated Go stubs in gen/"
+			found=1
+		fi
+		if command -v grpc_cpp_plugin >/dev/null 2>&1; then
+			protoc --cpp_out=gen \
+				--grpc_out=gen \
+				--plugin=protoc-gen-grpc="$(command -v grpc_cpp_plugin)" \
+				proto/robson.proto
+			echo "generated C++ stubs in gen/"
+			found=1
+		fi
+	fi
+fi
+if [ "$found" = 0 ]; then
+	echo "no protoc plugins found; install protoc plus protoc-gen-go" >&2
+	echo "or grpc_cpp_plugin, then re-run scripts/gen-grpc.sh" >&2
+	exit 1
+fi
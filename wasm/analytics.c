@@ -0,0 +1,37 @@
+/* Analytics core for the WebAssembly build. The exported functions
+ * are the exact implementations the CLI uses (ta.h and the sizing
+ * math), so the frontend's previews cannot drift from the robot.
+ * Build with scripts/build-wasm.sh. */
+#define RBS_TA_MATH_ONLY 1
+#include "../include/ta.h"
+
+__attribute__((export_name("sma")))
+double wasm_sma(const double *v, int n, int period) {
+	return rbs_ta_sma(v, n, period);
+}
+
+__attribute__((export_name("ema")))
+double wasm_ema(const double *v, int n, int period) {
+	return rbs_ta_ema(v, n, period);
+}
+
+__attribute__((export_name("rsi")))
+double wasm_rsi(const double *v, int n, int period) {
+	return rbs_ta_rsi(v, n, period);
+}
+
+/* Position size from capital, leverage and entry - the same formula
+ * whatif uses. */
+__attribute__((export_name("position_size")))
+double wasm_position_size(double capital, double leverage, double entry) {
+	if (entry <= 0 || capital <= 0) return 0;
+	if (leverage < 1) leverage = 1;
+	return capital * leverage / entry;
+}
+
+/* R multiple of a move given entry and stop. */
+__attribute__((export_name("r_multiple")))
+double wasm_r_multiple(double entry, double stop, double price) {
+	if (entry == stop) return 0;
+	return (price - entry) / (entry - stop);
+}
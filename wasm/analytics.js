@@ -0,0 +1,21 @@
+// Thin binding for the robson analytics WASM module. Usage:
+//   const a = await loadAnalytics('analytics.wasm');
+//   a.sma([1,2,3,4], 3)
+export async function loadAnalytics(url) {
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), {});
+  const { memory, sma, ema, rsi, position_size, r_multiple } = instance.exports;
+  // Scratch space on a fresh page so series data can never overlap
+  // the module's own data segment.
+  const scratchPage = memory.grow(1) * 65536;
+  function withSeries(fn, values, period) {
+    new Float64Array(memory.buffer, scratchPage, values.length).set(values);
+    return fn(scratchPage, values.length, period);
+  }
+  return {
+    sma: (v, p) => withSeries(sma, v, p),
+    ema: (v, p) => withSeries(ema, v, p),
+    rsi: (v, p) => withSeries(rsi, v, p),
+    positionSize: position_size,
+    rMultiple: r_multiple,
+  };
+}